@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+)
+
+// writeAnkiFile writes the ranked kanji, and the ranked words when word
+// counting is enabled, to path as a tab-separated file that Anki imports
+// directly.
+func writeAnkiFile(path string, rep *report) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeAnki(f, rep); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeAnki(w io.Writer, rep *report) error {
+	// The header lines tell Anki how to read the file, see
+	// https://docs.ankiweb.net/importing/text-files.html#file-headers.
+	header := "#separator:tab\n#html:false\n#columns:character\treading\tmeaning\tfrequency\texample url\n"
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = '\t'
+
+	for i, kanji := range kanjikana.Ranking(rep.res.Kanjis) {
+		if i >= rep.rankingSize {
+			break
+		}
+		reading, meaning := rep.kanjiReadingAndMeaning(kanji.Character)
+		record := []string{kanji.Character, reading, meaning, strconv.Itoa(kanji.Count), rep.res.Examples[kanji.Character]}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	for i, word := range kanjikana.WordRanking(rep.res.Words) {
+		if i >= rep.rankingSize {
+			break
+		}
+		var reading, meaning string
+		if rep.jmdict != nil {
+			if info := rep.jmdict.Lookup(word.Word); info != nil {
+				reading = strings.Join(info.Readings, "、")
+				meaning = strings.Join(info.Glosses, "; ")
+			}
+		}
+		record := []string{word.Word, reading, meaning, strconv.Itoa(word.Count), rep.res.Examples[word.Word]}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// kanjiReadingAndMeaning returns the readings and the meanings of kanji from
+// the KANJIDIC2 dictionary, or empty strings when none was loaded.
+func (rep *report) kanjiReadingAndMeaning(kanji string) (string, string) {
+	if rep.kanjidic == nil {
+		return "", ""
+	}
+	info := rep.kanjidic.Lookup(kanji)
+	if info == nil {
+		return "", ""
+	}
+	readings := append(append([]string(nil), info.OnReadings...), info.KunReadings...)
+	return strings.Join(readings, "、"), strings.Join(info.Meanings, "; ")
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+const aozoraURL = "https://www.aozora.gr.jp/"
+
+var (
+	// aozoraBookID matches the "author/book" ids of books, e.g. 148/789
+	// for Natsume Sōseki's 吾輩は猫である.
+	aozoraBookID = regexp.MustCompile(`^(\d+)/(\d+)$`)
+	// aozoraCardLink matches the links of an author page to the cards of
+	// the books.
+	aozoraCardLink = regexp.MustCompile(`/cards/\d+/card\d+\.html$`)
+	// aozoraXHTMLLink matches the link of a card to the XHTML text.
+	aozoraXHTMLLink = regexp.MustCompile(`/files/\d+_\d+\.html$`)
+)
+
+// countAozora downloads the Aozora Bunko books given by ids and counts
+// them together. An id is an "author/book" pair of numbers, an author number
+// standing for all the books of the author, or the URL of a card or author
+// page. The per-book results are available in Result.Files, by title, the
+// books of the same title being numbered from the second one.
+func countAozora(ctx context.Context, ids []string, options ...kanjikana.CountOption) (*kanjikana.Result, error) {
+	client := &http.Client{}
+
+	var cards []string
+	for _, id := range ids {
+		cardURLs, err := aozoraCards(ctx, client, id)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, cardURLs...)
+	}
+
+	total := &kanjikana.Result{Files: make(map[string]*kanjikana.Result)}
+	for _, card := range cards {
+		title, text, err := aozoraBook(ctx, client, card)
+		if err != nil && ctx.Err() != nil {
+			return total, ctx.Err()
+		}
+		if err != nil {
+			return nil, err
+		}
+		res, err := kanjikana.CountReader(strings.NewReader(text), options...)
+		if err != nil {
+			return nil, err
+		}
+		if err := total.Merge(res); err != nil {
+			return nil, err
+		}
+		key := title
+		for n := 2; total.Files[key] != nil; n++ {
+			key = fmt.Sprintf("%s (%d)", title, n)
+		}
+		total.Files[key] = res
+	}
+	return total, nil
+}
+
+// aozoraCards returns the URLs of the cards of the books given by id.
+func aozoraCards(ctx context.Context, client *http.Client, id string) ([]string, error) {
+	if m := aozoraBookID.FindStringSubmatch(id); m != nil {
+		author, _ := strconv.Atoi(m[1])
+		book, _ := strconv.Atoi(m[2])
+		return []string{fmt.Sprintf("%scards/%06d/card%d.html", aozoraURL, author, book)}, nil
+	}
+
+	var authorURL string
+	if author, err := strconv.Atoi(id); err == nil {
+		authorURL = fmt.Sprintf("%sindex_pages/person%d.html", aozoraURL, author)
+	} else if aozoraCardLink.MatchString(id) {
+		return []string{id}, nil
+	} else if kanjikana.ValidateURL(id) {
+		authorURL = id
+	} else {
+		return nil, fmt.Errorf("invalid Aozora Bunko id %q: want author/book numbers, an author number or a URL", id)
+	}
+
+	doc, err := fetchAozoraPage(ctx, client, authorURL)
+	if err != nil {
+		return nil, err
+	}
+	cards := aozoraLinks(authorURL, doc, aozoraCardLink)
+	if len(cards) == 0 {
+		return nil, fmt.Errorf("no book found on %s", authorURL)
+	}
+	return cards, nil
+}
+
+// aozoraBook returns the title and the text of the book of a card.
+func aozoraBook(ctx context.Context, client *http.Client, cardURL string) (string, string, error) {
+	doc, err := fetchAozoraPage(ctx, client, cardURL)
+	if err != nil {
+		return "", "", err
+	}
+	files := aozoraLinks(cardURL, doc, aozoraXHTMLLink)
+	if len(files) == 0 {
+		return "", "", fmt.Errorf("no XHTML text found on %s", cardURL)
+	}
+
+	title := cardURL
+	if n := kanjikana.FindElement(doc, func(n *html.Node) bool { return n.DataAtom == atom.Title }); n != nil && n.FirstChild != nil {
+		title = strings.TrimPrefix(strings.TrimSpace(n.FirstChild.Data), "図書カード：")
+	}
+
+	resp, err := getAozora(ctx, client, files[0])
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	text, err := kanjikana.AozoraText(resp.Body)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", files[0], err)
+	}
+	return title, text, nil
+}
+
+// getAozora gets pageURL, failing on error statuses.
+func getAozora(ctx context.Context, client *http.Client, pageURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s", pageURL, resp.Status)
+	}
+	return resp, nil
+}
+
+// fetchAozoraPage gets and parses an HTML page of Aozora Bunko, most of
+// which are encoded in Shift_JIS.
+func fetchAozoraPage(ctx context.Context, client *http.Client, pageURL string) (*html.Node, error) {
+	resp, err := getAozora(ctx, client, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	reader, err := charset.NewReader(resp.Body, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", pageURL, err)
+	}
+	return html.Parse(reader)
+}
+
+// aozoraLinks returns the absolute URLs of the anchors of doc matching
+// pattern, in document order and without duplicates.
+func aozoraLinks(pageURL string, doc *html.Node, pattern *regexp.Regexp) []string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	var links []string
+	seen := make(map[string]bool)
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			for _, attr := range n.Attr {
+				if attr.Key != "href" {
+					continue
+				}
+				ref, err := url.Parse(strings.TrimSpace(attr.Val))
+				if err != nil {
+					continue
+				}
+				link := base.ResolveReference(ref).String()
+				if pattern.MatchString(link) && !seen[link] {
+					seen[link] = true
+					links = append(links, link)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return links
+}
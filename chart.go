@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+)
+
+// chartSize is the number of bars of the bar charts.
+const chartSize = 20
+
+// chartBar is a labeled bar of a bar chart.
+type chartBar struct {
+	label string
+	value int
+}
+
+// writeBarChartSVG draws bars as a horizontal bar chart in SVG, the largest
+// value spanning the whole width.
+func writeBarChartSVG(w io.Writer, title string, bars []chartBar) error {
+	const (
+		width       = 640
+		barHeight   = 22
+		gap         = 6
+		labelWidth  = 120
+		valueWidth  = 70
+		titleHeight = 32
+	)
+
+	maxValue := 0
+	for _, bar := range bars {
+		maxValue = max(maxValue, bar.value)
+	}
+	height := titleHeight + len(bars)*(barHeight+gap) + gap
+
+	if _, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="14">`+"\n", width, height, width, height); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, `<rect width="%d" height="%d" fill="#fff"/>`+"\n", width, height)
+	fmt.Fprintf(w, `<text x="%d" y="22" font-size="16" font-weight="bold">%s</text>`+"\n", gap, html.EscapeString(title))
+
+	for i, bar := range bars {
+		y := titleHeight + i*(barHeight+gap)
+		barWidth := 0
+		if maxValue > 0 {
+			barWidth = bar.value * (width - labelWidth - valueWidth) / maxValue
+		}
+		fmt.Fprintf(w, `<text x="%d" y="%d" text-anchor="end">%s</text>`+"\n", labelWidth-gap, y+barHeight-6, html.EscapeString(bar.label))
+		fmt.Fprintf(w, `<rect x="%d" y="%d" width="%d" height="%d" fill="#4a7ab5"/>`+"\n", labelWidth, y, max(barWidth, 1), barHeight)
+		fmt.Fprintf(w, `<text x="%d" y="%d">%d</text>`+"\n", labelWidth+barWidth+gap, y+barHeight-6, bar.value)
+	}
+
+	_, err := io.WriteString(w, "</svg>\n")
+	return err
+}
+
+// topBars returns the chart bars of the n most frequent entries of m.
+func topBars(m map[string]int, n int) []chartBar {
+	var bars []chartBar
+	for _, key := range kanjikana.MostCommonCharacters(m) {
+		if len(bars) >= n {
+			break
+		}
+		bars = append(bars, chartBar{label: key, value: m[key]})
+	}
+	return bars
+}
+
+// writeCoverageCurveSVG draws the cumulative coverage curve of a ranking as
+// an SVG line chart: the share of occurrences covered by the n most frequent
+// entries, for every n.
+func writeCoverageCurveSVG(w io.Writer, title string, coverage []float64) error {
+	const (
+		width   = 640
+		height  = 400
+		marginX = 60
+		marginY = 50
+	)
+	plotWidth := float64(width - 2*marginX)
+	plotHeight := float64(height - 2*marginY)
+
+	if _, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", width, height, width, height); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, `<rect width="%d" height="%d" fill="#fff"/>`+"\n", width, height)
+	fmt.Fprintf(w, `<text x="%d" y="28" font-size="16" font-weight="bold">%s</text>`+"\n", marginX, html.EscapeString(title))
+
+	// Axes, with a grid line every 20%.
+	for percent := 0; percent <= 100; percent += 20 {
+		y := float64(height-marginY) - plotHeight*float64(percent)/100
+		fmt.Fprintf(w, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#ddd"/>`+"\n", marginX, y, width-marginX, y)
+		fmt.Fprintf(w, `<text x="%d" y="%.1f" text-anchor="end">%d%%</text>`+"\n", marginX-6, y+4, percent)
+	}
+	fmt.Fprintf(w, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#222"/>`+"\n", marginX, height-marginY, width-marginX, height-marginY)
+	fmt.Fprintf(w, `<text x="%d" y="%d" text-anchor="middle">0</text>`+"\n", marginX, height-marginY+16)
+	fmt.Fprintf(w, `<text x="%d" y="%d" text-anchor="middle">%d</text>`+"\n", width-marginX, height-marginY+16, len(coverage))
+	fmt.Fprintf(w, `<text x="%d" y="%d" text-anchor="middle">number of most frequent characters</text>`+"\n", width/2, height-marginY+36)
+
+	if len(coverage) > 0 {
+		var points []byte
+		points = fmt.Appendf(points, "%d,%d", marginX, height-marginY)
+		for i, c := range coverage {
+			x := float64(marginX) + plotWidth*float64(i+1)/float64(len(coverage))
+			y := float64(height-marginY) - plotHeight*c/100
+			points = fmt.Appendf(points, " %.1f,%.1f", x, y)
+		}
+		fmt.Fprintf(w, `<polyline points="%s" fill="none" stroke="#4a7ab5" stroke-width="2"/>`+"\n", points)
+	}
+
+	_, err := io.WriteString(w, "</svg>\n")
+	return err
+}
+
+// writeCharts writes a bar chart of the most common characters of every
+// category, and of the words when they were counted, along with the coverage
+// curve of the kanji, as SVG files in dir.
+func writeCharts(dir string, res *kanjikana.Result) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	charts := []struct {
+		name, title string
+		m           map[string]int
+	}{
+		{"kanji", "Most common kanji", res.Kanjis},
+		{"hiragana", "Most common hiragana", res.Hiraganas},
+		{"katakana", "Most common katakana", res.Katakanas},
+		{"words", "Most common words", res.Words},
+		{"compounds", "Most common kanji compounds", res.Compounds},
+		{"punctuation", "Most common punctuation", res.Punctuation},
+	}
+	for _, chart := range charts {
+		if len(chart.m) == 0 {
+			continue
+		}
+		err := writeChartFile(filepath.Join(dir, chart.name+".svg"), func(w io.Writer) error {
+			return writeBarChartSVG(w, chart.title, topBars(chart.m, chartSize))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeChartFile(filepath.Join(dir, "kanji-coverage.svg"), func(w io.Writer) error {
+		return writeCoverageCurveSVG(w, "Kanji coverage", kanjikana.Coverage(res.Kanjis))
+	})
+}
+
+func writeChartFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+	"golang.org/x/term"
+)
+
+// ANSI escape sequences of the colors of the text output.
+const (
+	colorReset   = "\033[0m"
+	colorRed     = "\033[31m"
+	colorGreen   = "\033[32m"
+	colorYellow  = "\033[33m"
+	colorBlue    = "\033[34m"
+	colorMagenta = "\033[35m"
+	colorCyan    = "\033[36m"
+)
+
+var categoryColors = map[string]string{
+	kanjikana.CategoryKanji:       colorYellow,
+	kanjikana.CategoryHiragana:    colorGreen,
+	kanjikana.CategoryKatakana:    colorCyan,
+	kanjikana.CategoryWord:        colorMagenta,
+	kanjikana.CategoryNGram:       colorBlue,
+	kanjikana.CategoryCompound:    colorYellow,
+	kanjikana.CategoryPunctuation: colorRed,
+	kanjikana.CategoryComponent:   colorYellow,
+}
+
+// levelColors colors the levels of a kanji classification in the order they
+// are defined, e.g. from N5 to N1, from the easiest to the hardest.
+var levelColors = []string{colorGreen, colorCyan, colorBlue, colorYellow, colorRed, colorMagenta}
+
+// useColor reports whether the text output written to w should be colored:
+// w must be a terminal and colors must not be disabled by the NO_COLOR
+// environment variable (https://no-color.org).
+func useColor(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// lineColor returns the color of the ranking line of c, an entry of category:
+// the color of its JLPT level when kanji are annotated with one, the color
+// of its category otherwise.
+func (rep *report) lineColor(category, c string) string {
+	if !rep.color {
+		return ""
+	}
+	if category == kanjikana.CategoryKanji && rep.jlpt != nil {
+		if level := rep.jlpt.Level(c); level != "" {
+			for i, name := range rep.jlpt.Levels() {
+				if name == level {
+					return levelColors[i%len(levelColors)]
+				}
+			}
+		}
+	}
+	return categoryColors[category]
+}
+
+// colorize wraps s in color, unless color is empty.
+func colorize(s, color string) string {
+	if color == "" {
+		return s
+	}
+	return color + s + colorReset
+}
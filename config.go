@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile is the name of the configuration file looked up in the
+// home directory when -config is not given.
+const defaultConfigFile = ".kanjicounter.yaml"
+
+// applyConfig sets the flags of fs missing from the command line to the
+// values of the YAML configuration file at path, whose keys are flag names.
+// Lists set a flag once per element, for flags that can be repeated. Flags
+// of the other counting commands are ignored, so that a single file serves
+// them all. A missing file is only an error when required is true.
+func applyConfig(fs *flag.FlagSet, path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var settings map[string]any
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	setOnCommandLine := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		setOnCommandLine[f.Name] = true
+	})
+
+	// Every counting flag is accepted without a subcommand.
+	allFlags := flag.NewFlagSet("", flag.ContinueOnError)
+	new(countFlags).register(allFlags, legacyCommand)
+
+	for name, value := range settings {
+		if allFlags.Lookup(name) == nil || name == "config" {
+			return fmt.Errorf("%s: unknown setting %q", path, name)
+		}
+		if fs.Lookup(name) == nil || setOnCommandLine[name] {
+			continue
+		}
+		values, ok := value.([]any)
+		if !ok {
+			values = []any{value}
+		}
+		for _, v := range values {
+			if err := fs.Set(name, configValue(v)); err != nil {
+				return fmt.Errorf("%s: %s: %w", path, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// configValue formats a configuration value as a flag value, expanding a
+// leading "~/" in paths to the home directory.
+func configValue(v any) string {
+	s := fmt.Sprint(v)
+	if rest, ok := strings.CutPrefix(s, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return s
+}
+
+// defaultConfigPath returns the path of the configuration file in the home
+// directory, or "" when the home directory is unknown.
+func defaultConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, defaultConfigFile)
+}
@@ -0,0 +1,904 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+)
+
+// Commands counting characters. Without a subcommand, the flags of both
+// crawl and file are accepted, as in earlier versions of the CLI.
+const (
+	legacyCommand    = ""
+	crawlCommand     = "crawl"
+	fileCommand      = "file"
+	aozoraCommand    = "aozora"
+	wikipediaCommand = "wikipedia"
+	youtubeCommand   = "youtube"
+)
+
+// iterationMarkModes and longVowelMarkModes map the values of the
+// -iteration-mark and -long-vowel flags to their modes.
+var (
+	iterationMarkModes = map[string]kanjikana.IterationMark{
+		"symbol": kanjikana.IterationMarkSymbol,
+		"repeat": kanjikana.IterationMarkRepeat,
+	}
+	longVowelMarkModes = map[string]kanjikana.LongVowelMark{
+		"separate": kanjikana.LongVowelMarkSeparate,
+		"attach":   kanjikana.LongVowelMarkAttach,
+	}
+)
+
+// crawls reports whether command crawls websites, and accepts the crawl
+// flags.
+func crawls(command string) bool {
+	return command == legacyCommand || command == crawlCommand
+}
+
+// countFlags are the flags of the commands counting characters.
+type countFlags struct {
+	logFlags
+
+	url           string
+	urls          []string
+	searchDepth   int
+	rankingSize   int
+	outputFormat  string
+	outputFile    string
+	concurrency   int
+	hostRequests  int
+	rateLimit     float64
+	inputFile     string
+	inputDir      string
+	warcFile      string
+	warcOut       string
+	mainContent   bool
+	dedupBlocks   bool
+	sameDomain    bool
+	perDomain     bool
+	ignoreRobots  bool
+	minJapanese   float64
+	duplicates    bool
+	maxRedirects  int
+	hostRedirects bool
+	allowHosts    []string
+	denyHosts     []string
+	includeURLs   []*regexp.Regexp
+	excludeURLs   []*regexp.Regexp
+	timeout       time.Duration
+	deadline      time.Time
+	fetchTimeout  time.Duration
+	proxyURL      string
+	render        bool
+	renderWait    time.Duration
+	cookies       []*http.Cookie
+	basicAuth     string
+	retries       int
+	sitemap       bool
+	feed          string
+	preset        string
+	maxPages      int
+	stopKanjis    int
+	stopChars     int
+	sample        float64
+	wayback       string
+	strategy      string
+	keywords      []string
+	cacheDir      string
+	watch         string
+	dbPath        string
+	appendDB      bool
+	words         bool
+	ngramSize     int
+	punctuation   bool
+	compounds     bool
+	halfWidth     bool
+	morae         bool
+	shinjitai     bool
+	variantsFile  string
+	skipChinese   bool
+	iterationMark string
+	longVowelMark string
+	jlpt          bool
+	jlptFile      string
+	joyo          bool
+	grades        bool
+	kanjidicFile  string
+	jmdictFile    string
+	ankiFile      string
+	wanikani      bool
+	kradfiles     []string
+	strokes       bool
+	chapters      bool
+	coverage      bool
+	distribution  bool
+	blocks        bool
+	sentences     bool
+	readings      bool
+	readingCounts bool
+	pos           bool
+	furiganaFile  string
+	furiganaMin   int
+	reportFile    string
+	chartsDir     string
+	histogram     bool
+	noColor       bool
+	noProgress    bool
+	configFile    string
+}
+
+// urlsValue is the value of the -url flag, which can be repeated: url holds
+// the first URL given, and urls all of them.
+type urlsValue struct {
+	url  *string
+	urls *[]string
+}
+
+func (v urlsValue) String() string {
+	if v.url == nil {
+		return ""
+	}
+	return *v.url
+}
+
+func (v urlsValue) Set(value string) error {
+	*v.urls = append(*v.urls, value)
+	*v.url = (*v.urls)[0]
+	return nil
+}
+
+// waybackURL returns the URL archived by rawURL when it is a Wayback
+// Machine URL, setting -wayback to its timestamp, and rawURL otherwise.
+func (f *countFlags) waybackURL(rawURL string) string {
+	pageURL, timestamp, ok := kanjikana.ParseWaybackURL(rawURL)
+	if !ok {
+		return rawURL
+	}
+	if f.wayback == "" {
+		f.wayback = timestamp
+	} else if f.wayback != timestamp {
+		fatalf("%s is a snapshot of %s, not of -wayback %s", rawURL, timestamp, f.wayback)
+	}
+	return pageURL
+}
+
+// register defines the flags of command on fs.
+func (f *countFlags) register(fs *flag.FlagSet, command string) {
+	if crawls(command) {
+		f.url = kanjikana.DefaultURL
+		fs.Var(urlsValue{url: &f.url, urls: &f.urls}, "url", "target website (\"-\" reads text from stdin; can be repeated to crawl several websites in parallel)")
+		fs.IntVar(&f.searchDepth, "depth", kanjikana.DefaultSearchDepth, "search depth")
+		fs.IntVar(&f.concurrency, "concurrency", kanjikana.DefaultConcurrency, "number of pages fetched in parallel")
+		fs.IntVar(&f.hostRequests, "host-concurrency", kanjikana.DefaultHostConcurrency, "maximum number of pages fetched in parallel from the same host")
+		fs.Float64Var(&f.rateLimit, "ratelimit", 0, "maximum requests per second to the same host (0 means unlimited)")
+		fs.BoolVar(&f.sameDomain, "samedomain", false, "only follow links to the host of the target website")
+		fs.BoolVar(&f.perDomain, "per-domain", false, "also report the counts of every host reached by the crawl separately")
+		fs.BoolVar(&f.ignoreRobots, "ignore-robots", false, "follow rel=\"nofollow\" links and count the pages whose robots meta tag asks for noindex or nofollow")
+		fs.Float64Var(&f.minJapanese, "min-japanese", 0, "skip the pages whose share of Japanese characters among the letters and digits of their text is below this ratio, e.g. 0.3")
+		fs.BoolVar(&f.duplicates, "keep-duplicates", false, "count the pages that duplicate a page already counted, by canonical URL or by text, which are skipped by default")
+		fs.IntVar(&f.maxRedirects, "max-redirects", kanjikana.DefaultMaxRedirects, "maximum number of redirects followed per page (0 follows none)")
+		fs.BoolVar(&f.hostRedirects, "same-host-redirects", false, "only follow the redirects to the host of the redirecting page")
+		fs.Func("allow-hosts", "comma-separated hosts whose links are followed besides the target host, with their subdomains, e.g. www3.nhk.or.jp (can be repeated)", func(hosts string) error {
+			f.allowHosts = append(f.allowHosts, strings.Split(hosts, ",")...)
+			return nil
+		})
+		fs.Func("deny-hosts", "comma-separated hosts whose links are never followed, with their subdomains, e.g. doubleclick.net (can be repeated)", func(hosts string) error {
+			f.denyHosts = append(f.denyHosts, strings.Split(hosts, ",")...)
+			return nil
+		})
+		fs.Func("include-url", "only follow links whose URL matches this regular expression, e.g. /news/ (can be repeated to follow links matching any)", func(pattern string) error {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return err
+			}
+			f.includeURLs = append(f.includeURLs, re)
+			return nil
+		})
+		fs.Func("exclude-url", "do not follow links whose URL matches this regular expression, e.g. /(english|photo)/ (can be repeated)", func(pattern string) error {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return err
+			}
+			f.excludeURLs = append(f.excludeURLs, re)
+			return nil
+		})
+		fs.DurationVar(&f.timeout, "timeout", 0, "maximum duration of the crawl (0 means no limit)")
+		fs.Func("deadline", "time at which the crawl stops, e.g. 2024-04-01T06:00:00+09:00", func(value string) error {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return err
+			}
+			f.deadline = t
+			return nil
+		})
+		fs.DurationVar(&f.fetchTimeout, "request-timeout", 0, "maximum duration of every request, including reading the page (0 means no limit)")
+		fs.StringVar(&f.proxyURL, "proxy", "", "HTTP or SOCKS5 proxy URL, e.g. socks5://localhost:1080")
+		fs.BoolVar(&f.render, "render", false, "load the pages in a headless Chrome, for the sites that add their text with JavaScript (requires Chrome or Chromium)")
+		fs.DurationVar(&f.renderWait, "render-wait", time.Second, "how long the scripts of a page rendered with -render may run once it is loaded")
+		fs.Func("cookie", "cookies sent to the target website, as in a Cookie header, e.g. \"session=abc123; lang=ja\" (can be repeated)", func(header string) error {
+			cookies := (&http.Request{Header: http.Header{"Cookie": {header}}}).Cookies()
+			if len(cookies) == 0 {
+				return fmt.Errorf("no cookie in %q", header)
+			}
+			f.cookies = append(f.cookies, cookies...)
+			return nil
+		})
+		fs.StringVar(&f.basicAuth, "basic-auth", "", "user:password for the HTTP basic authentication of the target website")
+		fs.IntVar(&f.retries, "retries", 0, "number of retries of failed fetches")
+		fs.BoolVar(&f.sitemap, "sitemap", false, "crawl the pages listed in the site's sitemap instead of following links")
+		fs.StringVar(&f.preset, "preset", "", "crawl a known site with its root URL, article link pattern, article text selector and a polite rate limit ("+presetNames()+")")
+		fs.StringVar(&f.feed, "feed", "", "crawl the articles linked from an RSS or Atom feed instead of following links from -url")
+		fs.IntVar(&f.maxPages, "maxpages", 0, "maximum number of pages to crawl (0 means no limit)")
+		fs.IntVar(&f.stopKanjis, "stop-after-unique-kanji", 0, "stop the crawl once this number of different kanji is counted (0 means no limit)")
+		fs.IntVar(&f.stopChars, "stop-after-chars", 0, "stop the crawl once this number of Japanese characters is counted (0 means no limit)")
+		fs.Float64Var(&f.sample, "sample", 0, "only follow this random fraction of the links of every page, e.g. 0.2 (0 follows them all)")
+		fs.StringVar(&f.wayback, "wayback", "", "crawl the snapshots of the Wayback Machine closest to this date, e.g. 2005 or 20050401 (implied by a web.archive.org -url)")
+		fs.StringVar(&f.strategy, "strategy", "bfs", "crawl strategy (bfs, dfs, best)")
+		fs.Func("priority-keywords", "comma-separated path keywords of the pages visited first by the best strategy (default news, article, column, blog and the like)", func(keywords string) error {
+			f.keywords = append(f.keywords, strings.Split(keywords, ",")...)
+			return nil
+		})
+		fs.StringVar(&f.warcFile, "warc", "", "count the pages archived in a WARC file (.warc or .warc.gz), as written by wget or Heritrix, instead of crawling a website")
+		fs.BoolVar(&f.mainContent, "main-content", false, "only count the main article of every page, leaving out menus, footers and sidebars")
+		fs.BoolVar(&f.dedupBlocks, "dedup-boilerplate", false, "count the text blocks repeated on several pages of a site, such as headers, footers and cookie banners, on the first page only")
+		fs.StringVar(&f.warcOut, "warc-out", "", "record the crawled pages in a WARC file, compressed when named .warc.gz, to keep the corpus behind the result")
+		fs.StringVar(&f.cacheDir, "cache-dir", "", "directory where fetched pages are cached between runs")
+		fs.StringVar(&f.watch, "watch", "", "crawl again on a schedule, given as an interval (6h) or a cron expression (\"0 */6 * * *\"), and print what changed since the previous crawl")
+		fs.BoolVar(&f.noProgress, "no-progress", false, "do not show the crawl progress on stderr (only shown when stderr is a terminal)")
+	}
+	if command == legacyCommand {
+		fs.StringVar(&f.inputFile, "file", "", "count a local text, HTML, PDF, EPUB or subtitle (.srt, .vtt, .ass) file instead of crawling a website (\"-\" reads from stdin)")
+		fs.StringVar(&f.inputDir, "dir", "", "count every .txt, .html, .md, .pdf, .epub and subtitle file under a directory")
+	}
+	fs.IntVar(&f.rankingSize, "ranksize", defaultRankingSize, "ranking size")
+	fs.BoolVar(&f.words, "words", false, "also rank words, counted by their dictionary form")
+	fs.IntVar(&f.ngramSize, "ngram", 0, "also rank sequences of n consecutive characters, e.g. 2 for bigrams")
+	fs.BoolVar(&f.compounds, "compounds", false, "also rank kanji compounds, the runs of two or more consecutive kanji (日本, 経済産業省)")
+	fs.BoolVar(&f.punctuation, "punctuation", false, "also rank Japanese punctuation and symbols (。、「」・〜)")
+	fs.BoolVar(&f.halfWidth, "keep-halfwidth", false, "count half-width katakana (ｶﾀｶﾅ) as their own characters instead of normalizing them to full-width")
+	fs.BoolVar(&f.morae, "morae", false, "count kana in morae, combining digraphs such as きょ and ファ into single entries")
+	fs.BoolVar(&f.shinjitai, "shinjitai", false, "count kyūjitai and common kanji variants as their shinjitai forms (國 as 国, 學 as 学)")
+	fs.StringVar(&f.variantsFile, "variants", "", "also normalize the kanji variants listed in a file, one variant per line followed by its standard form (implies -shinjitai)")
+	fs.BoolVar(&f.skipChinese, "skip-chinese", false, "skip the text that looks Chinese rather than Japanese: lines of hanzi without kana and elements with a zh lang attribute")
+	fs.StringVar(&f.iterationMark, "iteration-mark", "symbol", "count the iteration mark 々 as a kanji of its own (symbol) or as a repetition of the preceding kanji (repeat)")
+	fs.StringVar(&f.longVowelMark, "long-vowel", "separate", "count the long vowel mark ー as a kana of its own (separate) or as part of the preceding kana (attach)")
+	fs.BoolVar(&f.jlpt, "jlpt", false, "annotate kanji with their JLPT level (bundled list covers N5 and N4)")
+	fs.StringVar(&f.jlptFile, "jlpt-file", "", "load JLPT kanji levels from a file instead of the bundled list (implies -jlpt)")
+	fs.BoolVar(&f.joyo, "joyo", false, "report how many of the 2,136 jōyō kanji appeared and list the missing ones")
+	fs.BoolVar(&f.grades, "grades", false, "annotate kanji with the school grade in which they are taught and show per-grade coverage")
+	fs.StringVar(&f.kanjidicFile, "kanjidic", "", "show kanji readings and meanings from a KANJIDIC2 XML file (optionally gzipped)")
+	fs.StringVar(&f.jmdictFile, "jmdict", "", "show readings, glosses and common-word markers of ranked words from a JMdict XML file (optionally gzipped, implies -words)")
+	fs.StringVar(&f.ankiFile, "anki", "", "also write the ranked kanji and words to a tab-separated file that Anki can import")
+	fs.BoolVar(&f.wanikani, "wanikani", false, "split the kanji ranking into kanji learned and not yet learned on WaniKani (reads the API token from $"+wanikaniTokenEnvVar+")")
+	fs.BoolVar(&f.readings, "readings", false, "show a best-effort reading of ranked kanji from the bundled reading table (covers the kyōiku kanji)")
+	fs.BoolVar(&f.readingCounts, "reading-counts", false, "count how every kanji is read in the text, from the readings of the words (implies -words)")
+	fs.BoolVar(&f.pos, "pos", false, "report the distribution of the words by part of speech (implies -words)")
+	fs.BoolVar(&f.strokes, "strokes", false, "annotate kanji with their stroke count and show a stroke count histogram (requires -kanjidic)")
+	fs.BoolVar(&f.coverage, "coverage", false, "report the share of kanji occurrences covered by the most frequent kanji, with the full cumulative curve in JSON and CSV")
+	fs.BoolVar(&f.sentences, "sentences", false, "report the number of sentences, split on 。！？, their average length and their average kanji density")
+	fs.BoolVar(&f.distribution, "distribution", false, "report the Zipf exponent fit, type-token ratio and Shannon entropy of the kanji distribution")
+	fs.BoolVar(&f.blocks, "blocks", false, "report the occurrences of the characters of every Unicode block, such as the CJK extensions of rare kanji")
+	fs.BoolVar(&f.chapters, "chapters", false, "report, for each chapter of an EPUB book, the kanji it introduces")
+	fs.Func("kradfile", "rank kanji components using a KRADFILE decomposition file (can be repeated, e.g. for KRADFILE2)", func(path string) error {
+		f.kradfiles = append(f.kradfiles, path)
+		return nil
+	})
+	fs.StringVar(&f.furiganaFile, "furigana", "", "also write the counted text to an HTML file with furigana over the kanji")
+	fs.IntVar(&f.furiganaMin, "furigana-min", 1, "only add furigana to kanji counted at least this many times")
+	fs.StringVar(&f.reportFile, "report", "", "also write a standalone HTML report with charts and sortable rankings")
+	fs.StringVar(&f.chartsDir, "charts", "", "also write SVG frequency bar charts and a kanji coverage curve to this directory")
+	fs.BoolVar(&f.histogram, "histogram", false, "draw a bar next to every ranked character in the text output, scaled to the terminal width")
+	fs.BoolVar(&f.noColor, "no-color", false, "disable colors in the text output (also disabled by the NO_COLOR environment variable)")
+	f.logFlags.register(fs)
+	fs.StringVar(&f.configFile, "config", "", "read default flag values from a YAML file (default ~/"+defaultConfigFile+")")
+	fs.StringVar(&f.outputFormat, "output", textOutput, "output format (text, json, csv, tsv)")
+	fs.StringVar(&f.outputFile, "outfile", "", "write output to file instead of stdout")
+	fs.StringVar(&f.dbPath, "db", "", "also store the result in a SQLite database")
+	fs.BoolVar(&f.appendDB, "append", false, "add the result to the cumulative corpus of the -db database and write the corpus totals instead")
+}
+
+func runCrawl(args []string) {
+	runCount(crawlCommand, args)
+}
+
+func runFile(args []string) {
+	runCount(fileCommand, args)
+}
+
+func runWikipedia(args []string) {
+	runCount(wikipediaCommand, args)
+}
+
+func runAozora(args []string) {
+	runCount(aozoraCommand, args)
+}
+
+func runYouTube(args []string) {
+	runCount(youtubeCommand, args)
+}
+
+// runCount counts the characters of a website, for the crawl command, or of
+// local files, for the file command, and writes the result.
+func runCount(command string, args []string) {
+	name := command
+	if command == legacyCommand {
+		name = os.Args[0]
+	}
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	var f countFlags
+	f.register(fs, command)
+	switch command {
+	case legacyCommand:
+		fs.Usage = func() {
+			usage(fs)
+		}
+	case crawlCommand:
+		setCommandUsage(fs, "crawl [flags] [url]", "Crawl a website and count its Japanese characters.")
+	case fileCommand:
+		setCommandUsage(fs, "file [flags] path", "Count the Japanese characters of a text, HTML, Markdown, PDF, EPUB or subtitle file, a directory, or stdin (\"-\").")
+	case wikipediaCommand:
+		setCommandUsage(fs, "wikipedia [flags] dump.xml.bz2", "Count the articles of a Wikipedia XML dump, such as jawiki-latest-pages-articles.xml.bz2 from https://dumps.wikimedia.org/jawiki/, compressed with bzip2 or not, or read from stdin (\"-\"). The wiki markup is stripped and the dump is streamed.")
+	case aozoraCommand:
+		setCommandUsage(fs, "aozora [flags] id...", "Download books from Aozora Bunko and count their Japanese characters. An id is an author/book pair of numbers (148/789), an author number (148) for all the books of the author, or the URL of a card or author page.")
+	case youtubeCommand:
+		setCommandUsage(fs, "youtube [flags] url...", "Fetch the Japanese captions of YouTube videos and count their characters. A URL is the one of a video or a playlist, or a video id. Captions written by people are preferred to automatic ones, and videos without Japanese captions are skipped.")
+	}
+	fs.Parse(args)
+
+	// Flags given on the command line override the configuration file.
+	if f.configFile != "" {
+		if err := applyConfig(fs, f.configFile, true); err != nil {
+			fatal(err)
+		}
+	} else if path := defaultConfigPath(); path != "" {
+		if err := applyConfig(fs, path, false); err != nil {
+			fatal(err)
+		}
+	}
+
+	// Presets only fill in the flags left unset, and a URL argument
+	// replaces the root URL of the preset.
+	var presetOptions []kanjikana.Option
+	if f.preset != "" {
+		p, ok := presets[f.preset]
+		if !ok {
+			fatalf("unknown preset: %s (want one of %s)", f.preset, presetNames())
+		}
+		presetOptions = p.apply(&f, func(name string) bool {
+			return isFlagSet(fs, name)
+		})
+	}
+
+	switch command {
+	case crawlCommand:
+		switch fs.NArg() {
+		case 0:
+		case 1:
+			if len(f.urls) > 1 {
+				fatal("crawl takes a single URL")
+			}
+			f.url = fs.Arg(0)
+		default:
+			fatal("crawl takes a single URL")
+		}
+	case fileCommand:
+		if fs.NArg() != 1 {
+			fatal("file takes a single path")
+		}
+		path := fs.Arg(0)
+		if kanjikana.IsWARCFile(path) {
+			fatal("WARC archives are counted with crawl -warc")
+		}
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			f.inputDir = path
+		} else {
+			f.inputFile = path
+		}
+	case wikipediaCommand:
+		if fs.NArg() != 1 {
+			fatal("wikipedia takes a single dump file")
+		}
+	case aozoraCommand:
+		if fs.NArg() == 0 {
+			fatal("aozora takes at least one book or author id")
+		}
+	case youtubeCommand:
+		if fs.NArg() == 0 {
+			fatal("youtube takes at least one video or playlist URL")
+		}
+	default:
+		if fs.NArg() > 0 {
+			fatalf("unknown command: %s", fs.Arg(0))
+		}
+	}
+
+	// Diagnostics go to stderr, below the progress line while crawling.
+	var (
+		progress  *progressLine
+		logOutput io.Writer = os.Stderr
+	)
+	if crawls(command) && !f.noProgress && !f.quiet && showProgress() {
+		progress = newProgressLine(os.Stderr)
+		logOutput = progress
+	}
+	logger := f.logFlags.setup(logOutput)
+
+	if _, ok := outputFormats[f.outputFormat]; !ok {
+		fatalf("unknown output format: %s", f.outputFormat)
+	}
+
+	if f.strokes && f.kanjidicFile == "" {
+		fatal("-strokes requires -kanjidic")
+	}
+	if f.appendDB && f.dbPath == "" {
+		fatal("-append requires -db")
+	}
+	if f.feed != "" {
+		if f.sitemap {
+			fatal("-feed and -sitemap cannot be used together")
+		}
+		f.url = f.feed
+	}
+	if len(f.urls) > 1 && (f.feed != "" || slices.Contains(f.urls, stdinInput)) {
+		fatal("-url can only be repeated to crawl websites")
+	}
+	// A Wayback Machine URL crawls the snapshots of the site it archives.
+	if len(f.urls) > 1 {
+		for i, u := range f.urls {
+			f.urls[i] = f.waybackURL(u)
+		}
+		f.url = f.urls[0]
+	} else {
+		f.url = f.waybackURL(f.url)
+	}
+
+	crawlStrategy, err := parseCrawlStrategy(f.strategy)
+	if err != nil && crawls(command) {
+		fatal(err)
+	}
+
+	var sched schedule
+	if f.watch != "" {
+		if f.inputFile != "" || f.inputDir != "" || f.url == stdinInput || f.warcFile != "" {
+			fatal("-watch only applies to crawls")
+		}
+		if sched, err = parseSchedule(f.watch); err != nil {
+			fatal(err)
+		}
+	}
+
+	var jmdict *kanjikana.JMdict
+	if f.jmdictFile != "" {
+		if jmdict, err = loadJMdict(f.jmdictFile); err != nil {
+			fatal(err)
+		}
+	}
+
+	var countOptions []kanjikana.CountOption
+	if f.words || f.readingCounts || f.pos || jmdict != nil {
+		tokenizer, err := loadKagomeTokenizer()
+		if err != nil {
+			fatal(err)
+		}
+		countOptions = append(countOptions, kanjikana.WithTokenizer(tokenizer))
+	}
+	if f.readingCounts {
+		countOptions = append(countOptions, kanjikana.WithReadingCounts())
+	}
+	if f.pos {
+		countOptions = append(countOptions, kanjikana.WithPartsOfSpeech())
+	}
+
+	if f.ngramSize > 0 {
+		countOptions = append(countOptions, kanjikana.WithNGrams(f.ngramSize))
+	}
+
+	if f.compounds {
+		countOptions = append(countOptions, kanjikana.WithCompounds())
+	}
+
+	if f.punctuation {
+		countOptions = append(countOptions, kanjikana.WithPunctuation())
+	}
+
+	if f.sentences {
+		countOptions = append(countOptions, kanjikana.WithSentences())
+	}
+
+	if f.halfWidth {
+		countOptions = append(countOptions, kanjikana.WithHalfWidthKatakana())
+	}
+
+	if f.morae {
+		countOptions = append(countOptions, kanjikana.WithMorae())
+	}
+
+	if f.shinjitai || f.variantsFile != "" {
+		variants := kanjikana.BundledVariants()
+		if f.variantsFile != "" {
+			extra, err := loadVariantTable(f.variantsFile)
+			if err != nil {
+				fatal(err)
+			}
+			variants = variants.Extend(extra)
+		}
+		countOptions = append(countOptions, kanjikana.WithVariants(variants))
+	}
+
+	if f.skipChinese {
+		countOptions = append(countOptions, kanjikana.WithChineseExclusion())
+	}
+
+	iterationMark, ok := iterationMarkModes[f.iterationMark]
+	if !ok {
+		fatalf("unknown -iteration-mark mode: %s (want symbol or repeat)", f.iterationMark)
+	}
+	longVowelMark, ok := longVowelMarkModes[f.longVowelMark]
+	if !ok {
+		fatalf("unknown -long-vowel mode: %s (want separate or attach)", f.longVowelMark)
+	}
+	countOptions = append(countOptions, kanjikana.WithIterationMark(iterationMark), kanjikana.WithLongVowelMark(longVowelMark))
+
+	options := []kanjikana.Option{
+		kanjikana.WithSearchDepth(f.searchDepth),
+		kanjikana.WithConcurrency(f.concurrency),
+		kanjikana.WithHostConcurrency(f.hostRequests),
+		kanjikana.WithCrawlStrategy(crawlStrategy),
+		kanjikana.WithCountOptions(countOptions...),
+		kanjikana.WithLogger(logger),
+	}
+	if f.sameDomain {
+		options = append(options, kanjikana.WithSameDomainOnly())
+	}
+	if f.perDomain {
+		options = append(options, kanjikana.WithPerDomain())
+	}
+	if f.ignoreRobots {
+		options = append(options, kanjikana.WithIgnoreRobots())
+	}
+	if f.minJapanese > 0 {
+		options = append(options, kanjikana.WithMinJapaneseRatio(f.minJapanese))
+	}
+	if f.duplicates {
+		options = append(options, kanjikana.WithDuplicatePages())
+	}
+	if f.maxRedirects != kanjikana.DefaultMaxRedirects {
+		options = append(options, kanjikana.WithMaxRedirects(f.maxRedirects))
+	}
+	if f.hostRedirects {
+		options = append(options, kanjikana.WithSameHostRedirects())
+	}
+	if len(f.keywords) > 0 {
+		options = append(options, kanjikana.WithPriorityKeywords(f.keywords...))
+	}
+	if len(f.allowHosts) > 0 {
+		options = append(options, kanjikana.WithAllowedHosts(f.allowHosts...))
+	}
+	if len(f.denyHosts) > 0 {
+		options = append(options, kanjikana.WithDeniedHosts(f.denyHosts...))
+	}
+	for _, re := range f.includeURLs {
+		options = append(options, kanjikana.WithLinkPattern(re))
+	}
+	for _, re := range f.excludeURLs {
+		options = append(options, kanjikana.WithExcludePattern(re))
+	}
+	if f.timeout > 0 {
+		options = append(options, kanjikana.WithTimeout(f.timeout))
+	}
+	if !f.deadline.IsZero() {
+		options = append(options, kanjikana.WithCrawlDeadline(f.deadline))
+	}
+	if f.fetchTimeout > 0 {
+		options = append(options, kanjikana.WithRequestTimeout(f.fetchTimeout))
+	}
+	if f.proxyURL != "" {
+		options = append(options, kanjikana.WithProxy(f.proxyURL))
+	}
+	if f.render {
+		renderer, stopRenderer, err := newChromeRenderer(f.renderWait, f.proxyURL)
+		if err != nil {
+			fatal(err)
+		}
+		defer stopRenderer()
+		options = append(options, kanjikana.WithRenderer(renderer))
+	}
+	for _, cookie := range f.cookies {
+		options = append(options, kanjikana.WithCookie(cookie))
+	}
+	if f.basicAuth != "" {
+		username, password, _ := strings.Cut(f.basicAuth, ":")
+		options = append(options, kanjikana.WithBasicAuth(username, password))
+	}
+	if f.retries > 0 {
+		options = append(options, kanjikana.WithRetries(f.retries))
+	}
+	if f.sitemap {
+		options = append(options, kanjikana.WithSitemap())
+	}
+	if f.feed != "" {
+		options = append(options, kanjikana.WithFeed())
+	}
+	options = append(options, presetOptions...)
+	if f.maxPages > 0 {
+		options = append(options, kanjikana.WithMaxPages(f.maxPages))
+	}
+	if f.stopKanjis > 0 {
+		options = append(options, kanjikana.WithStopAfterUniqueKanjis(f.stopKanjis))
+	}
+	if f.stopChars > 0 {
+		options = append(options, kanjikana.WithStopAfterCharacters(f.stopChars))
+	}
+	if f.sample > 0 {
+		options = append(options, kanjikana.WithLinkSample(f.sample))
+	}
+	if f.wayback != "" {
+		options = append(options, kanjikana.WithWayback(f.wayback))
+	}
+	if f.mainContent {
+		options = append(options, kanjikana.WithMainContent())
+	}
+	if f.dedupBlocks {
+		options = append(options, kanjikana.WithBoilerplateDedup())
+	}
+	if f.warcOut != "" {
+		file, err := os.Create(f.warcOut)
+		if err != nil {
+			fatal(err)
+		}
+		defer file.Close()
+		options = append(options, kanjikana.WithWARCOutput(file, strings.HasSuffix(strings.ToLower(f.warcOut), ".gz")))
+	}
+	if f.cacheDir != "" {
+		options = append(options, kanjikana.WithCacheDir(f.cacheDir))
+	}
+	if f.rateLimit > 0 {
+		options = append(options, kanjikana.WithRateLimit(f.rateLimit))
+	}
+
+	// The text is kept for the furigana output: stdin is copied while it is
+	// counted and crawled pages are collected by a page handler.
+	var (
+		stdin      io.Reader = os.Stdin
+		stdinText  strings.Builder
+		sections   []textSection
+		sectionsMu sync.Mutex
+	)
+	if f.furiganaFile != "" {
+		stdin = io.TeeReader(os.Stdin, &stdinText)
+		options = append(options, kanjikana.WithPageHandler(func(pageURL, text string) {
+			sectionsMu.Lock()
+			sections = append(sections, textSection{title: pageURL, text: text})
+			sectionsMu.Unlock()
+		}))
+	}
+
+	if progress != nil {
+		options = append(options, kanjikana.WithProgress(progress.update))
+	}
+
+	// Interrupting the count stops fetching new pages and reports the counts
+	// gathered so far. A second interrupt quits right away.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	// Repeating -url crawls the websites in parallel.
+	crawlURLs := []string{f.url}
+	if len(f.urls) > 1 {
+		crawlURLs = f.urls
+	}
+	crawl := func() (*kanjikana.Result, error) {
+		if len(crawlURLs) > 1 {
+			return kanjikana.ScrapeRoots(ctx, crawlURLs, options...)
+		}
+		return scrape(ctx, f.url, options...)
+	}
+
+	startExecTime := time.Now()
+
+	var (
+		res    *kanjikana.Result
+		source string
+	)
+	switch {
+	case command == wikipediaCommand:
+		source = fs.Arg(0)
+		res, err = countWikipedia(fs.Arg(0), countOptions...)
+	case command == aozoraCommand:
+		source = "Aozora Bunko " + strings.Join(fs.Args(), ", ")
+		res, err = countAozora(ctx, fs.Args(), countOptions...)
+	case command == youtubeCommand:
+		source = "YouTube " + strings.Join(fs.Args(), ", ")
+		res, err = countYouTube(ctx, fs.Args(), countOptions...)
+	case f.inputFile == stdinInput || f.url == stdinInput:
+		source = stdinInput
+		res, err = kanjikana.CountReader(stdin, countOptions...)
+	case f.inputFile != "":
+		source = f.inputFile
+		res, err = countFile(f.inputFile, countOptions...)
+	case f.inputDir != "":
+		source = f.inputDir
+		res, err = kanjikana.CountDir(f.inputDir, countOptions...)
+	case f.warcFile != "":
+		source = f.warcFile
+		res, err = scrapeWARC(ctx, f.warcFile, options...)
+	case command == legacyCommand && !isFlagSet(fs, "url") && isStdinPiped():
+		source = stdinInput
+		res, err = kanjikana.CountReader(stdin, countOptions...)
+	default:
+		source = strings.Join(crawlURLs, ", ")
+		res, err = crawl()
+	}
+	if progress != nil {
+		progress.finish()
+	}
+	if err != nil && ctx.Err() != nil && res != nil {
+		slog.Warn("interrupted: reporting the counts gathered so far", "characters", res.AllCharactersCount)
+		err = nil
+	}
+	if err != nil {
+		fatal(err)
+	}
+
+	// save stores a result in the database given with -db, if any.
+	save := func(res *kanjikana.Result, startedAt time.Time) error {
+		if f.dbPath == "" {
+			return nil
+		}
+		meta := crawlMetadata{
+			source:      source,
+			searchDepth: f.searchDepth,
+			startedAt:   startedAt,
+			finishedAt:  time.Now(),
+		}
+		return saveResult(f.dbPath, meta, res, f.appendDB)
+	}
+	if err := save(res, startExecTime); err != nil {
+		fatal(err)
+	}
+	crawled := res
+	if f.appendDB {
+		var crawls int
+		res, crawls, err = loadCorpus(f.dbPath)
+		if err != nil {
+			fatal(err)
+		}
+		slog.Info("appended to corpus", "crawls", crawls, "characters", res.AllCharactersCount)
+	}
+
+	var w io.Writer = os.Stdout
+	if f.outputFile != "" {
+		out, err := os.Create(f.outputFile)
+		if err != nil {
+			fatal(err)
+		}
+		defer out.Close()
+		w = out
+	}
+
+	rep := &report{res: res, rankingSize: f.rankingSize, joyo: f.joyo, strokes: f.strokes, chapters: f.chapters, coverage: f.coverage, distribution: f.distribution, blocks: f.blocks}
+	if f.jlptFile != "" {
+		rep.jlpt, err = loadKanjiLevels(f.jlptFile)
+		if err != nil {
+			fatal(err)
+		}
+	} else if f.jlpt {
+		rep.jlpt = kanjikana.JLPTLevels()
+	}
+	if f.grades {
+		rep.grades = kanjikana.SchoolGrades()
+	}
+	if f.histogram {
+		rep.histogramWidth = terminalWidth(w)
+	}
+	rep.color = !f.noColor && useColor(w)
+	if f.readings {
+		rep.readings = kanjikana.BundledReadings()
+	}
+	if f.kanjidicFile != "" {
+		rep.kanjidic, err = loadKanjidic(f.kanjidicFile)
+		if err != nil {
+			fatal(err)
+		}
+	}
+	rep.jmdict = jmdict
+	if len(f.kradfiles) > 0 {
+		rep.kradfile, err = loadKradfiles(f.kradfiles)
+		if err != nil {
+			fatal(err)
+		}
+	}
+	if f.wanikani {
+		token := os.Getenv(wanikaniTokenEnvVar)
+		if token == "" {
+			fatalf("-wanikani requires an API token in $%s", wanikaniTokenEnvVar)
+		}
+		rep.wanikani, err = fetchWaniKaniKanji(context.Background(), token)
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	if err := writeResult(w, f.outputFormat, rep); err != nil {
+		fatal(err)
+	}
+
+	if f.ankiFile != "" {
+		if err := writeAnkiFile(f.ankiFile, rep); err != nil {
+			fatal(err)
+		}
+	}
+
+	if f.reportFile != "" {
+		if err := writeHTMLReport(f.reportFile, source, rep); err != nil {
+			fatal(err)
+		}
+	}
+
+	if f.chartsDir != "" {
+		if err := writeCharts(f.chartsDir, res); err != nil {
+			fatal(err)
+		}
+	}
+
+	if f.furiganaFile != "" {
+		switch {
+		case source == stdinInput:
+			sections = []textSection{{text: stdinText.String()}}
+		case source == f.inputFile:
+			text, err := readText(f.inputFile)
+			if err != nil {
+				fatal(err)
+			}
+			sections = []textSection{{title: f.inputFile, text: text}}
+		case source == f.inputDir:
+			slog.Warn("furigana output is not available for directories")
+		}
+		if err := writeFuriganaFile(f.furiganaFile, sections, rep, f.furiganaMin); err != nil {
+			fatal(err)
+		}
+	}
+
+	slog.Info("done", "duration", time.Since(startExecTime))
+
+	if sched != nil {
+		watch(ctx, w, sched, crawled, startExecTime, f.rankingSize, func() (*kanjikana.Result, error) {
+			startedAt := time.Now()
+			res, err := crawl()
+			if progress != nil {
+				progress.finish()
+			}
+			if err != nil {
+				return nil, err
+			}
+			// The crawled text is only written to the furigana file once.
+			sectionsMu.Lock()
+			sections = nil
+			sectionsMu.Unlock()
+			return res, save(res, startedAt)
+		})
+	}
+}
+
+// setCommandUsage sets the usage message of the flag set of a subcommand.
+func setCommandUsage(fs *flag.FlagSet, synopsis, description string) {
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s\n\n%s\n\nFlags:\n", os.Args[0], synopsis, description)
+		fs.PrintDefaults()
+	}
+}
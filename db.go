@@ -0,0 +1,452 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const dbSchema = `
+CREATE TABLE IF NOT EXISTS crawls (
+	id                   INTEGER PRIMARY KEY AUTOINCREMENT,
+	source               TEXT NOT NULL,
+	search_depth         INTEGER NOT NULL,
+	started_at           TIMESTAMP NOT NULL,
+	finished_at          TIMESTAMP NOT NULL,
+	all_characters_count INTEGER NOT NULL,
+	unique_count         INTEGER NOT NULL,
+	page_count           INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS character_counts (
+	crawl_id  INTEGER NOT NULL REFERENCES crawls(id),
+	character TEXT NOT NULL,
+	category  TEXT NOT NULL,
+	count     INTEGER NOT NULL,
+	PRIMARY KEY (crawl_id, category, character)
+);
+
+CREATE TABLE IF NOT EXISTS pages (
+	crawl_id             INTEGER NOT NULL REFERENCES crawls(id),
+	url                  TEXT NOT NULL,
+	all_characters_count INTEGER NOT NULL,
+	kanji_count          INTEGER NOT NULL,
+	hiragana_count       INTEGER NOT NULL,
+	katakana_count       INTEGER NOT NULL,
+	final_url            TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS page_kanji_counts (
+	crawl_id  INTEGER NOT NULL REFERENCES crawls(id),
+	url       TEXT NOT NULL,
+	character TEXT NOT NULL,
+	count     INTEGER NOT NULL,
+	PRIMARY KEY (crawl_id, url, character)
+);
+
+CREATE TABLE IF NOT EXISTS page_metadata (
+	crawl_id  INTEGER NOT NULL REFERENCES crawls(id),
+	url       TEXT NOT NULL,
+	title     TEXT NOT NULL,
+	published TEXT NOT NULL,
+	lang      TEXT NOT NULL,
+	PRIMARY KEY (crawl_id, url)
+);
+
+CREATE TABLE IF NOT EXISTS corpus (
+	id                   INTEGER PRIMARY KEY CHECK (id = 1),
+	all_characters_count INTEGER NOT NULL,
+	crawl_count          INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS corpus_counts (
+	character TEXT NOT NULL,
+	category  TEXT NOT NULL,
+	count     INTEGER NOT NULL,
+	PRIMARY KEY (category, character)
+);
+`
+
+// crawlMetadata describes how a result was produced.
+type crawlMetadata struct {
+	source      string
+	searchDepth int
+	startedAt   time.Time
+	finishedAt  time.Time
+}
+
+// saveResult stores res as a new crawl in the SQLite database at path,
+// creating the tables if needed. With accumulate, its counts are also added
+// to the cumulative totals of the corpus tables.
+func saveResult(path string, meta crawlMetadata, res *kanjikana.Result, accumulate bool) error {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(dbSchema); err != nil {
+		return err
+	}
+	// The pages tables of databases written before the final URLs were
+	// stored lack their column.
+	finalURL, err := hasColumn(db, "pages", "final_url")
+	if err != nil {
+		return err
+	}
+	if !finalURL {
+		if _, err := db.Exec(`ALTER TABLE pages ADD COLUMN final_url TEXT NOT NULL DEFAULT ''`); err != nil {
+			return err
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Merged results and archives counted with their duplicates may list a
+	// page several times, but every page is stored once.
+	pages := kanjikana.UniquePages(res.Pages)
+	crawl, err := tx.Exec(
+		`INSERT INTO crawls (source, search_depth, started_at, finished_at, all_characters_count, unique_count, page_count)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		meta.source, meta.searchDepth, meta.startedAt, meta.finishedAt,
+		res.AllCharactersCount, res.UniqueCount, len(pages),
+	)
+	if err != nil {
+		return err
+	}
+	crawlID, err := crawl.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	insertCount, err := tx.Prepare(`INSERT INTO character_counts (crawl_id, character, category, count) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insertCount.Close()
+
+	categories := []struct {
+		name string
+		m    map[string]int
+	}{
+		{kanjikana.CategoryKanji, res.Kanjis},
+		{kanjikana.CategoryHiragana, res.Hiraganas},
+		{kanjikana.CategoryKatakana, res.Katakanas},
+		{kanjikana.CategoryPunctuation, res.Punctuation},
+	}
+	for _, category := range categories {
+		for c, count := range category.m {
+			if _, err := insertCount.Exec(crawlID, c, category.name, count); err != nil {
+				return err
+			}
+		}
+	}
+
+	insertPage, err := tx.Prepare(`INSERT INTO pages (crawl_id, url, all_characters_count, kanji_count, hiragana_count, katakana_count, final_url) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insertPage.Close()
+
+	insertPageKanji, err := tx.Prepare(`INSERT INTO page_kanji_counts (crawl_id, url, character, count) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insertPageKanji.Close()
+
+	insertPageMetadata, err := tx.Prepare(`INSERT INTO page_metadata (crawl_id, url, title, published, lang) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insertPageMetadata.Close()
+
+	for _, page := range pages {
+		_, err := insertPage.Exec(crawlID, page.URL, page.AllCharactersCount, page.KanjiCount, page.HiraganaCount, page.KatakanaCount, page.FinalURL)
+		if err != nil {
+			return err
+		}
+		for kanji, count := range page.Kanjis {
+			if _, err := insertPageKanji.Exec(crawlID, page.URL, kanji, count); err != nil {
+				return err
+			}
+		}
+		if page.Title != "" || page.Published != "" || page.Lang != "" {
+			if _, err := insertPageMetadata.Exec(crawlID, page.URL, page.Title, page.Published, page.Lang); err != nil {
+				return err
+			}
+		}
+	}
+
+	if accumulate {
+		if err := addToCorpus(tx, res); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// addToCorpus adds the counts of res to the cumulative totals.
+func addToCorpus(tx *sql.Tx, res *kanjikana.Result) error {
+	_, err := tx.Exec(
+		`INSERT INTO corpus (id, all_characters_count, crawl_count) VALUES (1, ?, 1)
+		ON CONFLICT (id) DO UPDATE SET
+			all_characters_count = all_characters_count + excluded.all_characters_count,
+			crawl_count = crawl_count + 1`,
+		res.AllCharactersCount,
+	)
+	if err != nil {
+		return err
+	}
+
+	addCount, err := tx.Prepare(
+		`INSERT INTO corpus_counts (character, category, count) VALUES (?, ?, ?)
+		ON CONFLICT (category, character) DO UPDATE SET count = count + excluded.count`,
+	)
+	if err != nil {
+		return err
+	}
+	defer addCount.Close()
+
+	categories := []struct {
+		name string
+		m    map[string]int
+	}{
+		{kanjikana.CategoryKanji, res.Kanjis},
+		{kanjikana.CategoryHiragana, res.Hiraganas},
+		{kanjikana.CategoryKatakana, res.Katakanas},
+		{kanjikana.CategoryPunctuation, res.Punctuation},
+	}
+	for _, category := range categories {
+		for c, count := range category.m {
+			if _, err := addCount.Exec(c, category.name, count); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// loadCorpus reads the cumulative totals of the crawls stored with
+// accumulation in the SQLite database at path, along with their number.
+func loadCorpus(path string) (*kanjikana.Result, int, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, 0, err
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer db.Close()
+
+	res := newStoredResult()
+	var crawls int
+	err = db.QueryRow(`SELECT all_characters_count, crawl_count FROM corpus WHERE id = 1`).Scan(&res.AllCharactersCount, &crawls)
+	if err != nil {
+		// The corpus tables are missing or empty until a crawl is appended.
+		return nil, 0, fmt.Errorf("%s: no crawl appended to the corpus", path)
+	}
+
+	rows, err := db.Query(`SELECT character, category, count FROM corpus_counts`)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	if err := scanCounts(rows, res); err != nil {
+		return nil, 0, err
+	}
+
+	res.CountUnique()
+	return res, crawls, nil
+}
+
+// newStoredResult returns an empty result to read stored counts into.
+func newStoredResult() *kanjikana.Result {
+	return &kanjikana.Result{
+		Kanjis:    make(map[string]int),
+		Hiraganas: make(map[string]int),
+		Katakanas: make(map[string]int),
+	}
+}
+
+// scanCounts adds the character, category and count rows to res.
+func scanCounts(rows *sql.Rows, res *kanjikana.Result) error {
+	categories := map[string]map[string]int{
+		kanjikana.CategoryKanji:    res.Kanjis,
+		kanjikana.CategoryHiragana: res.Hiraganas,
+		kanjikana.CategoryKatakana: res.Katakanas,
+	}
+	for rows.Next() {
+		var (
+			c, category string
+			count       int
+		)
+		if err := rows.Scan(&c, &category, &count); err != nil {
+			return err
+		}
+		if m, ok := categories[category]; ok {
+			m[c] = count
+		} else if category == kanjikana.CategoryPunctuation {
+			if res.Punctuation == nil {
+				res.Punctuation = make(map[string]int)
+			}
+			res.Punctuation[c] = count
+		}
+	}
+	return rows.Err()
+}
+
+// loadResult reads the crawl crawlID from the SQLite database at path, or
+// the latest crawl when crawlID is 0, along with its source.
+func loadResult(path string, crawlID int64) (*kanjikana.Result, string, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, "", err
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer db.Close()
+
+	if crawlID == 0 {
+		if err := db.QueryRow(`SELECT MAX(id) FROM crawls`).Scan(&crawlID); err != nil {
+			return nil, "", fmt.Errorf("%s: no crawl stored", path)
+		}
+	}
+
+	res := newStoredResult()
+	var source string
+	err = db.QueryRow(`SELECT source, all_characters_count FROM crawls WHERE id = ?`, crawlID).Scan(&source, &res.AllCharactersCount)
+	if err == sql.ErrNoRows {
+		return nil, "", fmt.Errorf("%s: no crawl with id %d", path, crawlID)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := db.Query(`SELECT character, category, count FROM character_counts WHERE crawl_id = ?`, crawlID)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	if err := scanCounts(rows, res); err != nil {
+		return nil, "", err
+	}
+
+	finalURL, err := hasColumn(db, "pages", "final_url")
+	if err != nil {
+		return nil, "", err
+	}
+	finalURLColumn := "''"
+	if finalURL {
+		finalURLColumn = "final_url"
+	}
+	pages, err := db.Query(`SELECT url, all_characters_count, kanji_count, hiragana_count, katakana_count, `+finalURLColumn+` FROM pages WHERE crawl_id = ?`, crawlID)
+	if err != nil {
+		return nil, "", err
+	}
+	defer pages.Close()
+	for pages.Next() {
+		var page kanjikana.PageStats
+		if err := pages.Scan(&page.URL, &page.AllCharactersCount, &page.KanjiCount, &page.HiraganaCount, &page.KatakanaCount, &page.FinalURL); err != nil {
+			return nil, "", err
+		}
+		res.Pages = append(res.Pages, page)
+	}
+	if err := pages.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if err := loadPageKanjis(db, crawlID, res.Pages); err != nil {
+		return nil, "", err
+	}
+	if err := loadPageMetadata(db, crawlID, res.Pages); err != nil {
+		return nil, "", err
+	}
+
+	res.CountUnique()
+	return res, source, nil
+}
+
+// hasColumn reports whether the table of the database has the column.
+func hasColumn(db *sql.DB, table, column string) (bool, error) {
+	var found bool
+	err := db.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&found)
+	return found, err
+}
+
+// loadPageKanjis reads the kanji counts of the pages of the crawl with the
+// given id. Databases written before they were stored lack their table and
+// leave the counts empty.
+func loadPageKanjis(db *sql.DB, crawlID int64, pages []kanjikana.PageStats) error {
+	var stored bool
+	err := db.QueryRow(`SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = 'page_kanji_counts'`).Scan(&stored)
+	if err != nil || !stored {
+		return err
+	}
+
+	pageIndex := make(map[string]int, len(pages))
+	for i, page := range pages {
+		pageIndex[page.URL] = i
+	}
+	rows, err := db.Query(`SELECT url, character, count FROM page_kanji_counts WHERE crawl_id = ?`, crawlID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pageURL, kanji string
+		var count int
+		if err := rows.Scan(&pageURL, &kanji, &count); err != nil {
+			return err
+		}
+		i, ok := pageIndex[pageURL]
+		if !ok {
+			continue
+		}
+		if pages[i].Kanjis == nil {
+			pages[i].Kanjis = make(map[string]int)
+		}
+		pages[i].Kanjis[kanji] = count
+	}
+	return rows.Err()
+}
+
+// loadPageMetadata reads the title, publication date and language of the
+// pages of the crawl with the given id, which databases written before they
+// were stored lack.
+func loadPageMetadata(db *sql.DB, crawlID int64, pages []kanjikana.PageStats) error {
+	var stored bool
+	err := db.QueryRow(`SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = 'page_metadata'`).Scan(&stored)
+	if err != nil || !stored {
+		return err
+	}
+
+	pageIndex := make(map[string]int, len(pages))
+	for i, page := range pages {
+		pageIndex[page.URL] = i
+	}
+	rows, err := db.Query(`SELECT url, title, published, lang FROM page_metadata WHERE crawl_id = ?`, crawlID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pageURL, title, published, lang string
+		if err := rows.Scan(&pageURL, &title, &published, &lang); err != nil {
+			return err
+		}
+		if i, ok := pageIndex[pageURL]; ok {
+			pages[i].Title, pages[i].Published, pages[i].Lang = title, published, lang
+		}
+	}
+	return rows.Err()
+}
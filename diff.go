@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+)
+
+const diffCommand = "diff"
+
+// sqliteHeader starts every SQLite database file.
+const sqliteHeader = "SQLite format 3\x00"
+
+func runDiff(args []string) {
+	fs := flag.NewFlagSet(diffCommand, flag.ExitOnError)
+	rankingSize := fs.Int("ranksize", defaultRankingSize, "maximum number of characters listed per category")
+	oldCrawl := fs.Int64("old-crawl", 0, "id of the crawl to compare when the first result is a SQLite database (0 means the latest)")
+	newCrawl := fs.Int64("new-crawl", 0, "id of the crawl to compare when the second result is a SQLite database (0 means the latest)")
+	var lf logFlags
+	lf.register(fs)
+	setCommandUsage(fs, "diff [flags] old new", "Compare two results saved with -output json or -db.")
+	fs.Parse(args)
+	lf.setup(os.Stderr)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	older, err := readResultFile(fs.Arg(0), *oldCrawl)
+	if err != nil {
+		fatal(err)
+	}
+	newer, err := readResultFile(fs.Arg(1), *newCrawl)
+	if err != nil {
+		fatal(err)
+	}
+	writeDiff(os.Stdout, older, newer, *rankingSize)
+}
+
+// readResultFile reads a result written with -output json, or the crawl
+// with the given id of a database written with -db.
+func readResultFile(path string, crawlID int64) (*kanjikana.Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, []byte(sqliteHeader)) {
+		res, _, err := loadResult(path, crawlID)
+		return res, err
+	}
+	var res kanjikana.Result
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &res, nil
+}
+
+// writeDiff lists, for every category, the most common characters found in
+// only one of the results, and the characters found in both whose rank and
+// relative frequency changed the most.
+func writeDiff(w io.Writer, older, newer *kanjikana.Result, rankingSize int) {
+	categories := []struct {
+		name         string
+		older, newer map[string]int
+	}{
+		{"Kanji", older.Kanjis, newer.Kanjis},
+		{"Hiragana", older.Hiraganas, newer.Hiraganas},
+		{"Katakana", older.Katakanas, newer.Katakanas},
+		{"Words", older.Words, newer.Words},
+		{"Compounds", older.Compounds, newer.Compounds},
+		{"Punctuation", older.Punctuation, newer.Punctuation},
+		{"Parts of speech", kanjikana.Totals(older.PartsOfSpeech), kanjikana.Totals(newer.PartsOfSpeech)},
+	}
+	for _, category := range categories {
+		printOnlyIn(w, category.name, "only in the first result", older, category.older, category.newer, rankingSize)
+		printOnlyIn(w, category.name, "only in the second result", newer, category.newer, category.older, rankingSize)
+		printChanges(w, category.name, older, newer, category.older, category.newer, rankingSize)
+	}
+}
+
+// printOnlyIn prints the most common entries of m, counted in res, missing
+// from other.
+func printOnlyIn(w io.Writer, category, title string, res *kanjikana.Result, m, other map[string]int, rankingSize int) {
+	var only []string
+	for _, c := range kanjikana.MostCommonCharacters(m) {
+		if _, ok := other[c]; !ok {
+			only = append(only, c)
+		}
+	}
+	if len(only) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s %s (%d):\n", category, title, len(only))
+	lines := make([]rankingLine, min(len(only), rankingSize))
+	for i := range lines {
+		lines[i] = rankingLine{label: only[i], count: m[only[i]]}
+	}
+	printRanking(w, &report{res: res}, lines)
+}
+
+// change is how an entry found in both results moved between them. The
+// frequencies are per 1,000 Japanese characters of the results.
+type change struct {
+	entry            string
+	oldRank, newRank int
+	oldFreq, newFreq float64
+}
+
+// printChanges prints the entries of the maps oldCounts and newCounts,
+// counted in older and newer, found in both whose rank changed the most,
+// then those whose frequency per 1,000 Japanese characters changed the most.
+func printChanges(w io.Writer, category string, older, newer *kanjikana.Result, oldCounts, newCounts map[string]int, rankingSize int) {
+	oldRanks := ranks(oldCounts)
+	newRanks := ranks(newCounts)
+	var changes []change
+	for c, newRank := range newRanks {
+		oldRank, ok := oldRanks[c]
+		if !ok {
+			continue
+		}
+		changes = append(changes, change{
+			entry:   c,
+			oldRank: oldRank,
+			newRank: newRank,
+			oldFreq: kanjikana.PerThousand(oldCounts[c], older.AllCharactersCount),
+			newFreq: kanjikana.PerThousand(newCounts[c], newer.AllCharactersCount),
+		})
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	rankDelta := func(c change) float64 { return float64(c.oldRank - c.newRank) }
+	moved := largestChanges(changes, rankDelta, rankingSize)
+	if len(moved) > 0 {
+		fmt.Fprintf(w, "%s rank changes:\n", category)
+		for i, c := range moved {
+			fmt.Fprintf(w, "%4d. %v %d → %d (%+d)\n", i+1, c.entry, c.oldRank, c.newRank, c.oldRank-c.newRank)
+		}
+		fmt.Fprintln(w)
+	}
+
+	freqDelta := func(c change) float64 { return c.newFreq - c.oldFreq }
+	shifted := largestChanges(changes, freqDelta, rankingSize)
+	if len(shifted) > 0 {
+		fmt.Fprintf(w, "%s frequency shifts (per 1,000):\n", category)
+		for i, c := range shifted {
+			fmt.Fprintf(w, "%4d. %v %.2f → %.2f (%+.2f)\n", i+1, c.entry, c.oldFreq, c.newFreq, freqDelta(c))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// ranks returns the rank of every entry of m, starting at 1 for the most
+// common. Entries with the same count share the same rank.
+func ranks(m map[string]int) map[string]int {
+	r := make(map[string]int, len(m))
+	var rank, previous int
+	for i, c := range kanjikana.MostCommonCharacters(m) {
+		if i == 0 || m[c] != previous {
+			rank, previous = i+1, m[c]
+		}
+		r[c] = rank
+	}
+	return r
+}
+
+// largestChanges returns up to n changes with a non-zero delta, by
+// decreasing absolute delta, then by new rank.
+func largestChanges(changes []change, delta func(change) float64, n int) []change {
+	var largest []change
+	for _, c := range changes {
+		if delta(c) != 0 {
+			largest = append(largest, c)
+		}
+	}
+	sort.Slice(largest, func(i, j int) bool {
+		di, dj := math.Abs(delta(largest[i])), math.Abs(delta(largest[j]))
+		if di != dj {
+			return di > dj
+		}
+		if largest[i].newRank != largest[j].newRank {
+			return largest[i].newRank < largest[j].newRank
+		}
+		return largest[i].entry < largest[j].entry
+	})
+	return largest[:min(len(largest), n)]
+}
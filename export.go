@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+)
+
+const exportCommand = "export"
+
+func runExport(args []string) {
+	fs := flag.NewFlagSet(exportCommand, flag.ExitOnError)
+	crawlID := fs.Int64("crawl", 0, "id of the crawl to export (0 means the latest)")
+	corpus := fs.Bool("corpus", false, "export the cumulative totals of the crawls stored with -append instead of a single crawl")
+	rankingSize := fs.Int("ranksize", defaultRankingSize, "ranking size")
+	outputFormat := fs.String("output", textOutput, "output format (text, json, csv, tsv)")
+	outputFile := fs.String("outfile", "", "write output to file instead of stdout")
+	reportFile := fs.String("report", "", "also write a standalone HTML report with charts and sortable rankings")
+	chartsDir := fs.String("charts", "", "also write SVG frequency bar charts and a kanji coverage curve to this directory")
+	var lf logFlags
+	lf.register(fs)
+	setCommandUsage(fs, "export [flags] results.sqlite", "Write a result stored with -db in any output format.")
+	fs.Parse(args)
+	lf.setup(os.Stderr)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if _, ok := outputFormats[*outputFormat]; !ok {
+		fatalf("unknown output format: %s", *outputFormat)
+	}
+
+	var (
+		res    *kanjikana.Result
+		source string
+		err    error
+	)
+	if *corpus {
+		var crawls int
+		res, crawls, err = loadCorpus(fs.Arg(0))
+		source = fmt.Sprintf("%s (%d crawls)", fs.Arg(0), crawls)
+	} else {
+		res, source, err = loadResult(fs.Arg(0), *crawlID)
+	}
+	if err != nil {
+		fatal(err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *outputFile != "" {
+		f, err := os.Create(*outputFile)
+		if err != nil {
+			fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	rep := &report{res: res, rankingSize: *rankingSize}
+	if err := writeResult(w, *outputFormat, rep); err != nil {
+		fatal(err)
+	}
+	if *reportFile != "" {
+		if err := writeHTMLReport(*reportFile, source, rep); err != nil {
+			fatal(err)
+		}
+	}
+	if *chartsDir != "" {
+		if err := writeCharts(*chartsDir, res); err != nil {
+			fatal(err)
+		}
+	}
+}
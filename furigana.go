@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"html"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+)
+
+// textSection is a piece of counted text, such as a crawled page, re-emitted
+// with furigana.
+type textSection struct {
+	title string
+	text  string
+}
+
+const furiganaHeader = `<!DOCTYPE html>
+<html lang="ja">
+<head>
+<meta charset="utf-8">
+<title>Furigana</title>
+<style>
+body { font-family: sans-serif; line-height: 2.2; max-width: 50em; margin: 2em auto; }
+rt { font-size: 0.55em; color: #555; }
+h2 { font-size: 1em; color: #555; word-break: break-all; }
+</style>
+</head>
+<body>
+`
+
+const furiganaFooter = `</body>
+</html>
+`
+
+// writeFuriganaFile writes sections to path as an HTML page where every
+// kanji counted at least minCount times is annotated with its reading.
+func writeFuriganaFile(path string, sections []textSection, rep *report, minCount int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	writeFurigana(w, sections, rep, minCount)
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeFurigana(w io.Writer, sections []textSection, rep *report, minCount int) {
+	io.WriteString(w, furiganaHeader)
+	for _, section := range sections {
+		if section.title != "" {
+			io.WriteString(w, "<h2>"+html.EscapeString(section.title)+"</h2>\n")
+		}
+		io.WriteString(w, "<p>\n")
+		for _, line := range strings.Split(section.text, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			for _, r := range line {
+				c := string(r)
+				reading := ""
+				if rep.res.Kanjis[c] >= minCount {
+					reading = rep.kanjiReading(c)
+				}
+				if reading == "" {
+					io.WriteString(w, html.EscapeString(c))
+					continue
+				}
+				io.WriteString(w, "<ruby>"+c+"<rt>"+html.EscapeString(reading)+"</rt></ruby>")
+			}
+			io.WriteString(w, "<br>\n")
+		}
+		io.WriteString(w, "</p>\n")
+	}
+	io.WriteString(w, furiganaFooter)
+}
+
+// kanjiReading returns the reading of kanji in hiragana, taken from the
+// KANJIDIC2 dictionary when one is loaded and from the bundled reading table
+// otherwise.
+func (rep *report) kanjiReading(kanji string) string {
+	var reading string
+	if rep.kanjidic != nil {
+		if info := rep.kanjidic.Lookup(kanji); info != nil {
+			switch {
+			case len(info.OnReadings) > 0:
+				reading = info.OnReadings[0]
+			case len(info.KunReadings) > 0:
+				reading = info.KunReadings[0]
+			}
+		}
+	}
+	if reading == "" {
+		readings := rep.readings
+		if readings == nil {
+			readings = kanjikana.BundledReadings()
+		}
+		reading = readings.Reading(kanji)
+	}
+	// KANJIDIC2 marks okurigana with a dot and affixes with a hyphen.
+	reading, _, _ = strings.Cut(reading, ".")
+	reading = strings.Trim(reading, "-")
+	return kanjikana.ToHiragana(reading)
+}
+
+// readText returns the text of a local file, or its visible text for HTML
+// files.
+func readText(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if kanjikana.IsPDFFile(path) {
+		info, err := f.Stat()
+		if err != nil {
+			return "", err
+		}
+		return kanjikana.PDFText(f, info.Size())
+	}
+	if kanjikana.IsEPUBFile(path) {
+		info, err := f.Stat()
+		if err != nil {
+			return "", err
+		}
+		chapters, err := kanjikana.EPUBChapters(f, info.Size())
+		if err != nil {
+			return "", err
+		}
+		texts := make([]string, len(chapters))
+		for i, chapter := range chapters {
+			texts[i] = chapter.Text
+		}
+		return strings.Join(texts, "\n"), nil
+	}
+	if kanjikana.IsSubtitleFile(path) {
+		return kanjikana.SubtitleText(f)
+	}
+	if kanjikana.IsHTMLFile(path) {
+		return kanjikana.VisibleText(f)
+	}
+	data, err := io.ReadAll(f)
+	return string(data), err
+}
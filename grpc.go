@@ -0,0 +1,144 @@
+package main
+
+import (
+	"io"
+
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// grpcFileDescriptor describes the messages of proto/kanjikana.proto, in the
+// protobuf text format. No Go code is generated for them: they are handled
+// as dynamic messages, so both files must be kept in sync.
+const grpcFileDescriptor = `
+name: "proto/kanjikana.proto"
+package: "kanjikana.v1"
+syntax: "proto3"
+message_type {
+  name: "TextChunk"
+  field { name: "text" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "text" }
+}
+message_type {
+  name: "CharacterCount"
+  field { name: "character" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "character" }
+  field { name: "count" number: 2 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "count" }
+  field { name: "per_thousand" number: 3 label: LABEL_OPTIONAL type: TYPE_DOUBLE json_name: "perThousand" }
+}
+message_type {
+  name: "FrequencyResult"
+  field { name: "all_characters_count" number: 1 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "allCharactersCount" }
+  field { name: "unique_count" number: 2 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "uniqueCount" }
+  field { name: "kanji_unique_count" number: 3 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "kanjiUniqueCount" }
+  field { name: "kana_unique_count" number: 4 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "kanaUniqueCount" }
+  field { name: "hiragana_unique_count" number: 5 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "hiraganaUniqueCount" }
+  field { name: "katakana_unique_count" number: 6 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "katakanaUniqueCount" }
+  field { name: "kanjis" number: 7 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".kanjikana.v1.CharacterCount" json_name: "kanjis" }
+  field { name: "hiraganas" number: 8 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".kanjikana.v1.CharacterCount" json_name: "hiraganas" }
+  field { name: "katakanas" number: 9 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".kanjikana.v1.CharacterCount" json_name: "katakanas" }
+}
+service {
+  name: "Counter"
+  method { name: "Count" input_type: ".kanjikana.v1.TextChunk" output_type: ".kanjikana.v1.FrequencyResult" client_streaming: true }
+}
+`
+
+var grpcMessages = mustGRPCMessages()
+
+func mustGRPCMessages() protoreflect.MessageDescriptors {
+	var fdp descriptorpb.FileDescriptorProto
+	if err := prototext.Unmarshal([]byte(grpcFileDescriptor), &fdp); err != nil {
+		panic(err)
+	}
+	fd, err := protodesc.NewFile(&fdp, nil)
+	if err != nil {
+		panic(err)
+	}
+	return fd.Messages()
+}
+
+// countService implements the kanjikana.v1.Counter gRPC service.
+type countService interface {
+	count(stream grpc.ServerStream) error
+}
+
+var countServiceDesc = grpc.ServiceDesc{
+	ServiceName: "kanjikana.v1.Counter",
+	HandlerType: (*countService)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName: "Count",
+			Handler: func(srv any, stream grpc.ServerStream) error {
+				return srv.(countService).count(stream)
+			},
+			ClientStreams: true,
+		},
+	},
+	Metadata: "proto/kanjikana.proto",
+}
+
+// count counts the text chunks received on stream and sends back the
+// frequencies once the client is done sending.
+func (srv *server) count(stream grpc.ServerStream) error {
+	counter, err := kanjikana.NewCounter()
+	if err != nil {
+		return err
+	}
+
+	chunkDesc := grpcMessages.ByName("TextChunk")
+	text := chunkDesc.Fields().ByName("text")
+	for {
+		chunk := dynamicpb.NewMessage(chunkDesc)
+		err := stream.RecvMsg(chunk)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		counter.Count(chunk.Get(text).String())
+	}
+
+	res := counter.Result()
+	srv.metrics.observeResult(res)
+	return stream.SendMsg(frequencyResultMessage(res))
+}
+
+// frequencyResultMessage converts res to a FrequencyResult message.
+func frequencyResultMessage(res *kanjikana.Result) *dynamicpb.Message {
+	resultDesc := grpcMessages.ByName("FrequencyResult")
+	countDesc := grpcMessages.ByName("CharacterCount")
+	fields := resultDesc.Fields()
+
+	msg := dynamicpb.NewMessage(resultDesc)
+	msg.Set(fields.ByName("all_characters_count"), protoreflect.ValueOfInt64(int64(res.AllCharactersCount)))
+	msg.Set(fields.ByName("unique_count"), protoreflect.ValueOfInt64(int64(res.UniqueCount)))
+	msg.Set(fields.ByName("kanji_unique_count"), protoreflect.ValueOfInt64(int64(res.KanjiUniqueCount)))
+	msg.Set(fields.ByName("kana_unique_count"), protoreflect.ValueOfInt64(int64(res.KanaUniqueCount)))
+	msg.Set(fields.ByName("hiragana_unique_count"), protoreflect.ValueOfInt64(int64(res.HiraganaUniqueCount)))
+	msg.Set(fields.ByName("katakana_unique_count"), protoreflect.ValueOfInt64(int64(res.KatakanaUniqueCount)))
+
+	categories := []struct {
+		field  protoreflect.Name
+		counts map[string]int
+	}{
+		{"kanjis", res.Kanjis},
+		{"hiraganas", res.Hiraganas},
+		{"katakanas", res.Katakanas},
+	}
+	for _, category := range categories {
+		list := msg.Mutable(fields.ByName(category.field)).List()
+		for _, c := range kanjikana.MostCommonCharacters(category.counts) {
+			entry := dynamicpb.NewMessage(countDesc)
+			entry.Set(countDesc.Fields().ByName("character"), protoreflect.ValueOfString(c))
+			entry.Set(countDesc.Fields().ByName("count"), protoreflect.ValueOfInt64(int64(category.counts[c])))
+			entry.Set(countDesc.Fields().ByName("per_thousand"), protoreflect.ValueOfFloat64(kanjikana.PerThousand(category.counts[c], res.AllCharactersCount)))
+			list.Append(protoreflect.ValueOfMessage(entry))
+		}
+	}
+	return msg
+}
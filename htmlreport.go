@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"os"
+	"time"
+
+	"github.com/gojp/kana"
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+)
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+<meta charset="utf-8">
+<title>Kanji and kana frequency report: {{.Source}}</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: 2em auto; padding: 0 1em; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { padding: 0.25em 0.75em; border-bottom: 1px solid #ddd; text-align: left; }
+td.number { text-align: right; }
+th.sortable { cursor: pointer; user-select: none; }
+th.sortable::after { content: " \2195"; color: #aaa; }
+section { margin: 2em 0; }
+svg { max-width: 100%; height: auto; }
+</style>
+</head>
+<body>
+<h1>Kanji and kana frequency report</h1>
+<table>
+<tr><th>Source</th><td>{{.Source}}</td></tr>
+<tr><th>Generated</th><td>{{.Generated}}</td></tr>
+{{- if .Pages}}
+<tr><th>Pages</th><td class="number">{{.Pages}}</td></tr>
+{{- end}}
+<tr><th>Japanese characters</th><td class="number">{{.Result.AllCharactersCount}}</td></tr>
+<tr><th>Unique characters</th><td class="number">{{.Result.UniqueCount}}</td></tr>
+<tr><th>Unique kanji</th><td class="number">{{.Result.KanjiUniqueCount}}</td></tr>
+<tr><th>Unique hiragana</th><td class="number">{{.Result.HiraganaUniqueCount}}</td></tr>
+<tr><th>Unique katakana</th><td class="number">{{.Result.KatakanaUniqueCount}}</td></tr>
+</table>
+{{range .Sections}}
+<section>
+<h2>{{.Title}}</h2>
+{{.Chart}}
+<table class="ranking">
+<thead><tr>
+<th class="sortable" data-type="number">Rank</th>
+<th class="sortable">{{.Label}}</th>
+<th class="sortable" data-type="number">Count</th>
+<th class="sortable" data-type="number">Share</th>
+<th class="sortable" data-type="number">Per 1,000</th>
+<th class="sortable">Details</th>
+</tr></thead>
+<tbody>
+{{- range .Rows}}
+<tr><td class="number">{{.Rank}}</td><td>{{.Entry}}</td><td class="number">{{.Count}}</td><td class="number">{{printf "%.2f" .Share}}%</td><td class="number">{{printf "%.2f" .PerThousand}}</td><td>{{.Details}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+</section>
+{{end}}
+<script>
+document.querySelectorAll("table.ranking").forEach(function (table) {
+  table.querySelectorAll("th.sortable").forEach(function (th, column) {
+    var ascending = false;
+    th.addEventListener("click", function () {
+      ascending = !ascending;
+      var numeric = th.dataset.type === "number";
+      var body = table.tBodies[0];
+      var rows = Array.from(body.rows);
+      rows.sort(function (a, b) {
+        var x = a.cells[column].textContent, y = b.cells[column].textContent;
+        var order = numeric ? parseFloat(x) - parseFloat(y) : x.localeCompare(y, "ja");
+        return ascending ? order : -order;
+      });
+      rows.forEach(function (row) { body.appendChild(row); });
+    });
+  });
+});
+</script>
+</body>
+</html>
+`))
+
+type htmlReport struct {
+	Source    string
+	Generated string
+	Pages     int
+	Result    *kanjikana.Result
+	Sections  []htmlReportSection
+}
+
+type htmlReportSection struct {
+	Title string
+	Label string
+	Chart template.HTML
+	Rows  []htmlReportRow
+}
+
+type htmlReportRow struct {
+	Rank  int
+	Entry string
+	Count int
+	Share float64
+	// PerThousand is the number of occurrences per 1,000 Japanese
+	// characters, while Share is relative to the category.
+	PerThousand float64
+	Details     string
+}
+
+// writeHTMLReport writes a standalone HTML page with the summary of the
+// result, a bar chart and a sortable ranking table per category.
+func writeHTMLReport(path, source string, rep *report) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := renderHTMLReport(f, source, rep); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// renderHTMLReport writes the page of writeHTMLReport to w.
+func renderHTMLReport(w io.Writer, source string, rep *report) error {
+	res := rep.res
+	data := htmlReport{
+		Source:    source,
+		Generated: time.Now().Format(time.RFC1123),
+		Pages:     len(res.Pages),
+		Result:    res,
+	}
+
+	categories := []struct {
+		title, label string
+		m            map[string]int
+		describe     func(string) string
+	}{
+		{"Kanji", "Kanji", res.Kanjis, rep.describeKanji},
+		{"Hiragana", "Hiragana", res.Hiraganas, kana.KanaToRomaji},
+		{"Katakana", "Katakana", res.Katakanas, kana.KanaToRomaji},
+		{"Words", "Word", res.Words, rep.describeWord},
+		{"Kanji compounds", "Compound", res.Compounds, rep.describeWord},
+		{"Punctuation and symbols", "Symbol", res.Punctuation, func(string) string { return "" }},
+	}
+	for _, category := range categories {
+		if len(category.m) == 0 {
+			continue
+		}
+		section, err := newHTMLReportSection(category.title, category.label, category.m, res.AllCharactersCount, rep.rankingSize, category.describe)
+		if err != nil {
+			return err
+		}
+		data.Sections = append(data.Sections, section)
+	}
+	return htmlReportTemplate.Execute(w, data)
+}
+
+func newHTMLReportSection(title, label string, m map[string]int, characters, rankingSize int, describe func(string) string) (htmlReportSection, error) {
+	section := htmlReportSection{Title: title, Label: label}
+
+	var chart bytes.Buffer
+	if err := writeBarChartSVG(&chart, title, topBars(m, chartSize)); err != nil {
+		return section, err
+	}
+	// The chart is generated by writeBarChartSVG, which escapes its labels.
+	section.Chart = template.HTML(chart.String())
+
+	total := 0
+	for _, count := range m {
+		total += count
+	}
+	for i, entry := range kanjikana.MostCommonCharacters(m) {
+		if i >= rankingSize {
+			break
+		}
+		section.Rows = append(section.Rows, htmlReportRow{
+			Rank:        i + 1,
+			Entry:       entry,
+			Count:       m[entry],
+			Share:       100 * float64(m[entry]) / float64(total),
+			PerThousand: kanjikana.PerThousand(m[entry], characters),
+			Details:     describe(entry),
+		})
+	}
+	return section, nil
+}
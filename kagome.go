@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"unicode"
+
+	"github.com/ikawaha/kagome-dict/ipa"
+	"github.com/ikawaha/kagome/v2/tokenizer"
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+)
+
+// kagomeTokenizer splits text into words with the kagome morphological
+// analyzer and its IPA dictionary, which gives every word its dictionary
+// form, so that 食べた is counted as 食べる, its reading in katakana and its
+// part of speech.
+type kagomeTokenizer struct {
+	t *tokenizer.Tokenizer
+}
+
+// loadKagomeTokenizer returns the kagome tokenizer shared by all counts, as
+// the IPA dictionary is only loaded once.
+var loadKagomeTokenizer = sync.OnceValues(newKagomeTokenizer)
+
+func newKagomeTokenizer() (kagomeTokenizer, error) {
+	t, err := tokenizer.New(ipa.Dict(), tokenizer.OmitBosEos())
+	if err != nil {
+		return kagomeTokenizer{}, fmt.Errorf("unable to load the IPA dictionary: %w", err)
+	}
+	return kagomeTokenizer{t: t}, nil
+}
+
+// Tokenize returns the words of text, leaving out symbols and the words
+// without kanji nor kana, such as numbers and Latin words.
+func (k kagomeTokenizer) Tokenize(text string) []kanjikana.Token {
+	var tokens []kanjikana.Token
+	for _, t := range k.t.Tokenize(text) {
+		pos := t.POS()
+		if len(pos) == 0 || pos[0] == "記号" || !hasKanjiOrKana(t.Surface) {
+			continue
+		}
+		token := kanjikana.Token{Surface: t.Surface, PartOfSpeech: ipaPartOfSpeech(pos)}
+		if base, ok := t.BaseForm(); ok && base != "*" {
+			token.BaseForm = base
+		}
+		if reading, ok := t.Reading(); ok && reading != "*" {
+			token.Reading = reading
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// ipaPartOfSpeech returns the class of the IPA dictionary part of speech
+// pos, such as kanjikana.PartOfSpeechVerb for 動詞,自立.
+func ipaPartOfSpeech(pos []string) string {
+	sub := func(i int) string {
+		if i < len(pos) {
+			return pos[i]
+		}
+		return ""
+	}
+	switch pos[0] {
+	case "名詞":
+		switch {
+		case sub(1) == "代名詞":
+			return kanjikana.PartOfSpeechPronoun
+		case sub(1) == "形容動詞語幹":
+			return kanjikana.PartOfSpeechAdjective
+		case sub(1) == "接尾" && sub(2) == "助数詞":
+			return kanjikana.PartOfSpeechCounter
+		case sub(1) == "接尾":
+			return kanjikana.PartOfSpeechSuffix
+		}
+		return kanjikana.PartOfSpeechNoun
+	case "動詞":
+		return kanjikana.PartOfSpeechVerb
+	case "形容詞", "連体詞":
+		return kanjikana.PartOfSpeechAdjective
+	case "副詞":
+		return kanjikana.PartOfSpeechAdverb
+	case "助詞":
+		return kanjikana.PartOfSpeechParticle
+	case "助動詞":
+		return kanjikana.PartOfSpeechAuxiliary
+	case "接続詞":
+		return kanjikana.PartOfSpeechConjunction
+	case "感動詞":
+		return kanjikana.PartOfSpeechInterjection
+	case "接頭詞":
+		return kanjikana.PartOfSpeechPrefix
+	default:
+		return kanjikana.PartOfSpeechOther
+	}
+}
+
+// hasKanjiOrKana reports whether word holds a kanji, hiragana or katakana.
+func hasKanjiOrKana(word string) bool {
+	for _, r := range word {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) {
+			return true
+		}
+	}
+	return false
+}
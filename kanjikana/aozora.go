@@ -0,0 +1,79 @@
+package kanjikana
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// AozoraText returns the text of a book of Aozora Bunko in its XHTML
+// format, in any charset, without the ruby readings, the transcriber's
+// annotations and the bibliographic information around the text.
+func AozoraText(r io.Reader) (string, error) {
+	reader, err := charset.NewReader(r, "text/html")
+	if err != nil {
+		return "", err
+	}
+	doc, err := html.Parse(reader)
+	if err != nil {
+		return "", err
+	}
+
+	main := doc
+	if n := FindElement(doc, func(n *html.Node) bool {
+		return n.DataAtom == atom.Div && hasClass(n, "main_text")
+	}); n != nil {
+		main = n
+	}
+	removeElements(main, func(n *html.Node) bool {
+		// <rt> and <rp> hold the readings of ruby annotations, and
+		// "notes" spans the ［＃...］ notes of the transcriber.
+		return n.DataAtom == atom.Rt || n.DataAtom == atom.Rp || hasClass(n, "notes")
+	})
+	return visibleText(main), nil
+}
+
+// FindElement returns the first element of the document rooted at n
+// matching match, or nil.
+func FindElement(n *html.Node, match func(*html.Node) bool) *html.Node {
+	if n.Type == html.ElementNode && match(n) {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := FindElement(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// removeElements removes the elements of the document rooted at n matching
+// match.
+func removeElements(n *html.Node, match func(*html.Node) bool) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode && match(c) {
+			n.RemoveChild(c)
+		} else {
+			removeElements(c, match)
+		}
+		c = next
+	}
+}
+
+// hasClass reports whether the element n has the class class.
+func hasClass(n *html.Node, class string) bool {
+	for _, attr := range n.Attr {
+		if attr.Key == "class" {
+			for _, c := range strings.Fields(attr.Val) {
+				if c == class {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
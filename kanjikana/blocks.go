@@ -0,0 +1,127 @@
+package kanjikana
+
+import (
+	"sort"
+	"unicode"
+)
+
+// unicodeBlock is a Unicode block holding Japanese characters.
+type unicodeBlock struct {
+	name        string
+	first, last rune
+	// category is the category of every character of the block, or "" when
+	// it depends on the character.
+	category string
+}
+
+// japaneseBlocks lists the blocks of the kanji and kana, ordered by code
+// point. The kanji blocks are listed up to Unicode 15.1, so that the rare
+// and historical kanji of the extensions are counted even when the Unicode
+// tables of Go do not know them yet.
+var japaneseBlocks = []unicodeBlock{
+	{"CJK Symbols and Punctuation", 0x3000, 0x303f, ""},
+	{"Hiragana", 0x3040, 0x309f, CategoryHiragana},
+	{"Katakana", 0x30a0, 0x30ff, CategoryKatakana},
+	{"Katakana Phonetic Extensions", 0x31f0, 0x31ff, CategoryKatakana},
+	{"CJK Unified Ideographs Extension A", 0x3400, 0x4dbf, CategoryKanji},
+	{"CJK Unified Ideographs", 0x4e00, 0x9fff, CategoryKanji},
+	{"CJK Compatibility Ideographs", 0xf900, 0xfaff, CategoryKanji},
+	{"Halfwidth and Fullwidth Forms", 0xff00, 0xffef, ""},
+	{"Kana Extended-B", 0x1aff0, 0x1afff, CategoryKatakana},
+	{"Kana Supplement", 0x1b000, 0x1b0ff, ""},
+	{"Kana Extended-A", 0x1b100, 0x1b12f, ""},
+	{"Small Kana Extension", 0x1b130, 0x1b16f, ""},
+	{"CJK Unified Ideographs Extension B", 0x20000, 0x2a6df, CategoryKanji},
+	{"CJK Unified Ideographs Extension C", 0x2a700, 0x2b73f, CategoryKanji},
+	{"CJK Unified Ideographs Extension D", 0x2b740, 0x2b81f, CategoryKanji},
+	{"CJK Unified Ideographs Extension E", 0x2b820, 0x2ceaf, CategoryKanji},
+	{"CJK Unified Ideographs Extension F", 0x2ceb0, 0x2ebef, CategoryKanji},
+	{"CJK Unified Ideographs Extension I", 0x2ebf0, 0x2ee5f, CategoryKanji},
+	{"CJK Compatibility Ideographs Supplement", 0x2f800, 0x2fa1f, CategoryKanji},
+	{"CJK Unified Ideographs Extension G", 0x30000, 0x3134f, CategoryKanji},
+	{"CJK Unified Ideographs Extension H", 0x31350, 0x323af, CategoryKanji},
+}
+
+// blockOf returns the index of the block of japaneseBlocks holding r, or -1.
+func blockOf(r rune) int {
+	i := sort.Search(len(japaneseBlocks), func(i int) bool { return japaneseBlocks[i].last >= r })
+	if i < len(japaneseBlocks) && japaneseBlocks[i].first <= r {
+		return i
+	}
+	return -1
+}
+
+// runeCategory returns the category of r, CategoryKanji, CategoryHiragana or
+// CategoryKatakana, or "" when r is not a Japanese character. The kana are
+// classified by block, so that the marks shared by both scripts, as ゛ or ・,
+// and the half-width marks belong to a single one. The common ranges are
+// checked first.
+func runeCategory(r rune) string {
+	switch {
+	case r < 0x3000:
+		return ""
+	case r >= 0x4e00 && r <= 0x9fff:
+		return CategoryKanji
+	case r >= 0x3041 && r <= 0x309f:
+		return CategoryHiragana
+	case r >= 0x30a0 && r <= 0x30ff, r >= 0xff65 && r <= 0xff9f:
+		return CategoryKatakana
+	}
+	i := blockOf(r)
+	switch {
+	case i < 0:
+		return ""
+	case japaneseBlocks[i].category != "":
+		return japaneseBlocks[i].category
+	case unicode.Is(unicode.Ideographic, r):
+		// 〆, 〇 and the Hangzhou numerals of the CJK symbols.
+		return CategoryKanji
+	case unicode.Is(unicode.Hiragana, r):
+		return CategoryHiragana
+	case unicode.Is(unicode.Katakana, r):
+		return CategoryKatakana
+	}
+	return ""
+}
+
+// BlockCount is the number of occurrences of the characters of a Unicode
+// block.
+type BlockCount struct {
+	Block string `json:"block"`
+	// Characters is the number of distinct characters of the block.
+	Characters int `json:"characters"`
+	Count      int `json:"count"`
+}
+
+// BlockCounts counts the occurrences of the characters of the counts maps,
+// such as the kanji, hiragana and katakana of a Result, by Unicode block,
+// listed in code point order. Every character of a multi-character key, as
+// the digraphs counted with WithMorae, is counted in its block.
+func BlockCounts(maps ...map[string]int) []BlockCount {
+	counts := make([]BlockCount, len(japaneseBlocks))
+	seen := make(map[rune]struct{})
+	for _, m := range maps {
+		for k, n := range m {
+			for _, r := range k {
+				i := blockOf(r)
+				if i < 0 {
+					continue
+				}
+				counts[i].Count += n
+				if _, ok := seen[r]; !ok {
+					seen[r] = struct{}{}
+					counts[i].Characters++
+				}
+			}
+		}
+	}
+
+	var blocks []BlockCount
+	for i, c := range counts {
+		if c.Count > 0 {
+			c.Block = japaneseBlocks[i].name
+			blocks = append(blocks, c)
+		}
+	}
+	return blocks
+}
@@ -0,0 +1,205 @@
+package kanjikana
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cachedPage is a fetched page stored in the page cache.
+type cachedPage struct {
+	URL          string    `json:"url"`
+	ContentType  string    `json:"content_type"`
+	FetchedAt    time.Time `json:"fetched_at"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Body         []byte    `json:"body"`
+	// FinalURL is the URL the page was redirected to, if any.
+	FinalURL string `json:"final_url,omitempty"`
+}
+
+// revalidatable reports whether the server gave validators to check the
+// page for changes with a conditional request.
+func (p *cachedPage) revalidatable() bool {
+	return p.ETag != "" || p.LastModified != ""
+}
+
+// pageCache stores fetched pages on disk, one file per URL.
+type pageCache struct {
+	dir string
+}
+
+func newPageCache(dir string) (*pageCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &pageCache{dir: dir}, nil
+}
+
+func (c *pageCache) path(pageURL string) string {
+	sum := sha256.Sum256([]byte(pageURL))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// get returns the cached copy of pageURL, if any.
+func (c *pageCache) get(pageURL string) (*cachedPage, bool) {
+	data, err := os.ReadFile(c.path(pageURL))
+	if err != nil {
+		return nil, false
+	}
+
+	var page cachedPage
+	if err := json.Unmarshal(data, &page); err != nil || page.URL != pageURL {
+		return nil, false
+	}
+	return &page, true
+}
+
+func (c *pageCache) put(page *cachedPage) error {
+	data, err := json.Marshal(page)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so concurrent readers never see a
+	// partially written page.
+	tmp, err := os.CreateTemp(c.dir, "page-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(page.URL))
+}
+
+// fetchedPage is a page loaded by loadPage.
+type fetchedPage struct {
+	body        io.ReadCloser
+	contentType string
+	// url is the URL the page resolved to after redirects.
+	url string
+}
+
+// fetched returns the page loaded from the cache.
+func (p *cachedPage) fetched() *fetchedPage {
+	return &fetchedPage{body: io.NopCloser(bytes.NewReader(p.Body)), contentType: p.ContentType, url: p.fetchedURL()}
+}
+
+// fetchedURL returns the URL the cached page resolved to.
+func (p *cachedPage) fetchedURL() string {
+	if p.FinalURL != "" {
+		return p.FinalURL
+	}
+	return p.URL
+}
+
+// loadPage returns the body, content type and final URL of pageURL, or of
+// its snapshot when crawling the Wayback Machine.
+func (s *Scraper) loadPage(ctx context.Context, pageURL string) (*fetchedPage, error) {
+	if s.opts.wayback == "" {
+		return s.loadURL(ctx, pageURL)
+	}
+	// Snapshots are cached by their own URL, and resolve to the URL they
+	// archive, so that their links are resolved as on the original site.
+	page, err := s.loadURL(ctx, waybackURL(pageURL, s.opts.wayback))
+	if err != nil {
+		return nil, err
+	}
+	if original, _, ok := ParseWaybackURL(page.url); ok {
+		page.url = original
+	}
+	return page, nil
+}
+
+// loadURL returns the body, content type and final URL of pageURL, reading
+// it from the page cache when possible. Cached pages served with an ETag or
+// a Last-Modified date are revalidated with a conditional request and only
+// downloaded again when they changed. Without a cache, the body is streamed
+// from the response rather than read into memory. The caller must close it.
+// With a renderer, the page is rendered instead.
+func (s *Scraper) loadURL(ctx context.Context, pageURL string) (*fetchedPage, error) {
+	if s.opts.renderer != nil {
+		return s.renderPage(ctx, pageURL)
+	}
+
+	var cached *cachedPage
+	header := make(http.Header)
+	if s.cache != nil {
+		if page, ok := s.cache.get(pageURL); ok {
+			if !page.revalidatable() {
+				s.archive(page.fetchedURL(), page.ContentType, page.Body)
+				return page.fetched(), nil
+			}
+			cached = page
+			if page.ETag != "" {
+				header.Set("If-None-Match", page.ETag)
+			}
+			if page.LastModified != "" {
+				header.Set("If-Modified-Since", page.LastModified)
+			}
+		}
+	}
+
+	resp, err := s.fetch(ctx, pageURL, header)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		s.archive(cached.fetchedURL(), cached.ContentType, cached.Body)
+		return cached.fetched(), nil
+	}
+	contentType := resp.Header.Get("Content-Type")
+	finalURL := resp.Request.URL.String()
+	// Images, scripts, fonts and other pages declared as not HTML are never
+	// counted, so they are not read in full to be cached nor recorded.
+	cacheable := s.cache != nil && resp.StatusCode == http.StatusOK
+	if (!cacheable && s.opts.warc == nil) || (contentType != "" && !isHTMLContent(contentType, nil)) {
+		return &fetchedPage{body: resp.Body, contentType: contentType, url: finalURL}, nil
+	}
+	defer resp.Body.Close()
+
+	// Cached and recorded pages are read into memory to be stored.
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if s.opts.warc != nil {
+		if err := s.opts.warc.writeResponse(finalURL, resp, body); err != nil {
+			s.logger().Warn("unable to record page", "url", finalURL, "error", err)
+		}
+	}
+	if !cacheable {
+		return &fetchedPage{body: io.NopCloser(bytes.NewReader(body)), contentType: contentType, url: finalURL}, nil
+	}
+	page := &cachedPage{
+		URL:          pageURL,
+		ContentType:  contentType,
+		FetchedAt:    time.Now(),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Body:         body,
+	}
+	if finalURL != pageURL {
+		page.FinalURL = finalURL
+	}
+	if err := s.cache.put(page); err != nil {
+		s.logger().Warn("unable to cache page", "url", pageURL, "error", err)
+	}
+
+	return page.fetched(), nil
+}
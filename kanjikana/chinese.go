@@ -0,0 +1,64 @@
+package kanjikana
+
+import "strings"
+
+// minChineseKanjis is the number of kanji from which a text without kana is
+// taken for Chinese. Japanese headings and names without kana are shorter.
+const minChineseKanjis = 10
+
+// looksChinese reports whether text looks like Chinese rather than Japanese:
+// hanzi with no kana context, either a run of minChineseKanjis or more, or
+// kanji punctuated with the full-width comma or semicolon of Chinese.
+func looksChinese(text string) bool {
+	kanjis := 0
+	chinesePunctuation := false
+	for _, r := range text {
+		switch runeCategory(r) {
+		case CategoryHiragana, CategoryKatakana:
+			return false
+		case CategoryKanji:
+			kanjis++
+		case "":
+			if r == '，' || r == '；' {
+				chinesePunctuation = true
+			}
+		}
+	}
+	return kanjis >= minChineseKanjis || (kanjis > 0 && chinesePunctuation)
+}
+
+// kanjiCount returns the number of kanji of text.
+func kanjiCount(text string) int {
+	n := 0
+	for _, r := range text {
+		if runeCategory(r) == CategoryKanji {
+			n++
+		}
+	}
+	return n
+}
+
+// isChineseLang reports whether the language tag lang, as found in the lang
+// attribute of HTML elements, is Chinese.
+func isChineseLang(lang string) bool {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	return lang == "zh" || strings.HasPrefix(lang, "zh-")
+}
+
+// skipChinese records the kanji of text, known to be Chinese, as skipped.
+func (c *Counter) skipChinese(text string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.chineseKanjis += kanjiCount(text)
+}
+
+// chineseHandler returns the function that scanHTML calls with the text of
+// the Chinese elements: skipChinese when WithChineseExclusion is set, or nil
+// to count them as any other text.
+func (c *Counter) chineseHandler() func(string) {
+	if !c.opts.excludeChinese {
+		return nil
+	}
+	return c.skipChinese
+}
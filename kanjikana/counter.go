@@ -0,0 +1,554 @@
+package kanjikana
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Counter accumulates Kanji, Hiragana and Katakana occurrences. It is safe
+// for concurrent use.
+type Counter struct {
+	mu                 sync.Mutex
+	opts               countOptions
+	allCharactersCount int
+	kanjis             map[rune]int
+	hiraganas          map[rune]int
+	katakanas          map[rune]int
+	// sequences counts the kana entries of several characters: the morae
+	// of WithMorae and the kana followed by ー with LongVowelMarkAttach.
+	sequences     map[entry]int
+	words         map[string]int
+	ngrams        map[string]int
+	punctuation   map[string]int
+	compounds     map[string]int
+	readings      map[string]map[string]int
+	partsOfSpeech map[string]map[string]int
+	sentences     SentenceStats
+	// sentenceCharacters and sentenceKanjis count the sentence being read,
+	// which may span several calls to count.
+	sentenceCharacters int
+	sentenceKanjis     int
+	// chineseKanjis counts the kanji of the text skipped as Chinese.
+	chineseKanjis int
+}
+
+func NewCounter(options ...CountOption) (*Counter, error) {
+	opts, err := newCountOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	return newCounter(opts), nil
+}
+
+func newCounter(opts countOptions) *Counter {
+	return &Counter{
+		opts:          opts,
+		kanjis:        make(map[rune]int),
+		katakanas:     make(map[rune]int),
+		hiraganas:     make(map[rune]int),
+		sequences:     make(map[entry]int),
+		words:         make(map[string]int),
+		ngrams:        make(map[string]int),
+		punctuation:   make(map[string]int),
+		compounds:     make(map[string]int),
+		readings:      make(map[string]map[string]int),
+		partsOfSpeech: make(map[string]map[string]int),
+	}
+}
+
+// Count adds every Japanese character of text to the counter.
+func (c *Counter) Count(text string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.count(text)
+}
+
+// CountReader adds every Japanese character read from r to the counter.
+func (c *Counter) CountReader(r io.Reader) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		c.count(line)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Counter) count(text string) {
+	if !c.opts.excludeChinese {
+		c.countText(text)
+		return
+	}
+	// Chinese is detected by line and by sentence, so that a Chinese
+	// quotation does not hide the Japanese text around it.
+	for text != "" {
+		segment := text
+		if i := strings.IndexAny(text, "\n。"); i >= 0 {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			segment = text[:i+size]
+		}
+		text = text[len(segment):]
+		if looksChinese(segment) {
+			c.chineseKanjis += kanjiCount(segment)
+			continue
+		}
+		c.countText(segment)
+	}
+}
+
+func (c *Counter) countText(text string) {
+	if !c.opts.halfWidthKatakana {
+		text = widenKatakana(text)
+	}
+	if c.opts.variants != nil {
+		text = c.opts.variants.Normalize(text)
+	}
+
+	// last is the entry counted for the previous character, which the
+	// iteration and long vowel marks refer to.
+	var last entry
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		characters := c.allCharactersCount
+		switch {
+		case r == iterationMark:
+			last = c.countIterationMark(last)
+		case r == longVowelMark:
+			last = c.countLongVowelMark(last)
+		default:
+			next, nextSize := utf8.DecodeRuneInString(text[i:])
+			if c.opts.morae && isDigraph(r, next) {
+				last = c.countDigraph(r, next)
+				i += nextSize
+			} else {
+				last = c.countRune(r)
+			}
+		}
+		if c.opts.sentences {
+			c.countSentence(r, c.allCharactersCount-characters, last)
+		}
+	}
+
+	if c.opts.ngramSize > 1 {
+		c.countNGrams(text)
+	}
+
+	if c.opts.compounds {
+		c.countCompounds(text)
+	}
+
+	if c.opts.tokenizer != nil {
+		for _, token := range c.opts.tokenizer.Tokenize(text) {
+			if containsJapanese(token.Surface) {
+				c.words[token.Word()] += 1
+			}
+			if c.opts.readings && token.Reading != "" {
+				c.countReadings(token)
+			}
+			if c.opts.partsOfSpeech && token.PartOfSpeech != "" {
+				if c.partsOfSpeech[token.PartOfSpeech] == nil {
+					c.partsOfSpeech[token.PartOfSpeech] = make(map[string]int)
+				}
+				c.partsOfSpeech[token.PartOfSpeech][token.Word()] += 1
+			}
+		}
+	}
+}
+
+// countNGrams counts the sequences of ngramSize consecutive Japanese
+// characters of text.
+func (c *Counter) countNGrams(text string) {
+	window := make([]rune, 0, c.opts.ngramSize)
+	for _, r := range text {
+		if !isJapanese(r) || (c.opts.punctuation && isPunctuation(r)) {
+			window = window[:0]
+			continue
+		}
+		if len(window) == c.opts.ngramSize {
+			copy(window, window[1:])
+			window = window[:len(window)-1]
+		}
+		window = append(window, r)
+		if len(window) == c.opts.ngramSize {
+			c.ngrams[string(window)] += 1
+		}
+	}
+}
+
+// countCompounds counts the runs of two or more consecutive kanji of text.
+func (c *Counter) countCompounds(text string) {
+	start, runes := -1, 0
+	for i, r := range text + " " {
+		if r == iterationMark || runeCategory(r) == CategoryKanji {
+			if start < 0 {
+				start, runes = i, 0
+			}
+			runes++
+			continue
+		}
+		if start >= 0 && runes > 1 {
+			c.compounds[text[start:i]] += 1
+		}
+		start = -1
+	}
+}
+
+// merge adds the counts of other to c.
+func (c *Counter) merge(other *Counter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	other.mu.Lock()
+	defer other.mu.Unlock()
+
+	c.allCharactersCount += other.allCharactersCount
+	c.chineseKanjis += other.chineseKanjis
+	for k, v := range other.kanjis {
+		c.kanjis[k] += v
+	}
+	for k, v := range other.hiraganas {
+		c.hiraganas[k] += v
+	}
+	for k, v := range other.katakanas {
+		c.katakanas[k] += v
+	}
+	for k, v := range other.sequences {
+		c.sequences[k] += v
+	}
+	for k, v := range other.words {
+		c.words[k] += v
+	}
+	for k, v := range other.ngrams {
+		c.ngrams[k] += v
+	}
+	for k, v := range other.punctuation {
+		c.punctuation[k] += v
+	}
+	for k, v := range other.compounds {
+		c.compounds[k] += v
+	}
+	c.readings = addNestedCounts(c.readings, other.readings)
+	c.partsOfSpeech = addNestedCounts(c.partsOfSpeech, other.partsOfSpeech)
+	// The sentence left unfinished by other ends with its text.
+	c.sentences.add(other.sentences)
+	c.sentences.addSentence(other.sentenceCharacters, other.sentenceKanjis)
+}
+
+// characters returns the number of Japanese characters counted so far.
+func (c *Counter) characters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.allCharactersCount
+}
+
+func (c *Counter) uniqueKanjis() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.kanjis)
+}
+
+// entry is a key of one of the character maps of a Counter, given by its
+// category: a single character r, or the characters seq of an entry of
+// several characters.
+type entry struct {
+	category string
+	r        rune
+	seq      string
+}
+
+// key returns the characters of e.
+func (e entry) key() string {
+	if e.seq != "" {
+		return e.seq
+	}
+	return string(e.r)
+}
+
+// The marks whose counting depends on the preceding character.
+const (
+	iterationMark = '々'
+	longVowelMark = 'ー'
+)
+
+// counts returns the map of the kanji, hiragana or katakana category.
+func (c *Counter) counts(category string) map[rune]int {
+	switch category {
+	case CategoryKanji:
+		return c.kanjis
+	case CategoryHiragana:
+		return c.hiraganas
+	default:
+		return c.katakanas
+	}
+}
+
+// add adds n to the count of e, removing e once its count drops to zero.
+func (c *Counter) add(e entry, n int) {
+	if e.seq != "" {
+		c.sequences[e] += n
+		if c.sequences[e] == 0 {
+			delete(c.sequences, e)
+		}
+		return
+	}
+	m := c.counts(e.category)
+	m[e.r] += n
+	if m[e.r] == 0 {
+		delete(m, e.r)
+	}
+}
+
+// characterCounts returns the counts of the characters of category, keyed
+// by their text, the entries of several characters included.
+func (c *Counter) characterCounts(category string) map[string]int {
+	m := c.counts(category)
+	counts := make(map[string]int, len(m))
+	for r, n := range m {
+		counts[string(r)] = n
+	}
+	for e, n := range c.sequences {
+		if e.category == category {
+			counts[e.seq] = n
+		}
+	}
+	return counts
+}
+
+// countRune counts r and returns its entry, or the zero entry when r is not
+// a Japanese character.
+func (c *Counter) countRune(r rune) entry {
+	if c.opts.punctuation && isPunctuation(r) {
+		c.punctuation[string(r)] += 1
+		return entry{}
+	}
+	category := runeCategory(r)
+	if category == "" {
+		return entry{}
+	}
+	c.allCharactersCount += 1
+	c.counts(category)[r] += 1
+	return entry{category: category, r: r}
+}
+
+// countDigraph counts a kana and the small kana following it as one mora.
+func (c *Counter) countDigraph(r, small rune) entry {
+	e := entry{category: CategoryKatakana, seq: string([]rune{r, small})}
+	if isHiragana(r) {
+		e.category = CategoryHiragana
+	}
+	c.allCharactersCount += 2
+	c.sequences[e] += 1
+	return e
+}
+
+// countIterationMark counts 々 following the entry last, as a repetition of
+// the kanji of last or as a kanji of its own.
+func (c *Counter) countIterationMark(last entry) entry {
+	c.allCharactersCount += 1
+	if c.opts.iterationMark == IterationMarkRepeat && last.category == CategoryKanji {
+		c.add(last, 1)
+		return last
+	}
+	c.kanjis[iterationMark] += 1
+	return entry{category: CategoryKanji, r: iterationMark}
+}
+
+// countLongVowelMark counts ー following the entry last, as part of the kana
+// mora of last or as a kana of its own, of the script of last.
+func (c *Counter) countLongVowelMark(last entry) entry {
+	c.allCharactersCount += 1
+	isKana := last.category == CategoryHiragana || last.category == CategoryKatakana
+	if c.opts.longVowelMark == LongVowelMarkAttach && isKana {
+		c.add(last, -1)
+		e := entry{category: last.category, seq: last.key() + string(longVowelMark)}
+		c.add(e, 1)
+		return e
+	}
+	e := entry{category: CategoryKatakana, r: longVowelMark}
+	if last.category == CategoryHiragana {
+		e.category = CategoryHiragana
+	}
+	c.add(e, 1)
+	return e
+}
+
+// Result summarizes the characters counted so far.
+func (c *Counter) Result() *Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	res := &Result{
+		AllCharactersCount: c.allCharactersCount,
+		Kanjis:             c.characterCounts(CategoryKanji),
+		Hiraganas:          c.characterCounts(CategoryHiragana),
+		Katakanas:          c.characterCounts(CategoryKatakana),
+	}
+
+	res.UniqueCount += len(res.Kanjis)
+	res.UniqueCount += len(res.Katakanas)
+	res.UniqueCount += len(res.Hiraganas)
+
+	res.KanjiUniqueCount = len(res.Kanjis)
+	res.KatakanaUniqueCount = len(res.Katakanas)
+	res.HiraganaUniqueCount = len(res.Hiraganas)
+
+	kanas := make(map[string]struct{})
+	for s := range res.Katakanas {
+		kanas[s] = struct{}{}
+	}
+
+	for s := range res.Hiraganas {
+		kanas[s] = struct{}{}
+	}
+
+	res.KanaUniqueCount = len(kanas)
+	res.SkippedChineseCount = c.chineseKanjis
+
+	if c.opts.tokenizer != nil {
+		res.Words = copyCounts(c.words)
+	}
+	if c.opts.ngramSize > 1 {
+		res.NGramSize = c.opts.ngramSize
+		res.NGrams = copyCounts(c.ngrams)
+	}
+	if c.opts.punctuation {
+		res.Punctuation = copyCounts(c.punctuation)
+	}
+	if c.opts.compounds {
+		res.Compounds = copyCounts(c.compounds)
+	}
+	if c.opts.tokenizer != nil && c.opts.readings {
+		res.Readings = addNestedCounts(nil, c.readings)
+	}
+	if c.opts.sentences {
+		sentences := c.sentences
+		sentences.addSentence(c.sentenceCharacters, c.sentenceKanjis)
+		res.Sentences = &sentences
+	}
+	if c.opts.tokenizer != nil && c.opts.partsOfSpeech {
+		res.PartsOfSpeech = addNestedCounts(nil, c.partsOfSpeech)
+	}
+
+	return res
+}
+
+func copyCounts(m map[string]int) map[string]int {
+	counts := make(map[string]int, len(m))
+	for k, v := range m {
+		counts[k] = v
+	}
+	return counts
+}
+
+// isJapanese reports whether r is a Kanji or kana character, or the
+// iteration mark 々.
+func isJapanese(r rune) bool {
+	return r == iterationMark || runeCategory(r) != ""
+}
+
+// isPunctuation reports whether r is a Japanese punctuation mark or symbol:
+// the CJK symbols and punctuation but the kanji-like 々, 〆 and 〇, the
+// middle dot ・, and the full-width and half-width forms of punctuation.
+func isPunctuation(r rune) bool {
+	switch {
+	case r >= 0x3005 && r <= 0x3007:
+		return false
+	case r >= 0x3001 && r <= 0x303f, r == 0x30fb:
+		return true
+	case r >= 0xff01 && r <= 0xff0f, r >= 0xff1a && r <= 0xff20, r >= 0xff3b && r <= 0xff40, r >= 0xff5b && r <= 0xff65:
+		return true
+	}
+	return false
+}
+
+// isHiragana and isKatakana report whether r is a hiragana or katakana
+// letter, leaving out the marks the kana package counts as both, such as
+// the long vowel mark ー.
+func isHiragana(r rune) bool { return r >= 0x3041 && r <= 0x3096 }
+func isKatakana(r rune) bool { return r >= 0x30a1 && r <= 0x30fa }
+
+// isDigraph reports whether the kana r followed by next form a single mora,
+// as in きょ, しゃ or ファ: next is a small vowel or y-kana of the same
+// script as r, and r is not itself small.
+func isDigraph(r, next rune) bool {
+	if isSmallKana(r) {
+		return false
+	}
+	return (isHiragana(r) && isHiragana(next) || isKatakana(r) && isKatakana(next)) && isSmallKana(next)
+}
+
+// isSmallKana reports whether r is a small kana that combines with the
+// preceding kana: ぁぃぅぇぉゃゅょゎ and their katakana forms. The small
+// っ, a mora of its own, is not one of them.
+func isSmallKana(r rune) bool {
+	switch r {
+	case 'ぁ', 'ぃ', 'ぅ', 'ぇ', 'ぉ', 'ゃ', 'ゅ', 'ょ', 'ゎ',
+		'ァ', 'ィ', 'ゥ', 'ェ', 'ォ', 'ャ', 'ュ', 'ョ', 'ヮ':
+		return true
+	}
+	return false
+}
+
+// isHalfWidth reports whether r is a half-width katakana or punctuation mark.
+func isHalfWidth(r rune) bool {
+	return r >= 0xff61 && r <= 0xff9f
+}
+
+// widenKatakana replaces the half-width katakana and punctuation of s by
+// their full-width forms, combining the voiced sound marks with the
+// preceding kana: ｶﾞｯｺｳ becomes ガッコウ.
+func widenKatakana(s string) string {
+	if strings.IndexFunc(s, isHalfWidth) < 0 {
+		return s
+	}
+	var sb strings.Builder
+	for s != "" {
+		start := strings.IndexFunc(s, isHalfWidth)
+		if start < 0 {
+			sb.WriteString(s)
+			break
+		}
+		end := strings.IndexFunc(s[start:], func(r rune) bool { return !isHalfWidth(r) })
+		if end < 0 {
+			end = len(s)
+		} else {
+			end += start
+		}
+		sb.WriteString(s[:start])
+		// NFKC is only applied to the half-width runs, as it also
+		// rewrites other characters, such as full-width letters.
+		sb.WriteString(norm.NFKC.String(s[start:end]))
+		s = s[end:]
+	}
+	return sb.String()
+}
+
+// containsJapanese reports whether s has at least one Kanji or kana character.
+func containsJapanese(s string) bool {
+	return strings.IndexFunc(s, isJapanese) >= 0
+}
+
+// CountReader counts the Japanese characters of a text or HTML document.
+func CountReader(r io.Reader, options ...CountOption) (*Result, error) {
+	c, err := NewCounter(options...)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.CountReader(r); err != nil {
+		return nil, err
+	}
+	return c.Result(), nil
+}
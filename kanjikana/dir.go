@@ -0,0 +1,113 @@
+package kanjikana
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// corpusExtensions lists the file extensions counted by CountDir.
+var corpusExtensions = map[string]struct{}{
+	".txt":  {},
+	".html": {},
+	".htm":  {},
+	".md":   {},
+	".pdf":  {},
+	".epub": {},
+	".srt":  {},
+	".vtt":  {},
+	".ass":  {},
+	".ssa":  {},
+}
+
+// CountDir walks the directory tree rooted at root and counts the Japanese
+// characters of every text, HTML, Markdown, PDF, EPUB and subtitle file. The
+// per-file results are available in Result.Files, keyed by path relative to
+// root.
+func CountDir(root string, options ...CountOption) (*Result, error) {
+	opts, err := newCountOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
+	total := newCounter(opts)
+	files := make(map[string]*Result)
+
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if _, ok := corpusExtensions[strings.ToLower(filepath.Ext(path))]; !ok {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		fileCounter := newCounter(opts)
+		if IsPDFFile(path) {
+			info, err := f.Stat()
+			if err != nil {
+				return err
+			}
+			text, err := PDFText(f, info.Size())
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			fileCounter.Count(text)
+		} else if IsEPUBFile(path) {
+			info, err := f.Stat()
+			if err != nil {
+				return err
+			}
+			chapters, err := EPUBChapters(f, info.Size())
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			for _, chapter := range chapters {
+				fileCounter.Count(chapter.Text)
+			}
+		} else if IsSubtitleFile(path) {
+			text, err := SubtitleText(f)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			fileCounter.Count(text)
+		} else if IsHTMLFile(path) {
+			if err := scanHTML(f, fileCounter.Count, fileCounter.chineseHandler(), nil); err != nil {
+				return err
+			}
+		} else if err := fileCounter.CountReader(f); err != nil {
+			return err
+		}
+		total.merge(fileCounter)
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			rel = path
+		}
+		files[filepath.ToSlash(rel)] = fileCounter.Result()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := total.Result()
+	res.Files = files
+	return res, nil
+}
+
+// IsHTMLFile reports whether path names an HTML document.
+func IsHTMLFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".html" || ext == ".htm"
+}
@@ -0,0 +1,68 @@
+package kanjikana
+
+import (
+	"math"
+	"sort"
+)
+
+// DistributionStats describes the shape of a frequency distribution, such
+// as the kanji counts of a result.
+type DistributionStats struct {
+	// Tokens is the number of occurrences and Types the number of distinct
+	// keys.
+	Tokens int `json:"tokens"`
+	Types  int `json:"types"`
+	// TypeTokenRatio is Types divided by Tokens. It decreases as the text
+	// grows, so it only compares texts of similar sizes.
+	TypeTokenRatio float64 `json:"type_token_ratio"`
+	// Entropy is the Shannon entropy of the distribution, in bits.
+	Entropy float64 `json:"entropy"`
+	// ZipfExponent is s in frequency ∝ 1/rank^s, fitted by least squares
+	// on the log-log rank-frequency plot, and ZipfR2 the coefficient of
+	// determination of the fit, 1 for a perfect Zipf distribution.
+	ZipfExponent float64 `json:"zipf_exponent"`
+	ZipfR2       float64 `json:"zipf_r2"`
+}
+
+// Distribution computes the statistics of the counts m. The Zipf fit needs
+// at least two distinct counts and is left at 0 otherwise.
+func Distribution(m map[string]int) DistributionStats {
+	counts := make([]int, 0, len(m))
+	tokens := 0
+	for _, count := range m {
+		if count > 0 {
+			counts = append(counts, count)
+			tokens += count
+		}
+	}
+	stats := DistributionStats{Tokens: tokens, Types: len(counts)}
+	if tokens == 0 {
+		return stats
+	}
+	stats.TypeTokenRatio = float64(stats.Types) / float64(tokens)
+
+	for _, count := range counts {
+		p := float64(count) / float64(tokens)
+		stats.Entropy -= p * math.Log2(p)
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(counts)))
+	n := float64(len(counts))
+	var sumX, sumY, sumXX, sumXY, sumYY float64
+	for i, count := range counts {
+		x, y := math.Log(float64(i+1)), math.Log(float64(count))
+		sumX += x
+		sumY += y
+		sumXX += x * x
+		sumXY += x * y
+		sumYY += y * y
+	}
+	varX := n*sumXX - sumX*sumX
+	varY := n*sumYY - sumY*sumY
+	if varX > 0 && varY > 0 {
+		cov := n*sumXY - sumX*sumY
+		stats.ZipfExponent = -cov / varX
+		stats.ZipfR2 = cov * cov / (varX * varY)
+	}
+	return stats
+}
@@ -0,0 +1,85 @@
+package kanjikana
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// acceptEncoding lists the content codings the crawler decodes.
+const acceptEncoding = "gzip, deflate, br"
+
+// decodeBody replaces the body of resp by its decoded content when it is
+// served with a gzip, deflate or brotli Content-Encoding. The decoder is
+// only created on the first read, so that empty bodies, as the ones of 304
+// responses, are never decoded.
+func decodeBody(resp *http.Response) {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "gzip", "x-gzip", "deflate", "br":
+	default:
+		return
+	}
+	resp.Body = &decodedBody{body: resp.Body, encoding: encoding}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+}
+
+// decodedBody decodes a response body of the given content coding.
+type decodedBody struct {
+	body     io.ReadCloser
+	encoding string
+	r        io.Reader
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.r == nil {
+		r, err := newDecoder(b.encoding, b.body)
+		if err == io.EOF {
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, fmt.Errorf("unable to decode %s body: %w", b.encoding, err)
+		}
+		b.r = r
+	}
+	return b.r.Read(p)
+}
+
+func (b *decodedBody) Close() error {
+	if closer, ok := b.r.(io.Closer); ok {
+		closer.Close()
+	}
+	return b.body.Close()
+}
+
+func newDecoder(encoding string, r io.Reader) (io.Reader, error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "br":
+		return brotli.NewReader(r), nil
+	}
+
+	// Deflate should be zlib-wrapped, but some servers send raw deflate
+	// data. A zlib header gives the deflate method in its low bits and is a
+	// multiple of 31.
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if len(header) == 0 {
+		return nil, err
+	}
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
@@ -0,0 +1,184 @@
+package kanjikana
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Chapter is a document of the reading order of an EPUB book.
+type Chapter struct {
+	Title string
+	Text  string
+}
+
+// ChapterStats summarizes the characters counted in a chapter of a book,
+// and the kanji it introduces.
+type ChapterStats struct {
+	Title              string `json:"title"`
+	AllCharactersCount int    `json:"all_characters_count"`
+	KanjiUniqueCount   int    `json:"kanji_unique_count"`
+	// NewKanjis lists, from the most to the least frequent in the chapter,
+	// the kanji that do not appear in the previous chapters.
+	NewKanjis []string `json:"new_kanjis"`
+}
+
+// IsEPUBFile reports whether path names an EPUB book.
+func IsEPUBFile(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) == ".epub"
+}
+
+// epubContainer is the META-INF/container.xml file of an EPUB book.
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+// epubPackage is the package document of an EPUB book.
+type epubPackage struct {
+	Manifest []struct {
+		ID        string `xml:"id,attr"`
+		Href      string `xml:"href,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef  string `xml:"idref,attr"`
+		Linear string `xml:"linear,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// EPUBChapters returns the visible text of the documents of the EPUB book r
+// of size bytes, in reading order and without ruby readings. A chapter is
+// titled by its <title>, its first heading, or its file name.
+func EPUBChapters(r io.ReaderAt, size int64) ([]Chapter, error) {
+	book, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var container epubContainer
+	if err := decodeZipXML(book, "META-INF/container.xml", &container); err != nil {
+		return nil, err
+	}
+	if len(container.Rootfiles) == 0 {
+		return nil, errors.New("no package document in EPUB container")
+	}
+	packagePath := container.Rootfiles[0].FullPath
+	var pkg epubPackage
+	if err := decodeZipXML(book, packagePath, &pkg); err != nil {
+		return nil, err
+	}
+
+	hrefs := make(map[string]string)
+	for _, item := range pkg.Manifest {
+		if item.MediaType == "application/xhtml+xml" || item.MediaType == "text/html" {
+			hrefs[item.ID] = item.Href
+		}
+	}
+
+	var chapters []Chapter
+	for _, ref := range pkg.Spine {
+		href, ok := hrefs[ref.IDRef]
+		if !ok || ref.Linear == "no" {
+			continue
+		}
+		// Hrefs are relative to the package document and URL-encoded.
+		name := path.Join(path.Dir(packagePath), href)
+		if unescaped, err := url.PathUnescape(name); err == nil {
+			name = unescaped
+		}
+		f, err := book.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		doc, err := html.Parse(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		removeElements(doc, func(n *html.Node) bool {
+			return n.DataAtom == atom.Rt || n.DataAtom == atom.Rp
+		})
+		title := path.Base(name)
+		if n := FindElement(doc, func(n *html.Node) bool {
+			switch n.DataAtom {
+			case atom.Title, atom.H1, atom.H2, atom.H3:
+				return strings.TrimSpace(visibleText(n)) != ""
+			}
+			return false
+		}); n != nil {
+			title = strings.Join(strings.Fields(visibleText(n)), " ")
+		}
+		body := doc
+		if n := FindElement(doc, func(n *html.Node) bool { return n.DataAtom == atom.Body }); n != nil {
+			body = n
+		}
+		chapters = append(chapters, Chapter{Title: title, Text: visibleText(body)})
+	}
+	return chapters, nil
+}
+
+// CountEPUB counts the Japanese characters of the chapters of the EPUB book
+// r of size bytes. The per-chapter statistics are available in
+// Result.Chapters.
+func CountEPUB(r io.ReaderAt, size int64, options ...CountOption) (*Result, error) {
+	opts, err := newCountOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	chapters, err := EPUBChapters(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	total := newCounter(opts)
+	var stats []ChapterStats
+	for _, chapter := range chapters {
+		chapterCounter := newCounter(opts)
+		chapterCounter.Count(chapter.Text)
+
+		var newKanjis []string
+		for _, k := range MostCommonCharacters(chapterCounter.characterCounts(CategoryKanji)) {
+			r, _ := utf8.DecodeRuneInString(k)
+			if _, ok := total.kanjis[r]; !ok {
+				newKanjis = append(newKanjis, k)
+			}
+		}
+		stats = append(stats, ChapterStats{
+			Title:              chapter.Title,
+			AllCharactersCount: chapterCounter.allCharactersCount,
+			KanjiUniqueCount:   len(chapterCounter.kanjis),
+			NewKanjis:          newKanjis,
+		})
+		total.merge(chapterCounter)
+	}
+
+	res := total.Result()
+	res.Chapters = stats
+	return res, nil
+}
+
+// decodeZipXML decodes the XML file name of archive into v.
+func decodeZipXML(archive *zip.Reader, name string, v any) error {
+	f, err := archive.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := xml.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package kanjikana
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// feedItem is an item of an RSS 2.0 or RSS 1.0 feed.
+type feedItem struct {
+	Link string `xml:"link"`
+}
+
+// feedDocument matches RSS 2.0, RSS 1.0 (RDF) and Atom feeds.
+type feedDocument struct {
+	// Channel holds the items of RSS 2.0 feeds.
+	Channel struct {
+		Items []feedItem `xml:"item"`
+	} `xml:"channel"`
+	// Items holds the items of RSS 1.0 feeds, which are siblings of the
+	// channel.
+	Items   []feedItem `xml:"item"`
+	Entries []struct {
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+	} `xml:"entry"`
+}
+
+// feedURLs returns the URLs of the articles linked from the RSS or Atom feed
+// at feedURL, in the order of the feed.
+func (s *Scraper) feedURLs(ctx context.Context, feedURL string) ([]string, error) {
+	base, err := url.Parse(feedURL)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.fetch(ctx, feedURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch feed %s: %s", feedURL, resp.Status)
+	}
+
+	var doc feedDocument
+	decoder := xml.NewDecoder(resp.Body)
+	decoder.CharsetReader = charset.NewReaderLabel
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("fail to parse feed %s: %w", feedURL, err)
+	}
+
+	var links []string
+	for _, item := range append(doc.Channel.Items, doc.Items...) {
+		links = append(links, item.Link)
+	}
+	for _, entry := range doc.Entries {
+		for _, link := range entry.Links {
+			if link.Rel == "" || link.Rel == "alternate" {
+				links = append(links, link.Href)
+				break
+			}
+		}
+	}
+
+	var pages []string
+	seen := make(map[string]struct{})
+	for _, link := range links {
+		ref, err := url.Parse(strings.TrimSpace(link))
+		if err != nil || link == "" {
+			continue
+		}
+		page := base.ResolveReference(ref).String()
+		if _, ok := seen[page]; !ok {
+			seen[page] = struct{}{}
+			pages = append(pages, page)
+		}
+	}
+	return pages, nil
+}
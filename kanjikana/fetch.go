@@ -0,0 +1,111 @@
+package kanjikana
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 30 * time.Second
+)
+
+// fetch requests pageURL with the given extra headers, retrying network
+// errors, 5xx and 429 responses up to the configured number of retries with
+// exponential backoff. Other 4xx responses fail at once, so that error pages
+// are never counted.
+func (s *Scraper) fetch(ctx context.Context, pageURL string, header http.Header) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		release, err := s.slots.acquire(ctx, pageURL)
+		if err != nil {
+			return nil, err
+		}
+		if s.limiter != nil {
+			if err := s.limiter.wait(ctx, pageURL); err != nil {
+				release()
+				return nil, err
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		if req.Header.Get("Accept-Encoding") == "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		// Credentials are only sent to the crawled site, not to the other
+		// hosts its pages link to.
+		if auth := s.opts.basicAuth; auth != nil && strings.EqualFold(req.URL.Hostname(), s.rootHost) {
+			password, _ := auth.Password()
+			req.SetBasicAuth(auth.Username(), password)
+		}
+
+		resp, err := s.client.Do(req)
+		if err == nil && resp.StatusCode < 400 {
+			resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+			decodeBody(resp)
+			return resp, nil
+		}
+
+		var retryAfter time.Duration
+		retryable := true
+		if err == nil {
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+			retryable = retryableStatus(resp.StatusCode)
+			resp.Body.Close()
+			err = fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		release()
+
+		if !retryable || attempt >= s.opts.retries || ctx.Err() != nil || errors.Is(err, errRedirectRefused) {
+			return nil, err
+		}
+
+		delay := max(backoff(attempt), retryAfter)
+		if s.opts.logger != nil {
+			s.opts.logger.Info("retrying", "url", pageURL, "delay", delay, "error", err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// backoff returns a random delay in [d/2, d) where d doubles with every
+// attempt, up to retryMaxDelay.
+func backoff(attempt int) time.Duration {
+	d := retryMaxDelay
+	if attempt < 16 {
+		d = min(retryBaseDelay<<attempt, retryMaxDelay)
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds.
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return min(time.Duration(seconds)*time.Second, retryMaxDelay)
+}
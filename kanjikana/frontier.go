@@ -0,0 +1,145 @@
+package kanjikana
+
+import (
+	"container/heap"
+	"net/url"
+	"strings"
+)
+
+// CrawlStrategy is the order in which discovered pages are visited.
+type CrawlStrategy int
+
+const (
+	// BFS visits every page of a depth level before going deeper, giving a
+	// representative sample of each level.
+	BFS CrawlStrategy = iota
+	// DFS follows links as deep as allowed before backtracking.
+	DFS
+	// BestFirst visits first the pages whose path has a priority keyword,
+	// as /news/, and the pages linked from the pages of highest Japanese
+	// density, so that a limited number of pages is spent on the pages
+	// richest in text.
+	BestFirst
+)
+
+func (cs CrawlStrategy) String() string {
+	switch cs {
+	case BFS:
+		return "bfs"
+	case DFS:
+		return "dfs"
+	case BestFirst:
+		return "best"
+	default:
+		return "unknown"
+	}
+}
+
+// frontier holds the pages waiting to be visited.
+type frontier interface {
+	push(task crawlTask)
+	// peek returns the next page to visit without removing it.
+	peek() crawlTask
+	pop()
+	len() int
+}
+
+func newFrontier(strategy CrawlStrategy) frontier {
+	switch strategy {
+	case DFS:
+		return &stackFrontier{}
+	case BestFirst:
+		return &priorityFrontier{}
+	}
+	return &queueFrontier{}
+}
+
+// queueFrontier visits pages in FIFO order.
+type queueFrontier struct {
+	tasks []crawlTask
+}
+
+func (q *queueFrontier) push(task crawlTask) { q.tasks = append(q.tasks, task) }
+func (q *queueFrontier) peek() crawlTask     { return q.tasks[0] }
+func (q *queueFrontier) pop()                { q.tasks = q.tasks[1:] }
+func (q *queueFrontier) len() int            { return len(q.tasks) }
+
+// stackFrontier visits pages in LIFO order.
+type stackFrontier struct {
+	tasks []crawlTask
+}
+
+func (st *stackFrontier) push(task crawlTask) { st.tasks = append(st.tasks, task) }
+func (st *stackFrontier) peek() crawlTask     { return st.tasks[len(st.tasks)-1] }
+func (st *stackFrontier) pop()                { st.tasks = st.tasks[:len(st.tasks)-1] }
+func (st *stackFrontier) len() int            { return len(st.tasks) }
+
+// priorityFrontier visits the pages of highest score first, and the pages of
+// equal score in FIFO order.
+type priorityFrontier struct {
+	tasks taskHeap
+	seq   int
+}
+
+func (p *priorityFrontier) push(task crawlTask) {
+	heap.Push(&p.tasks, scoredTask{task: task, seq: p.seq})
+	p.seq++
+}
+
+func (p *priorityFrontier) peek() crawlTask { return p.tasks[0].task }
+func (p *priorityFrontier) pop()            { heap.Pop(&p.tasks) }
+func (p *priorityFrontier) len() int        { return len(p.tasks) }
+
+// scoredTask is a task of a priorityFrontier with its insertion order.
+type scoredTask struct {
+	task crawlTask
+	seq  int
+}
+
+// taskHeap implements heap.Interface.
+type taskHeap []scoredTask
+
+func (h taskHeap) Len() int { return len(h) }
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].task.score != h[j].task.score {
+		return h[i].task.score > h[j].task.score
+	}
+	return h[i].seq < h[j].seq
+}
+func (h taskHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *taskHeap) Push(x any)   { *h = append(*h, x.(scoredTask)) }
+func (h *taskHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// defaultPriorityKeywords are the path keywords of the pages visited first
+// by BestFirst, which are usually articles.
+var defaultPriorityKeywords = []string{
+	"news", "article", "articles", "story", "stories", "column", "columns",
+	"blog", "entry", "entries", "topics", "feature", "features", "kiji",
+}
+
+// linkScore returns the priority of link, found on a page of the given
+// Japanese density: the density, plus 1 when a segment of the link path,
+// split at slashes, dashes, underscores and dots, is one of keywords.
+func linkScore(link string, density float64, keywords []string) float64 {
+	score := density
+	u, err := url.Parse(link)
+	if err != nil {
+		return score
+	}
+	segments := strings.FieldsFunc(strings.ToLower(u.Path), func(r rune) bool {
+		return r == '/' || r == '-' || r == '_' || r == '.'
+	})
+	for _, segment := range segments {
+		for _, keyword := range keywords {
+			if segment == keyword {
+				return score + 1
+			}
+		}
+	}
+	return score
+}
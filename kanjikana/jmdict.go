@@ -0,0 +1,197 @@
+package kanjikana
+
+import (
+	"encoding/xml"
+	"io"
+	"slices"
+	"strings"
+)
+
+// WordInfo holds the dictionary data of a word.
+type WordInfo struct {
+	Forms    []string `json:"forms"`
+	Readings []string `json:"readings"`
+	Glosses  []string `json:"glosses"`
+	// PartsOfSpeech holds the JMdict part-of-speech tags of the word, such
+	// as n, v5r or adj-i, those of the first sense first.
+	PartsOfSpeech []string `json:"parts_of_speech,omitempty"`
+	// Common reports whether the word is marked as common by one of the
+	// news1, ichi1, spec1, spec2 or gai1 priority tags.
+	Common bool `json:"common"`
+}
+
+// JMdict is a Japanese-English dictionary loaded from a JMdict file.
+type JMdict struct {
+	words map[string]*WordInfo
+}
+
+type jmdictEntry struct {
+	Kanji []struct {
+		Form     string   `xml:"keb"`
+		Priority []string `xml:"ke_pri"`
+	} `xml:"k_ele"`
+	Readings []struct {
+		Reading  string   `xml:"reb"`
+		Priority []string `xml:"re_pri"`
+	} `xml:"r_ele"`
+	Glosses []struct {
+		Lang  string `xml:"lang,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"sense>gloss"`
+	PartsOfSpeech []string `xml:"sense>pos"`
+}
+
+var commonPriorities = map[string]struct{}{
+	"news1": {},
+	"ichi1": {},
+	"spec1": {},
+	"spec2": {},
+	"gai1":  {},
+}
+
+// ParseJMdict reads a JMdict XML file, keeping the forms, readings and
+// English glosses of every entry. Words can be looked up by any of their
+// forms or readings; when several entries share one, common entries win over
+// the others and earlier entries over later ones.
+func ParseJMdict(r io.Reader) (*JMdict, error) {
+	jd := &JMdict{words: make(map[string]*WordInfo)}
+
+	decoder := xml.NewDecoder(r)
+	// JMdict marks parts of speech and other tags with entities declared in
+	// its DTD, which the decoder does not expand. They are left as is.
+	decoder.Strict = false
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "entry" {
+			continue
+		}
+
+		var entry jmdictEntry
+		if err := decoder.DecodeElement(&entry, &start); err != nil {
+			return nil, err
+		}
+
+		info := &WordInfo{}
+		for _, k := range entry.Kanji {
+			info.Forms = append(info.Forms, k.Form)
+			info.Common = info.Common || isCommonPriority(k.Priority)
+		}
+		for _, reading := range entry.Readings {
+			info.Readings = append(info.Readings, reading.Reading)
+			info.Common = info.Common || isCommonPriority(reading.Priority)
+		}
+		for _, pos := range entry.PartsOfSpeech {
+			// The tags are entities, left as is: &n; is the tag n.
+			pos = strings.TrimSuffix(strings.TrimPrefix(pos, "&"), ";")
+			if !slices.Contains(info.PartsOfSpeech, pos) {
+				info.PartsOfSpeech = append(info.PartsOfSpeech, pos)
+			}
+		}
+		for _, gloss := range entry.Glosses {
+			if gloss.Lang == "" || gloss.Lang == "eng" {
+				info.Glosses = append(info.Glosses, gloss.Value)
+			}
+		}
+
+		for _, key := range append(append([]string(nil), info.Forms...), info.Readings...) {
+			if existing, ok := jd.words[key]; ok && (existing.Common || !info.Common) {
+				continue
+			}
+			jd.words[key] = info
+		}
+	}
+
+	return jd, nil
+}
+
+func isCommonPriority(priorities []string) bool {
+	for _, p := range priorities {
+		if _, ok := commonPriorities[p]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// PartOfSpeech returns the class of the word, such as PartOfSpeechNoun, given
+// by its first part-of-speech tag, or an empty string when it has none.
+func (wi *WordInfo) PartOfSpeech() string {
+	if len(wi.PartsOfSpeech) == 0 {
+		return ""
+	}
+	return PartOfSpeechClass(wi.PartsOfSpeech[0])
+}
+
+// Part-of-speech classes.
+const (
+	PartOfSpeechNoun         = "noun"
+	PartOfSpeechPronoun      = "pronoun"
+	PartOfSpeechVerb         = "verb"
+	PartOfSpeechAdjective    = "adjective"
+	PartOfSpeechAdverb       = "adverb"
+	PartOfSpeechParticle     = "particle"
+	PartOfSpeechAuxiliary    = "auxiliary"
+	PartOfSpeechConjunction  = "conjunction"
+	PartOfSpeechInterjection = "interjection"
+	PartOfSpeechPrefix       = "prefix"
+	PartOfSpeechSuffix       = "suffix"
+	PartOfSpeechCounter      = "counter"
+	PartOfSpeechExpression   = "expression"
+	PartOfSpeechOther        = "other"
+)
+
+// PartOfSpeechClass returns the class of the JMdict part-of-speech tag, such
+// as PartOfSpeechVerb for v5r or vs-i, or PartOfSpeechOther when the tag is
+// not known.
+func PartOfSpeechClass(tag string) string {
+	switch {
+	case tag == "pn":
+		return PartOfSpeechPronoun
+	case tag == "n" || strings.HasPrefix(tag, "n-") || tag == "num":
+		return PartOfSpeechNoun
+	case strings.HasPrefix(tag, "aux"):
+		return PartOfSpeechAuxiliary
+	case strings.HasPrefix(tag, "v"):
+		return PartOfSpeechVerb
+	case strings.HasPrefix(tag, "adj"):
+		return PartOfSpeechAdjective
+	case strings.HasPrefix(tag, "adv"):
+		return PartOfSpeechAdverb
+	case tag == "prt":
+		return PartOfSpeechParticle
+	case tag == "conj":
+		return PartOfSpeechConjunction
+	case tag == "int":
+		return PartOfSpeechInterjection
+	case tag == "pref":
+		return PartOfSpeechPrefix
+	case tag == "suf":
+		return PartOfSpeechSuffix
+	case tag == "ctr":
+		return PartOfSpeechCounter
+	case tag == "exp":
+		return PartOfSpeechExpression
+	default:
+		return PartOfSpeechOther
+	}
+}
+
+// Lookup returns the dictionary data of word, or nil when it is not in the
+// dictionary.
+func (jd *JMdict) Lookup(word string) *WordInfo {
+	return jd.words[word]
+}
+
+// Len returns the number of forms and readings that can be looked up.
+func (jd *JMdict) Len() int {
+	return len(jd.words)
+}
@@ -0,0 +1,165 @@
+package kanjikana
+
+import "encoding/json"
+
+type jsonResult struct {
+	AllCharactersCount  int                     `json:"all_characters_count"`
+	UniqueCount         int                     `json:"unique_count"`
+	KanjiUniqueCount    int                     `json:"kanji_unique_count"`
+	KanaUniqueCount     int                     `json:"kana_unique_count"`
+	HiraganaUniqueCount int                     `json:"hiragana_unique_count"`
+	KatakanaUniqueCount int                     `json:"katakana_unique_count"`
+	SkippedChineseCount int                     `json:"skipped_chinese_count,omitempty"`
+	Kanjis              []CharacterFrequency    `json:"kanjis"`
+	Hiraganas           []CharacterFrequency    `json:"hiraganas"`
+	Katakanas           []CharacterFrequency    `json:"katakanas"`
+	Words               []WordFrequency         `json:"words,omitempty"`
+	NGramSize           int                     `json:"ngram_size,omitempty"`
+	NGrams              []NGramFrequency        `json:"ngrams,omitempty"`
+	Compounds           []CompoundFrequency     `json:"compounds,omitempty"`
+	Punctuation         []CharacterFrequency    `json:"punctuation,omitempty"`
+	Readings            []ReadingFrequency      `json:"readings,omitempty"`
+	PartsOfSpeech       []PartOfSpeechFrequency `json:"parts_of_speech,omitempty"`
+	Sentences           *SentenceStats          `json:"sentences,omitempty"`
+	Files               map[string]*Result      `json:"files,omitempty"`
+	Domains             map[string]*Result      `json:"domains,omitempty"`
+	Roots               map[string]*Result      `json:"roots,omitempty"`
+	Pages               []PageStats             `json:"pages,omitempty"`
+	NonJapanesePages    int                     `json:"non_japanese_pages,omitempty"`
+	DuplicatePages      int                     `json:"duplicate_pages,omitempty"`
+	RepeatedBlocks      int                     `json:"repeated_blocks,omitempty"`
+	Chapters            []ChapterStats          `json:"chapters,omitempty"`
+	Examples            map[string]string       `json:"examples,omitempty"`
+}
+
+// MarshalJSON encodes the result with its characters ranked by frequency.
+func (r *Result) MarshalJSON() ([]byte, error) {
+	jr := jsonResult{
+		AllCharactersCount:  r.AllCharactersCount,
+		UniqueCount:         r.UniqueCount,
+		KanjiUniqueCount:    r.KanjiUniqueCount,
+		KanaUniqueCount:     r.KanaUniqueCount,
+		HiraganaUniqueCount: r.HiraganaUniqueCount,
+		KatakanaUniqueCount: r.KatakanaUniqueCount,
+		SkippedChineseCount: r.SkippedChineseCount,
+		Kanjis:              Ranking(r.Kanjis),
+		Hiraganas:           Ranking(r.Hiraganas),
+		Katakanas:           Ranking(r.Katakanas),
+		Words:               WordRanking(r.Words),
+		NGramSize:           r.NGramSize,
+		NGrams:              NGramRanking(r.NGrams),
+		Compounds:           CompoundRanking(r.Compounds),
+		Punctuation:         Ranking(r.Punctuation),
+		Readings:            ReadingRanking(r.Readings),
+		PartsOfSpeech:       PartOfSpeechRanking(r.PartsOfSpeech),
+		Sentences:           r.Sentences,
+		Files:               r.Files,
+		Domains:             r.Domains,
+		Roots:               r.Roots,
+		Pages:               r.Pages,
+		NonJapanesePages:    r.NonJapanesePages,
+		DuplicatePages:      r.DuplicatePages,
+		RepeatedBlocks:      r.RepeatedBlocks,
+		Chapters:            r.Chapters,
+		Examples:            r.Examples,
+	}
+	for _, ranking := range [][]CharacterFrequency{jr.Kanjis, jr.Hiraganas, jr.Katakanas, jr.Punctuation} {
+		for i := range ranking {
+			ranking[i].PerThousand = PerThousand(ranking[i].Count, r.AllCharactersCount)
+		}
+	}
+	for i := range jr.Words {
+		jr.Words[i].PerThousand = PerThousand(jr.Words[i].Count, r.AllCharactersCount)
+	}
+	for i := range jr.NGrams {
+		jr.NGrams[i].PerThousand = PerThousand(jr.NGrams[i].Count, r.AllCharactersCount)
+	}
+	for _, pos := range jr.PartsOfSpeech {
+		for i := range pos.Words {
+			pos.Words[i].PerThousand = PerThousand(pos.Words[i].Count, r.AllCharactersCount)
+		}
+	}
+	for i := range jr.Compounds {
+		jr.Compounds[i].PerThousand = PerThousand(jr.Compounds[i].Count, r.AllCharactersCount)
+	}
+	return json.Marshal(jr)
+}
+
+// UnmarshalJSON decodes a result previously encoded by MarshalJSON.
+func (r *Result) UnmarshalJSON(data []byte) error {
+	var jr jsonResult
+	if err := json.Unmarshal(data, &jr); err != nil {
+		return err
+	}
+
+	r.AllCharactersCount = jr.AllCharactersCount
+	r.UniqueCount = jr.UniqueCount
+	r.KanjiUniqueCount = jr.KanjiUniqueCount
+	r.KanaUniqueCount = jr.KanaUniqueCount
+	r.HiraganaUniqueCount = jr.HiraganaUniqueCount
+	r.KatakanaUniqueCount = jr.KatakanaUniqueCount
+	r.SkippedChineseCount = jr.SkippedChineseCount
+	r.Kanjis = frequencyMap(jr.Kanjis)
+	r.Hiraganas = frequencyMap(jr.Hiraganas)
+	r.Katakanas = frequencyMap(jr.Katakanas)
+	if jr.Words != nil {
+		r.Words = make(map[string]int, len(jr.Words))
+		for _, f := range jr.Words {
+			r.Words[f.Word] = f.Count
+		}
+	}
+	r.NGramSize = jr.NGramSize
+	if jr.NGrams != nil {
+		r.NGrams = make(map[string]int, len(jr.NGrams))
+		for _, f := range jr.NGrams {
+			r.NGrams[f.NGram] = f.Count
+		}
+	}
+	if jr.Compounds != nil {
+		r.Compounds = make(map[string]int, len(jr.Compounds))
+		for _, f := range jr.Compounds {
+			r.Compounds[f.Compound] = f.Count
+		}
+	}
+	if jr.Punctuation != nil {
+		r.Punctuation = frequencyMap(jr.Punctuation)
+	}
+	if jr.PartsOfSpeech != nil {
+		r.PartsOfSpeech = make(map[string]map[string]int, len(jr.PartsOfSpeech))
+		for _, f := range jr.PartsOfSpeech {
+			r.PartsOfSpeech[f.PartOfSpeech] = make(map[string]int, len(f.Words))
+			for _, word := range f.Words {
+				r.PartsOfSpeech[f.PartOfSpeech][word.Word] = word.Count
+			}
+		}
+	}
+	if jr.Readings != nil {
+		r.Readings = make(map[string]map[string]int, len(jr.Readings))
+		for _, f := range jr.Readings {
+			r.Readings[f.Kanji] = make(map[string]int, len(f.Readings))
+			for _, reading := range f.Readings {
+				r.Readings[f.Kanji][reading.Reading] = reading.Count
+			}
+		}
+	}
+	r.Sentences = jr.Sentences
+	r.Files = jr.Files
+	r.Domains = jr.Domains
+	r.Roots = jr.Roots
+	r.Pages = jr.Pages
+	r.NonJapanesePages = jr.NonJapanesePages
+	r.DuplicatePages = jr.DuplicatePages
+	r.RepeatedBlocks = jr.RepeatedBlocks
+	r.Chapters = jr.Chapters
+	r.Examples = jr.Examples
+
+	return nil
+}
+
+func frequencyMap(ranking []CharacterFrequency) map[string]int {
+	m := make(map[string]int, len(ranking))
+	for _, f := range ranking {
+		m[f.Character] = f.Count
+	}
+	return m
+}
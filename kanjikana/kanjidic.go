@@ -0,0 +1,134 @@
+package kanjikana
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// KanjiInfo holds the dictionary data of a kanji.
+type KanjiInfo struct {
+	Literal     string   `json:"literal"`
+	OnReadings  []string `json:"on_readings"`
+	KunReadings []string `json:"kun_readings"`
+	Meanings    []string `json:"meanings"`
+	StrokeCount int      `json:"stroke_count,omitempty"`
+}
+
+// Kanjidic is a kanji dictionary loaded from a KANJIDIC2 file.
+type Kanjidic struct {
+	entries map[string]*KanjiInfo
+}
+
+type kanjidicCharacter struct {
+	Literal string `xml:"literal"`
+	// StrokeCounts lists the accepted stroke count first, followed by
+	// common miscounts.
+	StrokeCounts []int `xml:"misc>stroke_count"`
+	Readings     []struct {
+		Type  string `xml:"r_type,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"reading_meaning>rmgroup>reading"`
+	Meanings []struct {
+		Lang  string `xml:"m_lang,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"reading_meaning>rmgroup>meaning"`
+}
+
+// ParseKanjidic reads a KANJIDIC2 XML file, keeping the Japanese readings
+// and the English meanings of every kanji.
+func ParseKanjidic(r io.Reader) (*Kanjidic, error) {
+	kd := &Kanjidic{entries: make(map[string]*KanjiInfo)}
+
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "character" {
+			continue
+		}
+
+		var c kanjidicCharacter
+		if err := decoder.DecodeElement(&c, &start); err != nil {
+			return nil, err
+		}
+
+		info := &KanjiInfo{Literal: c.Literal}
+		if len(c.StrokeCounts) > 0 {
+			info.StrokeCount = c.StrokeCounts[0]
+		}
+		for _, reading := range c.Readings {
+			switch reading.Type {
+			case "ja_on":
+				info.OnReadings = append(info.OnReadings, reading.Value)
+			case "ja_kun":
+				info.KunReadings = append(info.KunReadings, reading.Value)
+			}
+		}
+		for _, meaning := range c.Meanings {
+			if meaning.Lang == "" || meaning.Lang == "en" {
+				info.Meanings = append(info.Meanings, meaning.Value)
+			}
+		}
+		kd.entries[c.Literal] = info
+	}
+
+	return kd, nil
+}
+
+// Lookup returns the dictionary data of kanji, or nil when it is not in the
+// dictionary.
+func (kd *Kanjidic) Lookup(kanji string) *KanjiInfo {
+	return kd.entries[kanji]
+}
+
+// Len returns the number of kanji in the dictionary.
+func (kd *Kanjidic) Len() int {
+	return len(kd.entries)
+}
+
+// StrokeStats summarizes the kanji of a result that have a given number of
+// strokes.
+type StrokeStats struct {
+	Strokes int `json:"strokes"`
+	// Kanjis is the number of distinct kanji with that many strokes.
+	Kanjis int `json:"kanjis"`
+	// Occurrences is the number of occurrences of those kanji.
+	Occurrences int `json:"occurrences"`
+}
+
+// StrokeHistogram groups the kanji counts m by stroke count, from the
+// simplest to the most complex kanji. Kanji missing from the dictionary are
+// left out.
+func (kd *Kanjidic) StrokeHistogram(m map[string]int) []StrokeStats {
+	byStrokes := make(map[int]*StrokeStats)
+	maxStrokes := 0
+	for c, count := range m {
+		info := kd.entries[c]
+		if info == nil || info.StrokeCount == 0 {
+			continue
+		}
+		stats, ok := byStrokes[info.StrokeCount]
+		if !ok {
+			stats = &StrokeStats{Strokes: info.StrokeCount}
+			byStrokes[info.StrokeCount] = stats
+		}
+		stats.Kanjis++
+		stats.Occurrences += count
+		maxStrokes = max(maxStrokes, info.StrokeCount)
+	}
+
+	var histogram []StrokeStats
+	for strokes := 1; strokes <= maxStrokes; strokes++ {
+		if stats, ok := byStrokes[strokes]; ok {
+			histogram = append(histogram, *stats)
+		}
+	}
+	return histogram
+}
@@ -0,0 +1,19 @@
+// Package kanjikana counts the frequency of Kanji, Hiragana and Katakana
+// characters found on websites and in arbitrary text.
+package kanjikana
+
+import "net/url"
+
+const (
+	DefaultURL         = "https://www.yomiuri.co.jp"
+	DefaultSearchDepth = 1
+	MaxSearchDepth     = 10
+	DefaultConcurrency = 4
+	MaxNGramSize       = 5
+)
+
+// ValidateURL reports whether url looks like a crawlable website address.
+func ValidateURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
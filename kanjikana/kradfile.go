@@ -0,0 +1,70 @@
+package kanjikana
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Kradfile maps kanji to the components they are made of, as listed in the
+// KRADFILE and KRADFILE2 decomposition files.
+type Kradfile struct {
+	components map[string][]string
+}
+
+// ParseKradfile reads a UTF-8 encoded KRADFILE, where every line lists a
+// kanji followed by a colon and its space separated components, e.g.
+// "語 : 口 五 言". Lines starting with # are comments. Several files can be
+// parsed into one Kradfile with Parse.
+func ParseKradfile(r io.Reader) (*Kradfile, error) {
+	kf := &Kradfile{components: make(map[string][]string)}
+	if err := kf.Parse(r); err != nil {
+		return nil, err
+	}
+	return kf, nil
+}
+
+// Parse adds the decompositions of another KRADFILE, e.g. KRADFILE2 which
+// covers the JIS X 0212 kanji.
+func (kf *Kradfile) Parse(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		kanji, components, ok := strings.Cut(line, ":")
+		if !ok {
+			return fmt.Errorf("line %d: missing colon after kanji", lineNumber)
+		}
+		kanji = strings.TrimSpace(kanji)
+		if _, ok := kf.components[kanji]; ok {
+			continue
+		}
+		kf.components[kanji] = strings.Fields(components)
+	}
+	return scanner.Err()
+}
+
+// Components returns the components of kanji, or nil when it is not in the
+// file.
+func (kf *Kradfile) Components(kanji string) []string {
+	return append([]string(nil), kf.components[kanji]...)
+}
+
+// CountComponents adds up the occurrences of the kanji of m under each of
+// their components: a kanji seen 3 times adds 3 to every one of its
+// components.
+func (kf *Kradfile) CountComponents(m map[string]int) map[string]int {
+	counts := make(map[string]int)
+	for c, count := range m {
+		for _, component := range kf.components[c] {
+			counts[component] += count
+		}
+	}
+	return counts
+}
@@ -0,0 +1,167 @@
+package kanjikana
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// KanjiLevels assigns kanji to ordered levels, such as JLPT levels or school
+// grades.
+type KanjiLevels struct {
+	names  []string
+	levels map[string]string
+	kanjis map[string][]string
+}
+
+// LevelStats summarizes how the kanji of a level appear in a result.
+type LevelStats struct {
+	Level string `json:"level"`
+	// Occurrences is the number of occurrences of the kanji of the level.
+	Occurrences int `json:"occurrences"`
+	// Seen is the number of distinct kanji of the level that appeared.
+	Seen int `json:"seen"`
+	// Total is the number of kanji of the level.
+	Total int `json:"total"`
+	// Coverage is the percentage of the kanji of the level that appeared.
+	Coverage float64 `json:"coverage"`
+}
+
+// ParseKanjiLevels reads a level mapping with one level per line: the level
+// name followed by its kanji. Blank lines and lines starting with # are
+// ignored. A kanji listed under several levels keeps the first one.
+func ParseKanjiLevels(r io.Reader) (*KanjiLevels, error) {
+	kl := &KanjiLevels{
+		levels: make(map[string]string),
+		kanjis: make(map[string][]string),
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, kanjis, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing kanji after level %q", lineNumber, name)
+		}
+		if _, ok := kl.kanjis[name]; !ok {
+			kl.names = append(kl.names, name)
+			kl.kanjis[name] = nil
+		}
+		for _, r := range kanjis {
+			if unicode.IsSpace(r) {
+				continue
+			}
+			c := string(r)
+			if _, ok := kl.levels[c]; ok {
+				continue
+			}
+			kl.levels[c] = name
+			kl.kanjis[name] = append(kl.kanjis[name], c)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return kl, nil
+}
+
+func mustParseKanjiLevels(data string) *KanjiLevels {
+	kl, err := ParseKanjiLevels(strings.NewReader(data))
+	if err != nil {
+		panic(err)
+	}
+	return kl
+}
+
+// Levels returns the level names in the order they were defined.
+func (kl *KanjiLevels) Levels() []string {
+	return append([]string(nil), kl.names...)
+}
+
+// Level returns the level of kanji, or an empty string when it has none.
+func (kl *KanjiLevels) Level(kanji string) string {
+	return kl.levels[kanji]
+}
+
+// Kanjis returns the kanji of level.
+func (kl *KanjiLevels) Kanjis(level string) []string {
+	return append([]string(nil), kl.kanjis[level]...)
+}
+
+// Stats reports, for every level, how its kanji appear in the kanji counts m.
+func (kl *KanjiLevels) Stats(m map[string]int) []LevelStats {
+	stats := make([]LevelStats, len(kl.names))
+	for i, name := range kl.names {
+		stats[i].Level = name
+		stats[i].Total = len(kl.kanjis[name])
+		for _, c := range kl.kanjis[name] {
+			if count := m[c]; count > 0 {
+				stats[i].Occurrences += count
+				stats[i].Seen++
+			}
+		}
+		if stats[i].Total > 0 {
+			stats[i].Coverage = 100 * float64(stats[i].Seen) / float64(stats[i].Total)
+		}
+	}
+	return stats
+}
+
+// Unseen returns the kanji of every level that do not appear in the kanji
+// counts m, in the order they were defined.
+func (kl *KanjiLevels) Unseen(m map[string]int) []string {
+	var unseen []string
+	for _, name := range kl.names {
+		for _, c := range kl.kanjis[name] {
+			if m[c] == 0 {
+				unseen = append(unseen, c)
+			}
+		}
+	}
+	return unseen
+}
+
+//go:embed data/jlpt.txt
+var jlptData string
+
+var jlptLevels = mustParseKanjiLevels(jlptData)
+
+// JLPTLevels returns the bundled JLPT kanji levels. JLPT lists are
+// unofficial and the bundled one only covers N5 and N4; use
+// ParseKanjiLevels to load a complete mapping.
+func JLPTLevels() *KanjiLevels {
+	return jlptLevels
+}
+
+//go:embed data/joyo.txt
+var joyoData string
+
+var joyoKanji = mustParseKanjiLevels(joyoData)
+
+// JoyoKanji returns the 2,136 jōyō kanji as a single level named "joyo".
+func JoyoKanji() *KanjiLevels {
+	return joyoKanji
+}
+
+//go:embed data/grades.txt
+var gradesData string
+
+var schoolGrades = mustParseKanjiLevels(gradesData)
+
+// SchoolGrades returns the jōyō kanji grouped by the school grade in which
+// they are taught: the kyōiku kanji under levels "grade1" to "grade6" and the
+// remaining ones under "secondary".
+func SchoolGrades() *KanjiLevels {
+	return schoolGrades
+}
@@ -0,0 +1,830 @@
+package kanjikana
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type scraperOptions struct {
+	searchDepth    *int
+	logger         *slog.Logger
+	concurrency    int
+	rateLimit      float64
+	hostRequests   int
+	sameDomainOnly bool
+	perDomain      bool
+	ignoreRobots   bool
+	minJapanese    float64
+	keepDuplicates bool
+	maxRedirects   *int
+	sameHostRedir  bool
+	allowedHosts   []string
+	deniedHosts    []string
+	linkPatterns   []*regexp.Regexp
+	excludes       []*regexp.Regexp
+	selectors      []simpleSelector
+	timeout        time.Duration
+	deadline       time.Time
+	requestTimeout time.Duration
+	proxyURL       *url.URL
+	client         *http.Client
+	renderer       Renderer
+	jar            http.CookieJar
+	cookies        []*http.Cookie
+	basicAuth      *url.Userinfo
+	retries        int
+	sitemap        bool
+	feed           bool
+	maxPages       int
+	stopKanjis     int
+	stopCharacters int
+	linkSample     float64
+	wayback        string
+	warc           *warcWriter
+	mainContent    bool
+	dedupBlocks    bool
+	strategy       CrawlStrategy
+	keywords       []string
+	cacheDir       string
+	countOptions   []CountOption
+	pageHandler    func(pageURL, text string)
+	progress       func(Progress)
+	fetchObserver  func(pageURL string, size int, err error)
+}
+
+// Option configures a Scraper.
+type Option func(*scraperOptions) error
+
+type countOptions struct {
+	tokenizer   Tokenizer
+	ngramSize   int
+	punctuation bool
+	compounds   bool
+	readings    bool
+	// partsOfSpeech counts the words of the tokenizer by part of speech.
+	partsOfSpeech bool
+	sentences     bool
+	// halfWidthKatakana keeps half-width katakana instead of normalizing
+	// them to full-width.
+	halfWidthKatakana bool
+	morae             bool
+	iterationMark     IterationMark
+	longVowelMark     LongVowelMark
+	// variants replaces kanji variants by their standard forms.
+	variants *VariantTable
+	// excludeChinese skips the text detected as Chinese.
+	excludeChinese bool
+}
+
+// CountOption configures how a Counter counts text.
+type CountOption func(*countOptions) error
+
+func newCountOptions(options []CountOption) (countOptions, error) {
+	var opts countOptions
+	for _, opt := range options {
+		if err := opt(&opts); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+func WithSearchDepth(depth int) Option {
+	return func(opts *scraperOptions) error {
+		if depth < 0 {
+			return errors.New("search depth should be positive")
+		}
+		if depth >= MaxSearchDepth {
+			return errors.New("search depth exceeds default maximum depth")
+		}
+		opts.searchDepth = &depth
+		return nil
+	}
+}
+
+// WithLogging logs the crawl settings, retries and visited pages to the
+// default slog logger.
+func WithLogging() Option {
+	return func(opts *scraperOptions) error {
+		opts.logger = slog.Default()
+		return nil
+	}
+}
+
+// WithLogger is like WithLogging but logs to l. Fetch errors are logged to
+// the default slog logger when neither option is set.
+func WithLogger(l *slog.Logger) Option {
+	return func(opts *scraperOptions) error {
+		if l == nil {
+			return errors.New("logger should not be nil")
+		}
+		opts.logger = l
+		return nil
+	}
+}
+
+// WithConcurrency sets the number of pages fetched in parallel.
+func WithConcurrency(n int) Option {
+	return func(opts *scraperOptions) error {
+		if n < 1 {
+			return errors.New("concurrency should be at least 1")
+		}
+		opts.concurrency = n
+		return nil
+	}
+}
+
+// WithHostConcurrency fetches at most n pages of the same host at once,
+// whatever the concurrency, to stay polite to every site of a crawl. The
+// default is DefaultHostConcurrency.
+func WithHostConcurrency(n int) Option {
+	return func(opts *scraperOptions) error {
+		if n < 1 {
+			return errors.New("host concurrency should be at least 1")
+		}
+		opts.hostRequests = n
+		return nil
+	}
+}
+
+// WithRateLimit throttles the requests made to the same host to at most
+// requestsPerSecond.
+func WithRateLimit(requestsPerSecond float64) Option {
+	return func(opts *scraperOptions) error {
+		if requestsPerSecond <= 0 {
+			return errors.New("rate limit should be positive")
+		}
+		opts.rateLimit = requestsPerSecond
+		return nil
+	}
+}
+
+// WithSameDomainOnly restricts the crawl to the host of the root URL.
+func WithSameDomainOnly() Option {
+	return func(opts *scraperOptions) error {
+		opts.sameDomainOnly = true
+		return nil
+	}
+}
+
+// WithPerDomain also counts the pages of every host separately, reported in
+// Result.Domains, to compare the sites reached by a crawl.
+func WithPerDomain() Option {
+	return func(opts *scraperOptions) error {
+		opts.perDomain = true
+		return nil
+	}
+}
+
+// WithIgnoreRobots follows the links marked rel="nofollow" and counts the
+// pages whose robots meta tag asks for noindex or nofollow, which the
+// crawler otherwise respects.
+func WithIgnoreRobots() Option {
+	return func(opts *scraperOptions) error {
+		opts.ignoreRobots = true
+		return nil
+	}
+}
+
+// WithMinJapaneseRatio skips the pages whose share of Japanese characters,
+// among the letters and digits of their visible text, is below ratio, such
+// as the English sections of a Japanese site. Their links are still
+// followed. The skipped pages are counted in Result.NonJapanesePages.
+func WithMinJapaneseRatio(ratio float64) Option {
+	return func(opts *scraperOptions) error {
+		if ratio < 0 || ratio > 1 {
+			return errors.New("minimum Japanese ratio should be between 0 and 1")
+		}
+		opts.minJapanese = ratio
+		return nil
+	}
+}
+
+// WithDuplicatePages counts every page, including the ones that duplicate a
+// page already counted. By default, a page whose <link rel="canonical"> or
+// URL is the canonical URL of a counted page, or whose text is the same as a
+// counted page, such as print views, AMP pages and URLs with tracking
+// parameters, is skipped and counted in Result.DuplicatePages.
+func WithDuplicatePages() Option {
+	return func(opts *scraperOptions) error {
+		opts.keepDuplicates = true
+		return nil
+	}
+}
+
+// WithMaxRedirects follows at most n redirects per page, 0 to follow none.
+// Pages over the limit are not counted. The default is 10.
+func WithMaxRedirects(n int) Option {
+	return func(opts *scraperOptions) error {
+		if n < 0 {
+			return errors.New("maximum number of redirects should not be negative")
+		}
+		opts.maxRedirects = &n
+		return nil
+	}
+}
+
+// WithSameHostRedirects only follows the redirects to the host of the
+// redirecting page. With WithSameDomainOnly, redirects that leave the host
+// of the root URL are not followed either, but for the ones of the root URL
+// itself.
+func WithSameHostRedirects() Option {
+	return func(opts *scraperOptions) error {
+		opts.sameHostRedir = true
+		return nil
+	}
+}
+
+// WithAllowedHosts only follows the links to the given hosts, their
+// subdomains and the host of the root URL. With WithSameDomainOnly, it adds
+// hosts to the one of the root URL, as www3.nhk.or.jp to www.nhk.or.jp.
+func WithAllowedHosts(hosts ...string) Option {
+	return func(opts *scraperOptions) error {
+		for _, host := range hosts {
+			host, err := hostPattern(host)
+			if err != nil {
+				return err
+			}
+			opts.allowedHosts = append(opts.allowedHosts, host)
+		}
+		return nil
+	}
+}
+
+// WithDeniedHosts never follows the links to the given hosts nor their
+// subdomains, as ad servers and CDNs. It takes precedence over
+// WithAllowedHosts.
+func WithDeniedHosts(hosts ...string) Option {
+	return func(opts *scraperOptions) error {
+		for _, host := range hosts {
+			host, err := hostPattern(host)
+			if err != nil {
+				return err
+			}
+			opts.deniedHosts = append(opts.deniedHosts, host)
+		}
+		return nil
+	}
+}
+
+// WithWayback crawls the snapshots of the Wayback Machine of archive.org
+// closest to timestamp, a date of the form yyyyMMddhhmmss possibly truncated
+// down to its year, as 2005 or 20050401, instead of the live site. URLs
+// keep referring to the archived pages, so that the other options apply as
+// to the original site.
+func WithWayback(timestamp string) Option {
+	return func(opts *scraperOptions) error {
+		if err := validWaybackTimestamp(timestamp); err != nil {
+			return err
+		}
+		opts.wayback = timestamp
+		return nil
+	}
+}
+
+// WithLinkPattern only follows the links whose URL matches re, or one of
+// the patterns of the other WithLinkPattern options. The root URL is always
+// visited.
+func WithLinkPattern(re *regexp.Regexp) Option {
+	return func(opts *scraperOptions) error {
+		if re == nil {
+			return errors.New("link pattern should not be nil")
+		}
+		opts.linkPatterns = append(opts.linkPatterns, re)
+		return nil
+	}
+}
+
+// WithExcludePattern does not follow the links whose URL matches re, such
+// as the English or photo sections of a news site. It takes precedence
+// over WithLinkPattern. The root URL is always visited.
+func WithExcludePattern(re *regexp.Regexp) Option {
+	return func(opts *scraperOptions) error {
+		if re == nil {
+			return errors.New("exclude pattern should not be nil")
+		}
+		opts.excludes = append(opts.excludes, re)
+		return nil
+	}
+}
+
+// WithMainContent only counts the main article of every page, found as by
+// the Readability algorithm, leaving out the menus, footers and sidebars
+// whose labels repeat on every page. Pages whose article is not found are
+// counted in full. WithContentSelector takes precedence.
+func WithMainContent() Option {
+	return func(opts *scraperOptions) error {
+		opts.mainContent = true
+		return nil
+	}
+}
+
+// WithBoilerplateDedup counts the text blocks repeated verbatim on several
+// pages of a host, such as headers, footers and cookie banners, on the first
+// page only. Blocks shorter than 10 characters, as the words of links inside
+// paragraphs, are always counted. Skipped blocks are counted in
+// Result.RepeatedBlocks.
+func WithBoilerplateDedup() Option {
+	return func(opts *scraperOptions) error {
+		opts.dedupBlocks = true
+		return nil
+	}
+}
+
+// WithContentSelector only counts the text of the elements matching
+// selector, a comma-separated list of tag names, #ids and .classes such as
+// "article, div.main_text", to skip the navigation of article pages. Pages
+// without a matching element are not counted, but their links are followed.
+func WithContentSelector(selector string) Option {
+	return func(opts *scraperOptions) error {
+		selectors, err := parseSelector(selector)
+		if err != nil {
+			return err
+		}
+		opts.selectors = selectors
+		return nil
+	}
+}
+
+// WithTimeout bounds the duration of the whole crawl. Pages still being
+// fetched when the timeout expires are dropped.
+func WithTimeout(d time.Duration) Option {
+	return func(opts *scraperOptions) error {
+		if d <= 0 {
+			return errors.New("timeout should be positive")
+		}
+		opts.timeout = d
+		return nil
+	}
+}
+
+// WithCrawlDeadline stops the crawl at t, like WithTimeout does after a
+// duration. When both are set, the crawl stops at the earliest.
+func WithCrawlDeadline(t time.Time) Option {
+	return func(opts *scraperOptions) error {
+		if t.IsZero() {
+			return errors.New("crawl deadline should be set")
+		}
+		opts.deadline = t
+		return nil
+	}
+}
+
+// WithRequestTimeout bounds the duration of every request, from connecting
+// to reading the whole page, so that a stalled server does not hold a worker
+// for the rest of the crawl. A request timing out is retried like other
+// network errors.
+func WithRequestTimeout(d time.Duration) Option {
+	return func(opts *scraperOptions) error {
+		if d <= 0 {
+			return errors.New("request timeout should be positive")
+		}
+		opts.requestTimeout = d
+		return nil
+	}
+}
+
+// WithProxy routes every request through an HTTP, HTTPS or SOCKS5 proxy,
+// e.g. "socks5://localhost:1080".
+func WithProxy(proxyURL string) Option {
+	return func(opts *scraperOptions) error {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return fmt.Errorf("unsupported proxy scheme: %q", u.Scheme)
+		}
+		opts.proxyURL = u
+		return nil
+	}
+}
+
+// WithHTTPClient makes the requests of the crawl with client, e.g. to use a
+// custom TLS configuration or an instrumented or recording transport. It
+// cannot be combined with WithProxy: set the proxy of the transport instead.
+// The cookie jar of client is used, or a new one when it has none.
+func WithHTTPClient(client *http.Client) Option {
+	return func(opts *scraperOptions) error {
+		if client == nil {
+			return errors.New("HTTP client should not be nil")
+		}
+		opts.client = client
+		return nil
+	}
+}
+
+// WithCookieJar stores and sends the cookies of the crawled sites in jar
+// instead of a new in-memory jar, to share a session with other clients.
+func WithCookieJar(jar http.CookieJar) Option {
+	return func(opts *scraperOptions) error {
+		if jar == nil {
+			return errors.New("cookie jar should not be nil")
+		}
+		opts.jar = jar
+		return nil
+	}
+}
+
+// WithCookie sends cookie to the host of the root URL, such as the session
+// cookie of a logged-in browser, to crawl pages behind a login.
+func WithCookie(cookie *http.Cookie) Option {
+	return func(opts *scraperOptions) error {
+		if cookie == nil || cookie.Name == "" {
+			return errors.New("cookie should have a name")
+		}
+		opts.cookies = append(opts.cookies, cookie)
+		return nil
+	}
+}
+
+// WithBasicAuth authenticates the requests made to the host of the root URL
+// with HTTP basic authentication.
+func WithBasicAuth(username, password string) Option {
+	return func(opts *scraperOptions) error {
+		if username == "" {
+			return errors.New("basic auth username should not be empty")
+		}
+		opts.basicAuth = url.UserPassword(username, password)
+		return nil
+	}
+}
+
+// WithRetries retries failed fetches, 5xx and 429 responses up to n times
+// with exponential backoff and jitter.
+func WithRetries(n int) Option {
+	return func(opts *scraperOptions) error {
+		if n < 0 {
+			return errors.New("retries should be positive")
+		}
+		opts.retries = n
+		return nil
+	}
+}
+
+// WithSitemap seeds the crawl with the pages listed in the sitemap of the
+// site instead of following links. Sitemap index files are followed.
+func WithSitemap() Option {
+	return func(opts *scraperOptions) error {
+		opts.sitemap = true
+		return nil
+	}
+}
+
+// WithFeed treats the URL given to Scrape as an RSS or Atom feed and crawls
+// the articles it links to instead of following links.
+func WithFeed() Option {
+	return func(opts *scraperOptions) error {
+		opts.feed = true
+		return nil
+	}
+}
+
+// WithMaxPages stops the crawl after n pages, regardless of the search depth.
+func WithMaxPages(n int) Option {
+	return func(opts *scraperOptions) error {
+		if n < 1 {
+			return errors.New("max pages should be at least 1")
+		}
+		opts.maxPages = n
+		return nil
+	}
+}
+
+// WithStopAfterUniqueKanjis stops the crawl once n different kanji are
+// counted. The pages being fetched are still counted.
+func WithStopAfterUniqueKanjis(n int) Option {
+	return func(opts *scraperOptions) error {
+		if n < 1 {
+			return errors.New("number of unique kanji should be at least 1")
+		}
+		opts.stopKanjis = n
+		return nil
+	}
+}
+
+// WithStopAfterCharacters stops the crawl once n Japanese characters are
+// counted. The pages being fetched are still counted.
+func WithStopAfterCharacters(n int) Option {
+	return func(opts *scraperOptions) error {
+		if n < 1 {
+			return errors.New("number of characters should be at least 1")
+		}
+		opts.stopCharacters = n
+		return nil
+	}
+}
+
+// WithLinkSample only follows a random fraction of the links of every page,
+// at least one, to count a sample of a site too large to crawl in full.
+func WithLinkSample(fraction float64) Option {
+	return func(opts *scraperOptions) error {
+		if fraction <= 0 || fraction > 1 {
+			return errors.New("link sample should be greater than 0 and at most 1")
+		}
+		opts.linkSample = fraction
+		return nil
+	}
+}
+
+// WithCrawlStrategy sets the order in which discovered pages are visited.
+// The default is BFS.
+func WithCrawlStrategy(strategy CrawlStrategy) Option {
+	return func(opts *scraperOptions) error {
+		if strategy != BFS && strategy != DFS && strategy != BestFirst {
+			return errors.New("unknown crawl strategy")
+		}
+		opts.strategy = strategy
+		return nil
+	}
+}
+
+// WithPriorityKeywords replaces the path keywords of the pages visited first
+// by the BestFirst strategy, which are news, article, column, blog and the
+// like by default.
+func WithPriorityKeywords(keywords ...string) Option {
+	return func(opts *scraperOptions) error {
+		opts.keywords = []string{}
+		for _, keyword := range keywords {
+			keyword = strings.ToLower(strings.TrimSpace(keyword))
+			if keyword == "" {
+				return errors.New("priority keyword should not be empty")
+			}
+			opts.keywords = append(opts.keywords, keyword)
+		}
+		return nil
+	}
+}
+
+// WithWARCOutput records the pages of the crawl in w as the records of a
+// WARC file, compressing every record with gzip when compress is set, so
+// that the exact corpus behind a result can be kept and counted again with
+// ScrapeWARC. Fetched pages are recorded with their response, and the pages
+// read from the page cache or rendered as resources. Files that are not
+// HTML are not recorded. Scrapers built with the same option share w.
+func WithWARCOutput(w io.Writer, compress bool) Option {
+	warc := &warcWriter{w: w, compress: compress}
+	return func(opts *scraperOptions) error {
+		if w == nil {
+			return errors.New("WARC writer should not be nil")
+		}
+		opts.warc = warc
+		return nil
+	}
+}
+
+// WithCacheDir stores fetched pages in dir and reuses them on later crawls
+// instead of downloading them again.
+func WithCacheDir(dir string) Option {
+	return func(opts *scraperOptions) error {
+		if dir == "" {
+			return errors.New("cache directory should not be empty")
+		}
+		opts.cacheDir = dir
+		return nil
+	}
+}
+
+// WithCountOptions sets how the text of the crawled pages is counted.
+func WithCountOptions(options ...CountOption) Option {
+	return func(opts *scraperOptions) error {
+		opts.countOptions = append(opts.countOptions, options...)
+		return nil
+	}
+}
+
+// WithPageHandler calls f with the URL and the visible text of every page
+// visited by the crawl. f may be called from several goroutines at once.
+func WithPageHandler(f func(pageURL, text string)) Option {
+	return func(opts *scraperOptions) error {
+		if f == nil {
+			return errors.New("page handler should not be nil")
+		}
+		opts.pageHandler = f
+		return nil
+	}
+}
+
+// WithProgress calls f every time a page starts or finishes being fetched.
+// f is called from the goroutine coordinating the crawl and should return
+// quickly.
+func WithProgress(f func(Progress)) Option {
+	return func(opts *scraperOptions) error {
+		if f == nil {
+			return errors.New("progress function should not be nil")
+		}
+		opts.progress = f
+		return nil
+	}
+}
+
+// WithFetchObserver calls f after every page fetch with the number of bytes
+// read from the page body, once its Content-Encoding is decoded, or with the
+// error that made the fetch or the reading of the page fail. Only the first
+// bytes of a page that is not HTML are read. f may be called from several
+// goroutines at once.
+func WithFetchObserver(f func(pageURL string, size int, err error)) Option {
+	return func(opts *scraperOptions) error {
+		if f == nil {
+			return errors.New("fetch observer should not be nil")
+		}
+		opts.fetchObserver = f
+		return nil
+	}
+}
+
+// WithRenderer loads the crawled pages with r rather than with a plain HTTP
+// request, so that the text that scripts add to the pages is counted. The
+// sitemaps and feeds are still fetched over HTTP.
+func WithRenderer(r Renderer) Option {
+	return func(opts *scraperOptions) error {
+		if r == nil {
+			return errors.New("renderer should not be nil")
+		}
+		opts.renderer = r
+		return nil
+	}
+}
+
+// WithTokenizer also counts the words found by t, reported in Result.Words.
+func WithTokenizer(t Tokenizer) CountOption {
+	return func(opts *countOptions) error {
+		if t == nil {
+			return errors.New("tokenizer should not be nil")
+		}
+		opts.tokenizer = t
+		return nil
+	}
+}
+
+// WithPunctuation also counts Japanese punctuation and symbols, such as 。、
+// 「」・〜 and full-width ！？, reported in Result.Punctuation. They are not
+// Japanese characters: they do not add to Result.AllCharactersCount, and the
+// middle dot ・ is no longer counted as a kana.
+func WithPunctuation() CountOption {
+	return func(opts *countOptions) error {
+		opts.punctuation = true
+		return nil
+	}
+}
+
+// WithHalfWidthKatakana counts half-width katakana (ｶﾀｶﾅ) as their own
+// characters. By default, they are normalized to full-width katakana (カタカナ)
+// before counting, so that older and Shift_JIS sites do not split the
+// katakana counts.
+func WithHalfWidthKatakana() CountOption {
+	return func(opts *countOptions) error {
+		opts.halfWidthKatakana = true
+		return nil
+	}
+}
+
+// WithMorae counts kana in phonological units: a kana followed by a small
+// ゃ, ゅ, ょ, ぁ, ぃ, ぅ, ぇ, ぉ or ゎ, or their katakana forms, is counted as a
+// single entry of Result.Hiraganas or Result.Katakanas, such as きょ or ファ,
+// rather than as two characters. Result.AllCharactersCount still counts
+// characters.
+func WithMorae() CountOption {
+	return func(opts *countOptions) error {
+		opts.morae = true
+		return nil
+	}
+}
+
+// WithVariants replaces the kanji variants listed in table by their
+// standard forms before counting, so that texts in old orthography do not
+// split the counts of a kanji: with BundledVariants, 國 and 學 are counted as
+// 国 and 学. Extend the bundled table to add variants.
+func WithVariants(table *VariantTable) CountOption {
+	return func(opts *countOptions) error {
+		if table == nil {
+			return errors.New("variant table should not be nil")
+		}
+		opts.variants = table
+		return nil
+	}
+}
+
+// WithChineseExclusion skips the text that looks Chinese rather than
+// Japanese, so that the pages of multilingual sites do not add hanzi to the
+// kanji counts. A line or sentence is taken for Chinese when it has no kana
+// and either ten kanji or more or the full-width comma or semicolon of
+// Chinese; HTML elements are also skipped when their lang attribute is
+// Chinese (zh, zh-TW). The skipped kanji are reported in
+// Result.SkippedChineseCount.
+func WithChineseExclusion() CountOption {
+	return func(opts *countOptions) error {
+		opts.excludeChinese = true
+		return nil
+	}
+}
+
+// IterationMark is how the iteration mark 々 is counted.
+type IterationMark int
+
+const (
+	// IterationMarkSymbol counts 々 as a kanji of its own. This is the
+	// default.
+	IterationMarkSymbol IterationMark = iota
+	// IterationMarkRepeat counts 々 as a repetition of the preceding kanji:
+	// 人々 counts 人 twice. A 々 that does not follow a kanji is counted as
+	// a symbol.
+	IterationMarkRepeat
+)
+
+// WithIterationMark sets how the iteration mark 々 is counted.
+func WithIterationMark(mode IterationMark) CountOption {
+	return func(opts *countOptions) error {
+		if mode != IterationMarkSymbol && mode != IterationMarkRepeat {
+			return fmt.Errorf("unknown iteration mark mode: %d", mode)
+		}
+		opts.iterationMark = mode
+		return nil
+	}
+}
+
+// LongVowelMark is how the long vowel mark ー is counted.
+type LongVowelMark int
+
+const (
+	// LongVowelMarkSeparate counts ー as a kana of its own, of the script
+	// of the preceding kana, or as a katakana. This is the default.
+	LongVowelMarkSeparate LongVowelMark = iota
+	// LongVowelMarkAttach counts ー as part of the preceding kana mora:
+	// コーヒー counts コー and ヒー. A ー that does not follow a kana is
+	// counted separately.
+	LongVowelMarkAttach
+)
+
+// WithLongVowelMark sets how the long vowel mark ー is counted.
+func WithLongVowelMark(mode LongVowelMark) CountOption {
+	return func(opts *countOptions) error {
+		if mode != LongVowelMarkSeparate && mode != LongVowelMarkAttach {
+			return fmt.Errorf("unknown long vowel mark mode: %d", mode)
+		}
+		opts.longVowelMark = mode
+		return nil
+	}
+}
+
+// WithCompounds also counts the maximal runs of two or more consecutive
+// kanji, such as 日本 or 経済産業省, reported in Result.Compounds. It is a
+// lightweight way to find common multi-kanji words without a tokenizer.
+func WithCompounds() CountOption {
+	return func(opts *countOptions) error {
+		opts.compounds = true
+		return nil
+	}
+}
+
+// WithReadingCounts also counts how every kanji is read, reported in
+// Result.Readings. The readings are taken from the tokens of the Tokenizer,
+// which should set Token.Reading: the reading of a token is aligned with its
+// surface, using the okurigana and the bundled reading table to split it
+// between kanji, and tokens that cannot be aligned are skipped.
+func WithReadingCounts() CountOption {
+	return func(opts *countOptions) error {
+		opts.readings = true
+		return nil
+	}
+}
+
+// WithPartsOfSpeech also counts the words of the Tokenizer by part of
+// speech, reported in Result.PartsOfSpeech. The Tokenizer should set
+// Token.PartOfSpeech; words without one are not counted.
+func WithPartsOfSpeech() CountOption {
+	return func(opts *countOptions) error {
+		opts.partsOfSpeech = true
+		return nil
+	}
+}
+
+// WithSentences also splits the text into sentences on 。, ！ and ？,
+// reported in Result.Sentences.
+func WithSentences() CountOption {
+	return func(opts *countOptions) error {
+		opts.sentences = true
+		return nil
+	}
+}
+
+// WithNGrams also counts the sequences of n consecutive Japanese characters,
+// reported in Result.NGrams.
+func WithNGrams(n int) CountOption {
+	return func(opts *countOptions) error {
+		if n < 2 || n > MaxNGramSize {
+			return fmt.Errorf("n-gram size should be between 2 and %d", MaxNGramSize)
+		}
+		opts.ngramSize = n
+		return nil
+	}
+}
@@ -0,0 +1,68 @@
+package kanjikana
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// IsPDFFile reports whether path names a PDF document.
+func IsPDFFile(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) == ".pdf"
+}
+
+// PDFText extracts the text of the PDF document r of size bytes. Text
+// drawn as images, as in scanned documents, cannot be extracted.
+func PDFText(r io.ReaderAt, size int64) (text string, err error) {
+	// The PDF reader panics on some malformed documents.
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("malformed PDF: %v", p)
+		}
+	}()
+
+	doc, err := pdf.NewReader(r, size)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	fonts := make(map[string]*pdf.Font)
+	for i := 1; i <= doc.NumPage(); i++ {
+		page := doc.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		// Fonts are shared by the pages, parse their character maps once.
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				font := page.Font(name)
+				fonts[name] = &font
+			}
+		}
+		pageText, err := page.GetPlainText(fonts)
+		if err != nil {
+			return "", fmt.Errorf("page %d: %w", i, err)
+		}
+		sb.WriteString(pageText)
+		sb.WriteByte('\n')
+	}
+	return sb.String(), nil
+}
+
+// CountPDF counts the Japanese characters of the text of the PDF document
+// r of size bytes.
+func CountPDF(r io.ReaderAt, size int64, options ...CountOption) (*Result, error) {
+	c, err := NewCounter(options...)
+	if err != nil {
+		return nil, err
+	}
+	text, err := PDFText(r, size)
+	if err != nil {
+		return nil, err
+	}
+	c.Count(text)
+	return c.Result(), nil
+}
@@ -0,0 +1,17 @@
+package kanjikana
+
+import "time"
+
+// Progress describes the state of a running crawl.
+type Progress struct {
+	// Fetched is the number of pages visited so far.
+	Fetched int
+	// Queued is the number of pages waiting to be fetched.
+	Queued int
+	// InFlight is the number of pages being fetched.
+	InFlight int
+	// Characters is the number of Japanese characters counted so far.
+	Characters int
+	// Elapsed is the time since the crawl started.
+	Elapsed time.Duration
+}
@@ -0,0 +1,188 @@
+package kanjikana
+
+import "github.com/gojp/kana"
+
+// Character categories.
+const (
+	CategoryKanji    = "kanji"
+	CategoryHiragana = "hiragana"
+	CategoryKatakana = "katakana"
+	CategoryWord     = "word"
+	CategoryNGram    = "ngram"
+	// CategoryCompound is the category of the runs of consecutive kanji
+	// counted with WithCompounds.
+	CategoryCompound = "compound"
+	// CategoryPunctuation is the category of the punctuation and symbols
+	// counted with WithPunctuation.
+	CategoryPunctuation = "punctuation"
+	// CategoryComponent is the category of the kanji components counted with
+	// a Kradfile.
+	CategoryComponent = "component"
+)
+
+// CharacterFrequency is a ranked character with its number of occurrences.
+// PerThousand, the occurrences per 1,000 Japanese characters of the result,
+// is set when a result is encoded to JSON.
+type CharacterFrequency struct {
+	Character   string  `json:"character"`
+	Count       int     `json:"count"`
+	PerThousand float64 `json:"per_thousand"`
+	Romaji      string  `json:"romaji,omitempty"`
+}
+
+// Ranking lists the characters of m from the most to the least frequent,
+// along with the romaji reading of kana characters.
+func Ranking(m map[string]int) []CharacterFrequency {
+	mostCommon := MostCommonCharacters(m)
+	ranking := make([]CharacterFrequency, len(mostCommon))
+	for i, c := range mostCommon {
+		ranking[i] = CharacterFrequency{Character: c, Count: m[c]}
+		if kana.IsKana(c) && !isPunctuation([]rune(c)[0]) {
+			ranking[i].Romaji = kana.KanaToRomaji(c)
+		}
+	}
+	return ranking
+}
+
+// WordFrequency is a ranked word with its number of occurrences.
+type WordFrequency struct {
+	Word        string  `json:"word"`
+	Count       int     `json:"count"`
+	PerThousand float64 `json:"per_thousand"`
+}
+
+// WordRanking lists the words of m from the most to the least frequent.
+func WordRanking(m map[string]int) []WordFrequency {
+	mostCommon := MostCommonCharacters(m)
+	ranking := make([]WordFrequency, len(mostCommon))
+	for i, w := range mostCommon {
+		ranking[i] = WordFrequency{Word: w, Count: m[w]}
+	}
+	return ranking
+}
+
+// NGramFrequency is a ranked n-gram with its number of occurrences.
+type NGramFrequency struct {
+	NGram       string  `json:"ngram"`
+	Count       int     `json:"count"`
+	PerThousand float64 `json:"per_thousand"`
+}
+
+// NGramRanking lists the n-grams of m from the most to the least frequent.
+func NGramRanking(m map[string]int) []NGramFrequency {
+	mostCommon := MostCommonCharacters(m)
+	ranking := make([]NGramFrequency, len(mostCommon))
+	for i, ngram := range mostCommon {
+		ranking[i] = NGramFrequency{NGram: ngram, Count: m[ngram]}
+	}
+	return ranking
+}
+
+// CompoundFrequency is a ranked kanji compound with its number of
+// occurrences.
+type CompoundFrequency struct {
+	Compound    string  `json:"compound"`
+	Count       int     `json:"count"`
+	PerThousand float64 `json:"per_thousand"`
+}
+
+// CompoundRanking lists the compounds of m from the most to the least
+// frequent.
+func CompoundRanking(m map[string]int) []CompoundFrequency {
+	mostCommon := MostCommonCharacters(m)
+	ranking := make([]CompoundFrequency, len(mostCommon))
+	for i, compound := range mostCommon {
+		ranking[i] = CompoundFrequency{Compound: compound, Count: m[compound]}
+	}
+	return ranking
+}
+
+// ReadingFrequency is a kanji with the counts of its readings, from the
+// most to the least common.
+type ReadingFrequency struct {
+	Kanji    string         `json:"kanji"`
+	Count    int            `json:"count"`
+	Readings []ReadingCount `json:"readings"`
+}
+
+// ReadingCount is a reading of a kanji with its number of occurrences.
+type ReadingCount struct {
+	Reading string `json:"reading"`
+	Count   int    `json:"count"`
+}
+
+// ReadingRanking lists the kanji of m from the one read the most often to
+// the least, each with its readings from the most to the least common.
+func ReadingRanking(m map[string]map[string]int) []ReadingFrequency {
+	counts := Totals(m)
+	mostCommon := MostCommonCharacters(counts)
+	ranking := make([]ReadingFrequency, len(mostCommon))
+	for i, kanji := range mostCommon {
+		ranking[i] = ReadingFrequency{Kanji: kanji, Count: counts[kanji]}
+		for _, reading := range MostCommonCharacters(m[kanji]) {
+			ranking[i].Readings = append(ranking[i].Readings, ReadingCount{Reading: reading, Count: m[kanji][reading]})
+		}
+	}
+	return ranking
+}
+
+// PartOfSpeechFrequency is a part of speech with its number of words and its
+// words from the most to the least frequent.
+type PartOfSpeechFrequency struct {
+	PartOfSpeech string          `json:"part_of_speech"`
+	Count        int             `json:"count"`
+	Words        []WordFrequency `json:"words"`
+}
+
+// PartOfSpeechRanking lists the parts of speech of m from the most to the
+// least frequent.
+func PartOfSpeechRanking(m map[string]map[string]int) []PartOfSpeechFrequency {
+	counts := Totals(m)
+	mostCommon := MostCommonCharacters(counts)
+	ranking := make([]PartOfSpeechFrequency, len(mostCommon))
+	for i, pos := range mostCommon {
+		ranking[i] = PartOfSpeechFrequency{PartOfSpeech: pos, Count: counts[pos], Words: WordRanking(m[pos])}
+	}
+	return ranking
+}
+
+// Totals returns the sum of the counts of every key of m, such as the number
+// of words of every part of speech of Result.PartsOfSpeech.
+func Totals(m map[string]map[string]int) map[string]int {
+	totals := make(map[string]int, len(m))
+	for k, counts := range m {
+		for _, count := range counts {
+			totals[k] += count
+		}
+	}
+	return totals
+}
+
+// ComponentFrequency is a ranked kanji component with its number of
+// occurrences.
+type ComponentFrequency struct {
+	Component   string  `json:"component"`
+	Count       int     `json:"count"`
+	PerThousand float64 `json:"per_thousand"`
+}
+
+// ComponentRanking lists the components of m from the most to the least
+// frequent.
+func ComponentRanking(m map[string]int) []ComponentFrequency {
+	mostCommon := MostCommonCharacters(m)
+	ranking := make([]ComponentFrequency, len(mostCommon))
+	for i, component := range mostCommon {
+		ranking[i] = ComponentFrequency{Component: component, Count: m[component]}
+	}
+	return ranking
+}
+
+// PerThousand returns count as a number of occurrences per 1,000 of total
+// characters, which makes the frequencies of corpora of different sizes
+// comparable. It returns 0 when total is 0.
+func PerThousand(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 1000 * float64(count) / float64(total)
+}
@@ -0,0 +1,114 @@
+package kanjikana
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// hostLimiter spaces out requests made to the same host.
+type hostLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     map[string]time.Time
+}
+
+func newHostLimiter(requestsPerSecond float64) *hostLimiter {
+	return &hostLimiter{
+		interval: time.Duration(float64(time.Second) / requestsPerSecond),
+		next:     make(map[string]time.Time),
+	}
+}
+
+// wait blocks until a request to the host of rawURL is allowed or ctx is done.
+func (l *hostLimiter) wait(ctx context.Context, rawURL string) error {
+	host := urlHost(rawURL)
+
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next[host] = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(slot)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// DefaultHostConcurrency is the default number of requests in flight to the
+// same host.
+const DefaultHostConcurrency = 2
+
+// hostSlots caps the number of requests in flight to the same host, whatever
+// the number of pages fetched in parallel.
+type hostSlots struct {
+	mu    sync.Mutex
+	size  int
+	slots map[string]chan struct{}
+}
+
+func newHostSlots(size int) *hostSlots {
+	return &hostSlots{
+		size:  size,
+		slots: make(map[string]chan struct{}),
+	}
+}
+
+// acquire blocks until a request to the host of rawURL may start or ctx is
+// done. The returned function ends the request, and may be called more than
+// once.
+func (h *hostSlots) acquire(ctx context.Context, rawURL string) (func(), error) {
+	host := urlHost(rawURL)
+
+	h.mu.Lock()
+	slots, ok := h.slots[host]
+	if !ok {
+		slots = make(chan struct{}, h.size)
+		h.slots[host] = slots
+	}
+	h.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return sync.OnceFunc(func() { <-slots }), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// releasingBody ends a request when its body is closed, as pages are counted
+// while they download.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// urlHost returns the host of rawURL, or rawURL when it cannot be parsed.
+func urlHost(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		return u.Host
+	}
+	return rawURL
+}
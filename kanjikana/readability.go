@@ -0,0 +1,180 @@
+package kanjikana
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// minParagraphLength is the number of characters below which a paragraph
+// is too short to tell where the article is, as menu labels.
+const minParagraphLength = 25
+
+var (
+	// positiveNames and negativeNames match the classes and ids of the
+	// elements that usually hold, or do not hold, the text of an article,
+	// including the romanized names of Japanese sites (honbun, kiji).
+	positiveNames = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|text|blog|story|honbun|kiji|news-?detail`)
+	negativeNames = regexp.MustCompile(`(?i)nav|menu|footer|header|sidebar|side|aside|comment|banner|breadcrumb|share|social|sns|ranking|related|recommend|widget|ad-|ads|promo|login|copyright|pagetop|pr-`)
+)
+
+// boilerplateElements are the elements whose paragraphs are never taken for
+// the article.
+var boilerplateElements = map[atom.Atom]struct{}{
+	atom.Nav:    {},
+	atom.Header: {},
+	atom.Footer: {},
+	atom.Aside:  {},
+	atom.Form:   {},
+}
+
+// mainContent returns the element of doc most likely to hold its article,
+// as the Readability algorithm finds it: every paragraph long enough scores
+// its parent and grandparent by its length and punctuation, and the
+// element of highest score, discounted by the share of its text in links,
+// is the article. It returns nil when no paragraph is long enough.
+func mainContent(doc *html.Node) *html.Node {
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if _, ok := invisibleElements[n.DataAtom]; ok {
+				return
+			}
+			if _, ok := boilerplateElements[n.DataAtom]; ok || unlikelyCandidate(n) {
+				return
+			}
+			if isParagraph(n) {
+				text := visibleText(n)
+				length := textLength(text)
+				if length >= minParagraphLength {
+					// A point per paragraph, per comma and per 100
+					// characters, up to 3.
+					score := 1 + float64(strings.Count(text, "、")+strings.Count(text, "，")+strings.Count(text, ",")) + min(float64(length)/100, 3)
+					addScore(n.Parent, score)
+					if n.Parent != nil {
+						addScore(n.Parent.Parent, score/2)
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	var best *html.Node
+	bestScore := 0.0
+	for _, n := range candidates {
+		score := scores[n] * (1 - linkDensity(n))
+		if best == nil || score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	return best
+}
+
+// isParagraph reports whether n holds a paragraph of text: a <p>, <pre> or
+// <td>, or a <div> with text of its own, as the many Japanese sites that
+// write their articles as lines of a <div> separated by <br>.
+func isParagraph(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.P, atom.Pre, atom.Td, atom.Blockquote:
+		return true
+	case atom.Div:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.TextNode && textLength(c.Data) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// initialScore scores n by its tag, class and id.
+func initialScore(n *html.Node) float64 {
+	var score float64
+	switch n.DataAtom {
+	case atom.Article, atom.Main:
+		score = 10
+	case atom.Div:
+		score = 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score = 3
+	case atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li:
+		score = -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score = -5
+	}
+	for _, name := range []string{attribute(n.Attr, "class"), attribute(n.Attr, "id")} {
+		if name == "" {
+			continue
+		}
+		if negativeNames.MatchString(name) {
+			score -= 25
+		}
+		if positiveNames.MatchString(name) {
+			score += 25
+		}
+	}
+	return score
+}
+
+// unlikelyCandidate reports whether the class or id of n names a part of
+// the page that is not the article, as a menu or a sidebar, unless it also
+// names the article.
+func unlikelyCandidate(n *html.Node) bool {
+	if n.DataAtom == atom.Body || n.DataAtom == atom.Article || n.DataAtom == atom.Main {
+		return false
+	}
+	names := attribute(n.Attr, "class") + " " + attribute(n.Attr, "id")
+	return negativeNames.MatchString(names) && !positiveNames.MatchString(names)
+}
+
+// linkDensity returns the share of the text of n in links.
+func linkDensity(n *html.Node) float64 {
+	length := textLength(visibleText(n))
+	if length == 0 {
+		return 0
+	}
+	links := 0
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			links += textLength(visibleText(n))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return float64(links) / float64(length)
+}
+
+// textLength returns the number of characters of text, but spaces.
+func textLength(text string) int {
+	n := 0
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			n++
+		}
+	}
+	return n
+}
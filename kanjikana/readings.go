@@ -0,0 +1,248 @@
+package kanjikana
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// ReadingTable lists the readings of kanji from the most to the least
+// common.
+type ReadingTable struct {
+	readings map[string][]string
+}
+
+// ParseReadingTable reads a reading table with one kanji per line followed by
+// its space separated readings, the most common first. Blank lines and lines
+// starting with # are ignored.
+func ParseReadingTable(r io.Reader) (*ReadingTable, error) {
+	rt := &ReadingTable{readings: make(map[string][]string)}
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: missing readings after kanji %q", lineNumber, fields[0])
+		}
+		rt.readings[fields[0]] = fields[1:]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rt, nil
+}
+
+// Readings returns the readings of kanji, the most common first.
+func (rt *ReadingTable) Readings(kanji string) []string {
+	return append([]string(nil), rt.readings[kanji]...)
+}
+
+// Reading returns the most common reading of kanji, or an empty string when
+// the table does not have it.
+func (rt *ReadingTable) Reading(kanji string) string {
+	if readings := rt.readings[kanji]; len(readings) > 0 {
+		return readings[0]
+	}
+	return ""
+}
+
+//go:embed data/readings.txt
+var readingsData string
+
+var bundledReadings = func() *ReadingTable {
+	rt, err := ParseReadingTable(strings.NewReader(readingsData))
+	if err != nil {
+		panic(err)
+	}
+	return rt
+}()
+
+// BundledReadings returns the bundled reading table, which covers the 1,026
+// kyōiku kanji.
+func BundledReadings() *ReadingTable {
+	return bundledReadings
+}
+
+// countReadings counts the reading of every kanji of token.
+func (c *Counter) countReadings(token Token) {
+	for _, kr := range alignReading(token.Surface, ToHiragana(token.Reading), BundledReadings()) {
+		if c.readings[kr.kanji] == nil {
+			c.readings[kr.kanji] = make(map[string]int)
+		}
+		c.readings[kr.kanji][kr.reading] += 1
+	}
+}
+
+type kanjiReading struct {
+	kanji   string
+	reading string
+}
+
+// readingSegment is a run of consecutive kanji, or of other characters, of
+// the surface of a token.
+type readingSegment struct {
+	kanji bool
+	runes []rune
+}
+
+// alignReading splits reading, in hiragana, between the kanji of surface.
+// The kana of surface must be found as is in reading and the runs of
+// consecutive kanji are split with the readings of rt, allowing for rendaku
+// (がわ in あまのがわ) and gemination (がっ in がっこう). A kanji is given the
+// reading of rt it matches, so that these variants are counted together. It
+// returns nil when the reading cannot be aligned. The iteration mark 々 is
+// not counted.
+func alignReading(surface, reading string, rt *ReadingTable) []kanjiReading {
+	var segments []readingSegment
+	for _, r := range surface {
+		kanji := runeCategory(r) == CategoryKanji || r == iterationMark
+		if n := len(segments); n > 0 && segments[n-1].kanji == kanji {
+			segments[n-1].runes = append(segments[n-1].runes, r)
+			continue
+		}
+		segments = append(segments, readingSegment{kanji: kanji, runes: []rune{r}})
+	}
+	return alignSegments(segments, reading, rt)
+}
+
+func alignSegments(segments []readingSegment, reading string, rt *ReadingTable) []kanjiReading {
+	if len(segments) == 0 {
+		if reading != "" {
+			return nil
+		}
+		return []kanjiReading{}
+	}
+	segment := segments[0]
+	if !segment.kanji {
+		rest, ok := strings.CutPrefix(reading, ToHiragana(string(segment.runes)))
+		if !ok {
+			return nil
+		}
+		return alignSegments(segments[1:], rest, rt)
+	}
+	if len(segments) == 1 {
+		return splitReading(segment.runes, reading, "", rt)
+	}
+	// The kanji take the shortest reading the rest of the surface can
+	// follow.
+	for i := range reading {
+		if i == 0 {
+			continue
+		}
+		split := splitReading(segment.runes, reading[:i], "", rt)
+		if split == nil {
+			continue
+		}
+		if rest := alignSegments(segments[1:], reading[i:], rt); rest != nil {
+			return append(split, rest...)
+		}
+	}
+	return nil
+}
+
+// splitReading splits reading between the kanji of run, where previous is
+// the reading of the kanji before run, repeated by an iteration mark. The
+// last kanji takes the rest of the reading, so it does not need to be in rt.
+func splitReading(run []rune, reading, previous string, rt *ReadingTable) []kanjiReading {
+	kanji := string(run[0])
+	if run[0] == iterationMark && len(run) == 1 {
+		if !matchesReading(reading, previous) {
+			return nil
+		}
+		return []kanjiReading{}
+	}
+
+	candidates := []string{previous}
+	if run[0] != iterationMark {
+		candidates = rt.Readings(kanji)
+	}
+	if len(run) == 1 {
+		for _, candidate := range candidates {
+			if matchesReading(reading, ToHiragana(candidate)) {
+				return []kanjiReading{{kanji, ToHiragana(candidate)}}
+			}
+		}
+		return []kanjiReading{{kanji, reading}}
+	}
+	for _, candidate := range candidates {
+		candidate = ToHiragana(candidate)
+		for _, variant := range readingVariants(candidate) {
+			rest, ok := strings.CutPrefix(reading, variant)
+			if !ok || rest == "" {
+				continue
+			}
+			split := splitReading(run[1:], rest, candidate, rt)
+			if split == nil {
+				continue
+			}
+			if run[0] == iterationMark {
+				return split
+			}
+			return append([]kanjiReading{{kanji, candidate}}, split...)
+		}
+	}
+	return nil
+}
+
+// matchesReading reports whether s is reading or one of its variants in a
+// compound.
+func matchesReading(s, reading string) bool {
+	for _, variant := range readingVariants(reading) {
+		if s == variant {
+			return true
+		}
+	}
+	return false
+}
+
+var rendaku = map[rune]rune{
+	'か': 'が', 'き': 'ぎ', 'く': 'ぐ', 'け': 'げ', 'こ': 'ご',
+	'さ': 'ざ', 'し': 'じ', 'す': 'ず', 'せ': 'ぜ', 'そ': 'ぞ',
+	'た': 'だ', 'ち': 'ぢ', 'つ': 'づ', 'て': 'で', 'と': 'ど',
+	'は': 'ば', 'ひ': 'び', 'ふ': 'ぶ', 'へ': 'べ', 'ほ': 'ぼ',
+}
+
+// readingVariants returns reading as it can be found in a compound: as is,
+// voiced by rendaku, with its h turned to p after ん or っ, and geminated.
+func readingVariants(reading string) []string {
+	if reading == "" {
+		return nil
+	}
+	first, size := utf8.DecodeRuneInString(reading)
+	variants := []string{reading}
+	if voiced, ok := rendaku[first]; ok {
+		variants = append(variants, string(voiced)+reading[size:])
+		if first >= 'は' && first <= 'ほ' {
+			variants = append(variants, string(voiced+1)+reading[size:])
+		}
+	}
+	for _, v := range variants {
+		last, size := utf8.DecodeLastRuneInString(v)
+		if utf8.RuneCountInString(v) > 1 && strings.ContainsRune("きくちつ", last) {
+			variants = append(variants, v[:len(v)-size]+"っ")
+		}
+	}
+	return variants
+}
+
+// ToHiragana converts the katakana of s to hiragana, leaving the other
+// characters as they are.
+func ToHiragana(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'ァ' && r <= 'ヶ' {
+			return r - ('ァ' - 'ぁ')
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,43 @@
+package kanjikana
+
+import (
+	"context"
+	"io"
+	"strings"
+)
+
+// Renderer loads a page in a browser and returns its HTML once its scripts
+// ran. A headless browser, such as Chrome driven by chromedp, can be plugged
+// in with WithRenderer to crawl the sites that load their text with
+// JavaScript.
+type Renderer interface {
+	Render(ctx context.Context, pageURL string) (string, error)
+}
+
+// renderPage returns the HTML of pageURL rendered by the renderer. The rate
+// limits and the request timeout apply, but rendered pages are not cached.
+// They are recorded with WithWARCOutput as resources, without response.
+func (s *Scraper) renderPage(ctx context.Context, pageURL string) (*fetchedPage, error) {
+	release, err := s.slots.acquire(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if s.limiter != nil {
+		if err := s.limiter.wait(ctx, pageURL); err != nil {
+			return nil, err
+		}
+	}
+	if s.opts.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.opts.requestTimeout)
+		defer cancel()
+	}
+
+	page, err := s.opts.renderer.Render(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	s.archive(pageURL, "text/html; charset=utf-8", []byte(page))
+	return &fetchedPage{body: io.NopCloser(strings.NewReader(page)), contentType: "text/html; charset=utf-8", url: pageURL}, nil
+}
@@ -0,0 +1,288 @@
+package kanjikana
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Result describes the counting of Kanji, Hiragana and Katakana characters.
+type Result struct {
+	AllCharactersCount  int
+	UniqueCount         int
+	KanjiUniqueCount    int
+	KanaUniqueCount     int
+	HiraganaUniqueCount int
+	KatakanaUniqueCount int
+	Kanjis              map[string]int
+	Hiraganas           map[string]int
+	Katakanas           map[string]int
+	// SkippedChineseCount is the number of kanji of the text skipped as
+	// Chinese when WithChineseExclusion is set.
+	SkippedChineseCount int
+	// Words holds the word counts when a Tokenizer is set.
+	Words map[string]int
+	// NGrams holds the counts of sequences of NGramSize consecutive
+	// characters when n-gram counting is enabled.
+	NGrams    map[string]int
+	NGramSize int
+	// Compounds holds the counts of the runs of consecutive kanji when
+	// WithCompounds is set.
+	Compounds map[string]int
+	// Punctuation holds the counts of Japanese punctuation and symbols
+	// when WithPunctuation is set.
+	Punctuation map[string]int
+	// Readings maps every kanji to the counts of its readings, in hiragana,
+	// when WithReadingCounts is set.
+	Readings map[string]map[string]int
+	// PartsOfSpeech maps every part of speech to the counts of its words
+	// when WithPartsOfSpeech is set.
+	PartsOfSpeech map[string]map[string]int
+	// Sentences summarizes the sentences of the text when WithSentences
+	// is set.
+	Sentences *SentenceStats
+	// Files holds the per-file results of a directory corpus.
+	Files map[string]*Result
+	// Domains holds the per-host results of a crawl when WithPerDomain is
+	// set.
+	Domains map[string]*Result
+	// Roots holds the result of every root URL of ScrapeRoots.
+	Roots map[string]*Result
+	// Pages holds the statistics of every page visited by a crawl.
+	Pages []PageStats
+	// NonJapanesePages is the number of pages of a crawl skipped by
+	// WithMinJapaneseRatio.
+	NonJapanesePages int
+	// DuplicatePages is the number of pages of a crawl skipped as
+	// duplicates of a page already counted.
+	DuplicatePages int
+	// RepeatedBlocks is the number of text blocks of a crawl skipped by
+	// WithBoilerplateDedup as counted on another page of the same host.
+	RepeatedBlocks int
+	// Chapters holds the statistics of every chapter of a book, in
+	// reading order.
+	Chapters []ChapterStats
+	// Examples maps every character and word counted by a crawl to the URL
+	// of a page where it appears.
+	Examples map[string]string
+}
+
+// PageStats summarizes the characters counted on a single page.
+type PageStats struct {
+	URL                string `json:"url"`
+	AllCharactersCount int    `json:"all_characters_count"`
+	KanjiCount         int    `json:"kanji_count"`
+	HiraganaCount      int    `json:"hiragana_count"`
+	KatakanaCount      int    `json:"katakana_count"`
+	// Kanjis holds the kanji counts of the page.
+	Kanjis map[string]int `json:"kanjis,omitempty"`
+	// UniqueKanjis lists the kanji found on no other page of the result,
+	// from the most to the least frequent on the page.
+	UniqueKanjis []string `json:"unique_kanjis,omitempty"`
+	// FinalURL is the URL the page was redirected to, if any.
+	FinalURL string `json:"final_url,omitempty"`
+	// Title is the <title> of the page, Published its publication date as
+	// given by its article:published_time <meta> tag, and Lang the lang
+	// attribute of its <html> element.
+	Title     string `json:"title,omitempty"`
+	Published string `json:"published,omitempty"`
+	Lang      string `json:"lang,omitempty"`
+}
+
+// UniquePages returns pages without the ones of a URL listed before, as
+// merged results may hold. pages is left unchanged.
+func UniquePages(pages []PageStats) []PageStats {
+	seen := make(map[string]struct{}, len(pages))
+	var unique []PageStats
+	for _, page := range pages {
+		if _, ok := seen[page.URL]; ok {
+			continue
+		}
+		seen[page.URL] = struct{}{}
+		unique = append(unique, page)
+	}
+	return unique
+}
+
+// Merge adds the counts of other to r, as if the texts of both results had
+// been counted together, and recomputes the unique counts of r. It fails
+// when both results hold n-grams of different sizes.
+func (r *Result) Merge(other *Result) error {
+	if r.NGrams != nil && other.NGrams != nil && r.NGramSize != other.NGramSize {
+		return fmt.Errorf("cannot merge %d-grams with %d-grams", r.NGramSize, other.NGramSize)
+	}
+
+	r.AllCharactersCount += other.AllCharactersCount
+	r.SkippedChineseCount += other.SkippedChineseCount
+	r.Kanjis = addCounts(r.Kanjis, other.Kanjis)
+	r.Hiraganas = addCounts(r.Hiraganas, other.Hiraganas)
+	r.Katakanas = addCounts(r.Katakanas, other.Katakanas)
+	if other.Words != nil {
+		r.Words = addCounts(r.Words, other.Words)
+	}
+	if other.NGrams != nil {
+		r.NGrams = addCounts(r.NGrams, other.NGrams)
+		r.NGramSize = other.NGramSize
+	}
+	if other.Compounds != nil {
+		r.Compounds = addCounts(r.Compounds, other.Compounds)
+	}
+	if other.Punctuation != nil {
+		r.Punctuation = addCounts(r.Punctuation, other.Punctuation)
+	}
+	if other.Readings != nil {
+		r.Readings = addNestedCounts(r.Readings, other.Readings)
+	}
+	if other.Sentences != nil {
+		if r.Sentences == nil {
+			r.Sentences = &SentenceStats{}
+		}
+		r.Sentences.add(*other.Sentences)
+	}
+	if other.PartsOfSpeech != nil {
+		r.PartsOfSpeech = addNestedCounts(r.PartsOfSpeech, other.PartsOfSpeech)
+	}
+	var err error
+	if r.Files, err = mergeResults(r.Files, other.Files); err != nil {
+		return err
+	}
+	if r.Domains, err = mergeResults(r.Domains, other.Domains); err != nil {
+		return err
+	}
+	if r.Roots, err = mergeResults(r.Roots, other.Roots); err != nil {
+		return err
+	}
+	r.Pages = append(r.Pages, other.Pages...)
+	r.NonJapanesePages += other.NonJapanesePages
+	r.DuplicatePages += other.DuplicatePages
+	r.RepeatedBlocks += other.RepeatedBlocks
+	r.Chapters = append(r.Chapters, other.Chapters...)
+	for k, url := range other.Examples {
+		if r.Examples == nil {
+			r.Examples = make(map[string]string)
+		}
+		if _, ok := r.Examples[k]; !ok {
+			r.Examples[k] = url
+		}
+	}
+
+	r.CountUnique()
+	return nil
+}
+
+// CountUnique sets the unique counts of r from its character counts, and
+// the kanji unique to every page from the kanji counts of its pages.
+func (r *Result) CountUnique() {
+	r.KanjiUniqueCount = len(r.Kanjis)
+	r.HiraganaUniqueCount = len(r.Hiraganas)
+	r.KatakanaUniqueCount = len(r.Katakanas)
+	r.KanaUniqueCount = r.HiraganaUniqueCount + r.KatakanaUniqueCount
+	r.UniqueCount = r.KanjiUniqueCount + r.KanaUniqueCount
+
+	pageCounts := make(map[string]int)
+	for _, page := range r.Pages {
+		for kanji := range page.Kanjis {
+			pageCounts[kanji]++
+		}
+	}
+	for i, page := range r.Pages {
+		unique := make(map[string]int)
+		for kanji, count := range page.Kanjis {
+			if pageCounts[kanji] == 1 {
+				unique[kanji] = count
+			}
+		}
+		r.Pages[i].UniqueKanjis = nil
+		if len(unique) > 0 {
+			r.Pages[i].UniqueKanjis = MostCommonCharacters(unique)
+		}
+	}
+}
+
+// mergeResults merges the results of other into the ones of m under the same
+// key, allocating m when nil and other is not empty.
+func mergeResults(m, other map[string]*Result) (map[string]*Result, error) {
+	for key, res := range other {
+		if m == nil {
+			m = make(map[string]*Result)
+		}
+		if existing, ok := m[key]; ok {
+			if err := existing.Merge(res); err != nil {
+				return m, err
+			}
+			continue
+		}
+		// A copy is stored, so that merging more results does not change
+		// the ones of other.
+		merged := &Result{}
+		if err := merged.Merge(res); err != nil {
+			return m, err
+		}
+		m[key] = merged
+	}
+	return m, nil
+}
+
+// addCounts adds the counts of other to m, allocating m when nil.
+func addCounts(m, other map[string]int) map[string]int {
+	if m == nil {
+		m = make(map[string]int, len(other))
+	}
+	for k, v := range other {
+		m[k] += v
+	}
+	return m
+}
+
+// addNestedCounts adds the nested counts of other to m, which it
+// allocates when nil, and returns m.
+func addNestedCounts(m, other map[string]map[string]int) map[string]map[string]int {
+	if m == nil {
+		m = make(map[string]map[string]int, len(other))
+	}
+	for kanji, readings := range other {
+		m[kanji] = addCounts(m[kanji], readings)
+	}
+	return m
+}
+
+// MostCommonCharacters returns the keys of m ordered from the most to the
+// least frequent, and in lexical order for the same frequency.
+func MostCommonCharacters(m map[string]int) []string {
+	var i int
+	charactersList := make([]string, len(m))
+	for k := range m {
+		charactersList[i] = k
+		i += 1
+	}
+
+	sort.Slice(charactersList, func(i, j int) bool {
+		a, b := charactersList[i], charactersList[j]
+		return m[a] > m[b] || m[a] == m[b] && a < b
+	})
+
+	return charactersList
+}
+
+// total sums the occurrences of every character of m.
+func total(m map[string]int) int {
+	var n int
+	for _, count := range m {
+		n += count
+	}
+	return n
+}
+
+// Coverage returns the cumulative coverage curve of the counts m: its i-th
+// value is the percentage of all occurrences accounted for by the i+1 most
+// frequent keys of m.
+func Coverage(m map[string]int) []float64 {
+	mostCommon := MostCommonCharacters(m)
+	n := total(m)
+	coverage := make([]float64, len(mostCommon))
+	cumulative := 0
+	for i, k := range mostCommon {
+		cumulative += m[k]
+		coverage[i] = 100 * float64(cumulative) / float64(n)
+	}
+	return coverage
+}
@@ -0,0 +1,116 @@
+package kanjikana
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestUniquePages(t *testing.T) {
+	pages := []PageStats{
+		{URL: "http://example.com/a", AllCharactersCount: 1},
+		{URL: "http://example.com/b", AllCharactersCount: 2},
+		{URL: "http://example.com/a", AllCharactersCount: 3},
+	}
+	got := UniquePages(pages)
+	if want := "[{a 1} {b 2}]"; fmt.Sprint(pageSummaries(got)) != want {
+		t.Errorf("UniquePages = %v, want %s", pageSummaries(got), want)
+	}
+	if want := "[{a 1} {b 2} {a 3}]"; fmt.Sprint(pageSummaries(pages)) != want {
+		t.Errorf("UniquePages changed its argument to %v", pageSummaries(pages))
+	}
+}
+
+type pageSummary struct {
+	path  string
+	count int
+}
+
+// pageSummaries returns the path and character count of every page.
+func pageSummaries(pages []PageStats) []pageSummary {
+	var summaries []pageSummary
+	for _, page := range pages {
+		summaries = append(summaries, pageSummary{page.URL[len("http://example.com/"):], page.AllCharactersCount})
+	}
+	return summaries
+}
+
+func TestMostCommonCharacters(t *testing.T) {
+	m := map[string]int{"い": 2, "あ": 2, "う": 5, "え": 1, "お": 2}
+	for i := 0; i < 10; i++ {
+		if got, want := fmt.Sprint(MostCommonCharacters(m)), "[う あ い お え]"; got != want {
+			t.Fatalf("MostCommonCharacters = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestCounterResult(t *testing.T) {
+	c, err := NewCounter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Count("日本の日本カナ")
+	c.Count("abc 123、")
+	res := c.Result()
+	if res.AllCharactersCount != 7 {
+		t.Errorf("AllCharactersCount = %d, want 7", res.AllCharactersCount)
+	}
+	if got, want := fmt.Sprint(res.Kanjis, res.Hiraganas, res.Katakanas), "map[日:2 本:2] map[の:1] map[カ:1 ナ:1]"; got != want {
+		t.Errorf("counts = %s, want %s", got, want)
+	}
+	if res.UniqueCount != 5 || res.KanjiUniqueCount != 2 || res.KanaUniqueCount != 3 {
+		t.Errorf("unique counts = %d, %d kanji, %d kana, want 5, 2 and 3", res.UniqueCount, res.KanjiUniqueCount, res.KanaUniqueCount)
+	}
+}
+
+func TestResultMerge(t *testing.T) {
+	texts := []string{"日本の政府は", "日本語のカタカナ", "経済"}
+	results := make([]*Result, len(texts))
+	for i, text := range texts {
+		c, err := NewCounter(WithTokenizer(ScriptTokenizer{}), WithNGrams(2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Count(text)
+		results[i] = c.Result()
+	}
+	merged := &Result{}
+	for _, res := range results {
+		if err := merged.Merge(res); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, err := NewCounter(WithTokenizer(ScriptTokenizer{}), WithNGrams(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range texts {
+		c.Count(text)
+	}
+	want := c.Result()
+	if merged.AllCharactersCount != want.AllCharactersCount || merged.UniqueCount != want.UniqueCount {
+		t.Errorf("merged counts = %d, %d unique, want %d, %d unique", merged.AllCharactersCount, merged.UniqueCount, want.AllCharactersCount, want.UniqueCount)
+	}
+	for _, m := range []struct {
+		name      string
+		got, want map[string]int
+	}{
+		{"Kanjis", merged.Kanjis, want.Kanjis},
+		{"Hiraganas", merged.Hiraganas, want.Hiraganas},
+		{"Katakanas", merged.Katakanas, want.Katakanas},
+		{"Words", merged.Words, want.Words},
+	} {
+		if fmt.Sprint(m.got) != fmt.Sprint(m.want) {
+			t.Errorf("merged %s = %v, want %v", m.name, m.got, m.want)
+		}
+	}
+
+	trigrams, err := NewCounter(WithNGrams(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	trigrams.Count("日本語")
+	if err := merged.Merge(trigrams.Result()); err == nil {
+		t.Error("merging bigrams with trigrams succeeded, want an error")
+	}
+}
@@ -0,0 +1,67 @@
+package kanjikana
+
+import (
+	"context"
+	"sync"
+)
+
+// ScrapeRoots crawls every URL of rootURLs in parallel, each with its own
+// scraper built from options, and returns their combined counts, with the
+// result of every root in Result.Roots. The page limits and stop conditions
+// apply to every root. The progress function, if any, is given the sum of
+// the progress of the crawls. A page reached from several roots is counted
+// once, in the result of the first root whose crawl reaches it.
+func ScrapeRoots(ctx context.Context, rootURLs []string, options ...Option) (*Result, error) {
+	keys := newKeySet()
+	scrapers := make([]*Scraper, len(rootURLs))
+	for i := range rootURLs {
+		s, err := NewScraper(options...)
+		if err != nil {
+			return nil, err
+		}
+		s.sharedKeys = keys
+		scrapers[i] = s
+	}
+	if progress := scrapers[0].opts.progress; progress != nil {
+		var mu sync.Mutex
+		states := make([]Progress, len(scrapers))
+		for i, s := range scrapers {
+			i := i
+			s.opts.progress = func(p Progress) {
+				mu.Lock()
+				defer mu.Unlock()
+				states[i] = p
+				var sum Progress
+				for _, state := range states {
+					sum.Fetched += state.Fetched
+					sum.Queued += state.Queued
+					sum.InFlight += state.InFlight
+					sum.Characters += state.Characters
+					sum.Elapsed = max(sum.Elapsed, state.Elapsed)
+				}
+				progress(sum)
+			}
+		}
+	}
+
+	results := make([]*Result, len(rootURLs))
+	var wg sync.WaitGroup
+	for i, s := range scrapers {
+		wg.Add(1)
+		go func(i int, s *Scraper) {
+			defer wg.Done()
+			// Only the context error is returned, which ctx.Err() reports.
+			results[i], _ = s.ScrapeContext(ctx, rootURLs[i])
+		}(i, s)
+	}
+	wg.Wait()
+
+	combined := &Result{Roots: make(map[string]*Result, len(rootURLs))}
+	for i, res := range results {
+		if err := combined.Merge(res); err != nil {
+			return nil, err
+		}
+		combined.Roots[rootURLs[i]] = res
+	}
+	return combined, ctx.Err()
+}
@@ -0,0 +1,87 @@
+package kanjikana
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+)
+
+// newTestSite serves pages, an HTML body by path, redirects the paths of
+// redirects to their target path and answers 404 for the other paths.
+func newTestSite(t *testing.T, pages, redirects map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if target, ok := redirects[r.URL.Path]; ok {
+			http.Redirect(w, r, target, http.StatusFound)
+			return
+		}
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, "<html><body>%s</body></html>", body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// pageURLs returns the sorted URLs of pages.
+func pageURLs(pages []PageStats) []string {
+	var urls []string
+	for _, page := range pages {
+		urls = append(urls, page.URL)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+func TestScrapeRootsCountsSharedPagesOnce(t *testing.T) {
+	srv := newTestSite(t, map[string]string{
+		"/a":      `日本<a href="/shared">リンク</a>`,
+		"/b":      `東京<a href="/shared">リンク</a>`,
+		"/shared": "漢字かな",
+	}, nil)
+
+	for _, keepDuplicates := range []bool{false, true} {
+		options := []Option{WithSearchDepth(1), WithConcurrency(2)}
+		if keepDuplicates {
+			options = append(options, WithDuplicatePages())
+		}
+		res, err := ScrapeRoots(context.Background(), []string{srv.URL + "/a", srv.URL + "/b"}, options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// リンク is counted on both roots, 漢字かな once.
+		if want := 2 + 3 + 2 + 3 + 4; res.AllCharactersCount != want {
+			t.Errorf("keep duplicates %t: AllCharactersCount = %d, want %d", keepDuplicates, res.AllCharactersCount, want)
+		}
+		for _, kanji := range []string{"漢", "字", "日", "東"} {
+			if res.Kanjis[kanji] != 1 {
+				t.Errorf("keep duplicates %t: Kanjis[%s] = %d, want 1", keepDuplicates, kanji, res.Kanjis[kanji])
+			}
+		}
+		if got, want := fmt.Sprint(pageURLs(res.Pages)), fmt.Sprint([]string{srv.URL + "/a", srv.URL + "/b", srv.URL + "/shared"}); got != want {
+			t.Errorf("keep duplicates %t: pages = %s, want %s", keepDuplicates, got, want)
+		}
+
+		// The shared page is in the result of one root only.
+		roots := 0
+		for _, root := range res.Roots {
+			if root.Kanjis["漢"] > 0 {
+				roots++
+			}
+		}
+		if roots != 1 {
+			t.Errorf("keep duplicates %t: shared page counted in %d roots, want 1", keepDuplicates, roots)
+		}
+		if res.DuplicatePages != 0 {
+			t.Errorf("keep duplicates %t: DuplicatePages = %d, want 0", keepDuplicates, res.DuplicatePages)
+		}
+	}
+}
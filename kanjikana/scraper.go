@@ -0,0 +1,1115 @@
+package kanjikana
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"mime"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Scraper crawls a website and counts the Japanese characters of every
+// visited page.
+type Scraper struct {
+	opts      scraperOptions
+	counter   *Counter
+	limiter   *hostLimiter
+	slots     *hostSlots
+	client    *http.Client
+	cache     *pageCache
+	rootURL   string
+	rootHost  string
+	countOpts countOptions
+
+	mu       sync.Mutex
+	pages    []PageStats
+	examples map[string]string
+	domains  map[string]*Counter
+	// nonJapanesePages counts the pages skipped by WithMinJapaneseRatio.
+	nonJapanesePages int
+	// keys holds the visit keys of the pages counted and of the URLs
+	// redirected to them, so that a page is counted once whatever the URL
+	// it was reached from, and the canonical URLs and the text digests of
+	// the pages visited, to skip their duplicates. The scrapers of
+	// ScrapeRoots share sharedKeys.
+	keys           *keySet
+	sharedKeys     *keySet
+	duplicatePages int
+	// blocks holds the keys of the text blocks counted by WithBoilerplateDedup.
+	blocks         map[uint64]struct{}
+	repeatedBlocks int
+}
+
+// crawlTask is a page waiting to be fetched. layer is the remaining search
+// depth below the page.
+type crawlTask struct {
+	url   string
+	layer int
+	// score orders the tasks of the BestFirst strategy.
+	score float64
+}
+
+// crawlResult holds the links discovered while visiting a crawlTask, the
+// URL the page was redirected to, if any, and the Japanese density of the
+// page.
+type crawlResult struct {
+	task     crawlTask
+	links    []string
+	redirect string
+	density  float64
+}
+
+func NewScraper(options ...Option) (*Scraper, error) {
+	var opts scraperOptions
+	for _, opt := range options {
+		err := opt(&opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The client given to WithHTTPClient is copied so that setting its
+	// cookie jar does not change it for its other users.
+	var client http.Client
+	if opts.client != nil {
+		if opts.proxyURL != nil {
+			return nil, errors.New("proxy cannot be set with a custom HTTP client")
+		}
+		client = *opts.client
+	} else {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if opts.proxyURL != nil {
+			transport.Proxy = http.ProxyURL(opts.proxyURL)
+		}
+		client.Transport = transport
+	}
+
+	if opts.requestTimeout > 0 {
+		client.Timeout = opts.requestTimeout
+	}
+
+	s := &Scraper{}
+	if opts.maxRedirects != nil || opts.sameHostRedir || opts.sameDomainOnly ||
+		len(opts.allowedHosts) > 0 || len(opts.deniedHosts) > 0 {
+		client.CheckRedirect = s.checkRedirect
+	}
+
+	countOpts, err := newCountOptions(opts.countOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.jar != nil {
+		client.Jar = opts.jar
+	}
+	if client.Jar == nil {
+		// Keep the cookies set by the crawled sites, as some only serve
+		// their pages once a session cookie is set.
+		client.Jar, err = cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.opts = opts
+	s.client = &client
+	s.countOpts = countOpts
+	if opts.cacheDir != "" {
+		cache, err := newPageCache(opts.cacheDir)
+		if err != nil {
+			return nil, err
+		}
+		s.cache = cache
+	}
+	if opts.rateLimit > 0 {
+		s.limiter = newHostLimiter(opts.rateLimit)
+	}
+	hostRequests := opts.hostRequests
+	if hostRequests == 0 {
+		hostRequests = DefaultHostConcurrency
+	}
+	s.slots = newHostSlots(hostRequests)
+
+	return s, nil
+}
+
+// Scrape crawls rootURL up to the configured search depth.
+func (s *Scraper) Scrape(rootURL string) (*Result, error) {
+	return s.ScrapeContext(context.Background(), rootURL)
+}
+
+// ScrapeContext is like Scrape but stops fetching new pages once ctx is
+// done. In that case it returns the counts gathered so far together with
+// the context error.
+func (s *Scraper) ScrapeContext(ctx context.Context, rootURL string) (*Result, error) {
+	if !ValidateURL(rootURL) {
+		rootURL = DefaultURL
+		if s.opts.logger != nil {
+			s.opts.logger.Info("invalid URL: setting to default URL", "url", rootURL)
+		}
+	}
+	rootURL = normalizeRawURL(rootURL)
+
+	var searchDepth int
+	if s.opts.searchDepth == nil {
+		searchDepth = DefaultSearchDepth
+	} else {
+		searchDepth = *s.opts.searchDepth
+	}
+
+	concurrency := s.opts.concurrency
+	if concurrency == 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	if l := s.opts.logger; l != nil {
+		l.Info("crawl settings",
+			"url", rootURL,
+			"depth", searchDepth,
+			"concurrency", concurrency,
+			"strategy", s.opts.strategy.String())
+		if s.opts.maxPages > 0 {
+			l.Info("page limit set", "pages", s.opts.maxPages)
+		}
+		if s.opts.stopKanjis > 0 {
+			l.Info("unique kanji limit set", "kanjis", s.opts.stopKanjis)
+		}
+		if s.opts.stopCharacters > 0 {
+			l.Info("character limit set", "characters", s.opts.stopCharacters)
+		}
+		if s.opts.timeout > 0 {
+			l.Info("crawl timeout set", "timeout", s.opts.timeout)
+		}
+		if !s.opts.deadline.IsZero() {
+			l.Info("crawl deadline set", "deadline", s.opts.deadline)
+		}
+		if s.opts.requestTimeout > 0 {
+			l.Info("request timeout set", "timeout", s.opts.requestTimeout)
+		}
+		if s.opts.proxyURL != nil {
+			l.Info("proxy set", "proxy", s.opts.proxyURL.Redacted())
+		}
+		if s.opts.rateLimit > 0 {
+			l.Info("rate limit set", "requests_per_second", s.opts.rateLimit)
+		}
+		if s.opts.minJapanese > 0 {
+			l.Info("minimum Japanese ratio set", "ratio", s.opts.minJapanese)
+		}
+		if s.opts.wayback != "" {
+			l.Info("crawling Wayback Machine snapshots", "timestamp", s.opts.wayback)
+		}
+	}
+
+	s.rootURL = rootURL
+	if u, err := url.Parse(rootURL); err == nil {
+		s.rootHost = u.Hostname()
+		if len(s.opts.cookies) > 0 {
+			s.client.Jar.SetCookies(&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}, s.opts.cookies)
+		}
+	}
+	s.reset()
+
+	crawlCtx := ctx
+	if s.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		crawlCtx, cancel = context.WithTimeout(ctx, s.opts.timeout)
+		defer cancel()
+	}
+	if !s.opts.deadline.IsZero() {
+		var cancel context.CancelFunc
+		crawlCtx, cancel = context.WithDeadline(crawlCtx, s.opts.deadline)
+		defer cancel()
+	}
+
+	roots := []crawlTask{{url: rootURL, layer: searchDepth}}
+	switch {
+	case s.opts.sitemap:
+		pages, err := s.sitemapURLs(crawlCtx, rootURL)
+		if err != nil && ctx.Err() != nil {
+			return s.result(), ctx.Err()
+		}
+		if s.opts.logger != nil {
+			s.opts.logger.Info("sitemap read", "pages", len(pages))
+		}
+		roots = s.pageTasks(pages)
+	case s.opts.feed:
+		pages, err := s.feedURLs(crawlCtx, rootURL)
+		if err != nil {
+			return s.result(), err
+		}
+		if s.opts.logger != nil {
+			s.opts.logger.Info("feed read", "articles", len(pages))
+		}
+		roots = s.pageTasks(pages)
+	}
+
+	s.crawl(crawlCtx, roots, concurrency)
+
+	return s.result(), ctx.Err()
+}
+
+// reset clears the counts of the previous crawl.
+func (s *Scraper) reset() {
+	s.counter = newCounter(s.countOpts)
+	s.pages = nil
+	s.examples = make(map[string]string)
+	s.domains = make(map[string]*Counter)
+	s.nonJapanesePages = 0
+	s.keys = s.sharedKeys
+	if s.keys == nil {
+		s.keys = newKeySet()
+	}
+	s.duplicatePages = 0
+	s.blocks = make(map[uint64]struct{})
+	s.repeatedBlocks = 0
+}
+
+// errRedirectRefused is the error of the redirects that the redirect policy
+// does not follow.
+var errRedirectRefused = errors.New("redirect refused")
+
+// DefaultMaxRedirects is the number of redirects followed per page by
+// default, as by the default HTTP client.
+const DefaultMaxRedirects = 10
+
+// checkRedirect applies the redirect policy to the redirect to req.
+func (s *Scraper) checkRedirect(req *http.Request, via []*http.Request) error {
+	maxRedirects := DefaultMaxRedirects
+	if s.opts.maxRedirects != nil {
+		maxRedirects = *s.opts.maxRedirects
+	}
+	if len(via) > maxRedirects {
+		return fmt.Errorf("%w: more than %d redirects", errRedirectRefused, maxRedirects)
+	}
+	// The redirects between snapshots are checked as the ones of the pages
+	// they archive.
+	target, previous, first := s.originalURL(req.URL), s.originalURL(via[len(via)-1].URL), s.originalURL(via[0].URL)
+	host := target.Hostname()
+	if s.opts.sameHostRedir && !strings.EqualFold(host, previous.Hostname()) {
+		return fmt.Errorf("%w: %s is on another host", errRedirectRefused, req.URL)
+	}
+	// The root URL may redirect elsewhere, as from example.com to
+	// www.example.com.
+	if !s.hostFollowable(host) && normalizeURL(first) != s.rootURL {
+		return fmt.Errorf("%w: %s is out of the crawled hosts", errRedirectRefused, req.URL)
+	}
+	return nil
+}
+
+// pageTasks returns the tasks visiting the followable pages, without
+// following their links.
+func (s *Scraper) pageTasks(pages []string) []crawlTask {
+	var tasks []crawlTask
+	for _, page := range pages {
+		if s.followable(page) {
+			tasks = append(tasks, crawlTask{url: normalizeRawURL(page)})
+		}
+	}
+	return tasks
+}
+
+func (s *Scraper) result() *Result {
+	res := s.counter.Result()
+
+	s.mu.Lock()
+	res.Pages = append([]PageStats(nil), s.pages...)
+	res.Examples = make(map[string]string, len(s.examples))
+	for k, v := range s.examples {
+		res.Examples[k] = v
+	}
+	domains := make(map[string]*Counter, len(s.domains))
+	for host, counter := range s.domains {
+		domains[host] = counter
+	}
+	res.NonJapanesePages = s.nonJapanesePages
+	res.DuplicatePages = s.duplicatePages
+	res.RepeatedBlocks = s.repeatedBlocks
+	s.mu.Unlock()
+
+	if s.opts.perDomain {
+		res.Domains = make(map[string]*Result, len(domains))
+		for host, counter := range domains {
+			res.Domains[host] = counter.Result()
+		}
+	}
+
+	res.CountUnique()
+	return res
+}
+
+// record adds the characters counted on a visited page to the crawl totals.
+// finalURL is the URL the page was redirected to, if any.
+func (s *Scraper) record(pageURL, finalURL string, page *scannedPage) {
+	pageCounter := page.counter
+	s.counter.merge(pageCounter)
+
+	kanjis := pageCounter.characterCounts(CategoryKanji)
+	hiraganas := pageCounter.characterCounts(CategoryHiragana)
+	katakanas := pageCounter.characterCounts(CategoryKatakana)
+	stats := PageStats{
+		URL:                pageURL,
+		FinalURL:           finalURL,
+		AllCharactersCount: pageCounter.allCharactersCount,
+		KanjiCount:         total(kanjis),
+		HiraganaCount:      total(hiraganas),
+		KatakanaCount:      total(katakanas),
+		Kanjis:             kanjis,
+		Title:              strings.Join(strings.Fields(page.title), " "),
+		Published:          page.published,
+		Lang:               page.lang,
+	}
+
+	if s.opts.perDomain {
+		s.domainCounter(pageURL).merge(pageCounter)
+	}
+
+	s.mu.Lock()
+	s.pages = append(s.pages, stats)
+	for _, m := range []map[string]int{kanjis, hiraganas, katakanas, pageCounter.words} {
+		for k := range m {
+			if _, ok := s.examples[k]; !ok {
+				s.examples[k] = pageURL
+			}
+		}
+	}
+	s.mu.Unlock()
+}
+
+// domainCounter returns the counter of the host of pageURL.
+func (s *Scraper) domainCounter(pageURL string) *Counter {
+	host := pageURL
+	if u, err := url.Parse(pageURL); err == nil {
+		host = strings.ToLower(u.Hostname())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counter, ok := s.domains[host]
+	if !ok {
+		counter = newCounter(s.countOpts)
+		s.domains[host] = counter
+	}
+	return counter
+}
+
+// crawl visits roots and the pages they link to with a pool of workers.
+// The frontier and the visited set are owned by the calling goroutine,
+// workers only fetch pages and report the links they found.
+func (s *Scraper) crawl(ctx context.Context, roots []crawlTask, concurrency int) {
+	tasks := make(chan crawlTask)
+	results := make(chan crawlResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range tasks {
+				results <- s.visit(ctx, task)
+			}
+		}()
+	}
+
+	keywords := s.opts.keywords
+	if keywords == nil {
+		keywords = defaultPriorityKeywords
+	}
+
+	visited := make(map[string]struct{})
+	// reached holds the visit keys of the pages a redirect led to, whose
+	// tasks queued before the redirect are dropped.
+	reached := make(map[string]struct{})
+	queue := newFrontier(s.opts.strategy)
+	for _, root := range roots {
+		if _, ok := visited[visitKey(root.url)]; ok {
+			continue
+		}
+		visited[visitKey(root.url)] = struct{}{}
+		queue.push(root)
+	}
+	inFlight := 0
+	dispatched := 0
+	fetched := 0
+	stopped := false
+	done := ctx.Done()
+	start := time.Now()
+
+	reportProgress := func() {
+		if s.opts.progress == nil {
+			return
+		}
+		s.opts.progress(Progress{
+			Fetched:    fetched,
+			Queued:     queue.len(),
+			InFlight:   inFlight,
+			Characters: s.counter.characters(),
+			Elapsed:    time.Since(start),
+		})
+	}
+
+	for queue.len() > 0 || inFlight > 0 {
+		for queue.len() > 0 {
+			if _, ok := reached[visitKey(queue.peek().url)]; !ok {
+				break
+			}
+			queue.pop()
+		}
+		if queue.len() == 0 && inFlight == 0 {
+			break
+		}
+
+		var next crawlTask
+		var out chan crawlTask
+		if queue.len() > 0 {
+			next = queue.peek()
+			out = tasks
+		}
+
+		select {
+		case out <- next:
+			queue.pop()
+			inFlight++
+			dispatched++
+			if s.opts.maxPages > 0 && dispatched >= s.opts.maxPages {
+				if s.opts.logger != nil {
+					s.opts.logger.Info("page limit reached", "pages", s.opts.maxPages)
+				}
+				queue = newFrontier(s.opts.strategy)
+				stopped = true
+			}
+		case <-done:
+			queue = newFrontier(s.opts.strategy)
+			stopped = true
+			done = nil
+		case res := <-results:
+			inFlight--
+			fetched++
+			// The page a redirect led to is not visited again.
+			if res.redirect != "" {
+				visited[visitKey(res.redirect)] = struct{}{}
+				reached[visitKey(res.redirect)] = struct{}{}
+			}
+			if !stopped && s.enough() {
+				queue = newFrontier(s.opts.strategy)
+				stopped = true
+			}
+			if !stopped {
+				for _, link := range res.links {
+					if _, ok := visited[visitKey(link)]; ok {
+						continue
+					}
+					visited[visitKey(link)] = struct{}{}
+					task := crawlTask{url: link, layer: res.task.layer - 1}
+					if s.opts.strategy == BestFirst {
+						task.score = linkScore(link, res.density, keywords)
+					}
+					queue.push(task)
+				}
+			}
+		}
+		reportProgress()
+	}
+
+	close(tasks)
+	wg.Wait()
+}
+
+// enough reports whether the counts reached the unique kanji or character
+// limit of the crawl.
+func (s *Scraper) enough() bool {
+	if n := s.opts.stopKanjis; n > 0 && s.counter.uniqueKanjis() >= n {
+		if s.opts.logger != nil {
+			s.opts.logger.Info("unique kanji limit reached", "kanjis", n)
+		}
+		return true
+	}
+	if n := s.opts.stopCharacters; n > 0 && s.counter.characters() >= n {
+		if s.opts.logger != nil {
+			s.opts.logger.Info("character limit reached", "characters", n)
+		}
+		return true
+	}
+	return false
+}
+
+// logger returns the logger of the scraper, falling back to the default
+// logger for fetch errors.
+func (s *Scraper) logger() *slog.Logger {
+	if s.opts.logger != nil {
+		return s.opts.logger
+	}
+	return slog.Default()
+}
+
+// visit fetches the page of task, counts its characters and returns the
+// links to follow from it.
+func (s *Scraper) visit(ctx context.Context, task crawlTask) crawlResult {
+	fetched, err := s.loadPage(ctx, task.url)
+	if err != nil {
+		if s.opts.fetchObserver != nil {
+			s.opts.fetchObserver(task.url, 0, err)
+		}
+		s.logger().Warn("unable to fetch page", "url", task.url, "error", err)
+		return crawlResult{task: task}
+	}
+	defer fetched.body.Close()
+	return s.countPage(task, fetched)
+}
+
+// countPage counts the characters of the page of task and returns the links
+// to follow from it.
+func (s *Scraper) countPage(task crawlTask, fetched *fetchedPage) crawlResult {
+	result := crawlResult{task: task}
+
+	// Links are resolved against the URL the page was redirected to.
+	pageURL := normalizeRawURL(fetched.url)
+	if pageURL != task.url {
+		result.redirect = pageURL
+		if s.opts.logger != nil {
+			s.opts.logger.Debug("page redirected", "url", task.url, "final_url", pageURL)
+		}
+	}
+
+	body := &countingReader{r: fetched.body}
+	page, err := s.readPage(body, pageURL, fetched.contentType)
+	if s.opts.fetchObserver != nil {
+		s.opts.fetchObserver(task.url, body.n, err)
+	}
+	if err != nil {
+		s.logger().Warn("unable to read page", "url", task.url, "error", err)
+		return result
+	}
+	if page == nil {
+		if s.opts.logger != nil {
+			s.opts.logger.Debug("skipping non-HTML page", "url", task.url, "content_type", fetched.contentType)
+		}
+		return result
+	}
+
+	noindex, nofollow := page.noindex, page.nofollow
+	if s.opts.ignoreRobots {
+		noindex, nofollow = false, false
+	}
+	result.density = page.japaneseRatio()
+	switch ratio := result.density; {
+	case noindex:
+		if s.opts.logger != nil {
+			s.opts.logger.Debug("skipping noindex page", "url", task.url)
+		}
+	case ratio < s.opts.minJapanese:
+		s.mu.Lock()
+		s.nonJapanesePages++
+		s.mu.Unlock()
+		if s.opts.logger != nil {
+			s.opts.logger.Debug("skipping page below the Japanese ratio", "url", task.url, "ratio", ratio)
+		}
+	case !s.keys.add("page:"+visitKey(task.url), "page:"+visitKey(pageURL)):
+		if s.opts.logger != nil {
+			s.opts.logger.Debug("skipping page already counted", "url", task.url)
+		}
+	case s.duplicate(pageURL, page):
+		if s.opts.logger != nil {
+			s.opts.logger.Debug("skipping duplicate page", "url", task.url, "canonical", page.canonical)
+		}
+	default:
+		s.record(task.url, result.redirect, page)
+		s.addBlocks(page)
+		if s.opts.logger != nil {
+			s.opts.logger.Debug("page visited", "url", task.url, "depth", task.layer)
+		}
+		if s.opts.pageHandler != nil {
+			s.opts.pageHandler(task.url, page.text.String())
+		}
+	}
+
+	if task.layer <= 0 || nofollow {
+		return result
+	}
+
+	for _, link := range page.linkList() {
+		if s.followable(link) {
+			result.links = append(result.links, link)
+		}
+	}
+	if s.opts.linkSample > 0 {
+		result.links = sampleLinks(result.links, s.opts.linkSample)
+	}
+	return result
+}
+
+// sampleLinks returns a random fraction of links, at least one, in their
+// order.
+func sampleLinks(links []string, fraction float64) []string {
+	n := int(math.Ceil(fraction * float64(len(links))))
+	if n >= len(links) {
+		return links
+	}
+	picked := rand.Perm(len(links))[:n]
+	sort.Ints(picked)
+	sample := make([]string, n)
+	for i, j := range picked {
+		sample[i] = links[j]
+	}
+	return sample
+}
+
+// scannedPage holds the counts, links and robots directives read from a
+// page.
+type scannedPage struct {
+	counter *Counter
+	// text holds the visible text of the page when a page handler is set.
+	text         strings.Builder
+	base         *url.URL
+	skipNofollow bool
+	links        map[string]struct{}
+	noindex      bool
+	nofollow     bool
+	// letters counts the letters and digits of the visible text.
+	letters int
+	// canonical is the URL of the <link rel="canonical"> of the page.
+	canonical string
+	// digest hashes the visible text.
+	digest hash.Hash
+	// blocks holds the keys of the text blocks counted on the page, and
+	// seenBlock reports the ones counted on the pages already visited, when
+	// WithBoilerplateDedup is set. repeated counts the blocks skipped.
+	blocks    map[uint64]struct{}
+	seenBlock func(key uint64) bool
+	repeated  int
+	// title, published and lang are the metadata of the page. inTitle is
+	// set while the tokenizer is in its <title>.
+	title     string
+	published string
+	lang      string
+	inTitle   bool
+}
+
+// minBlockLength is the number of characters from which a text block
+// repeated on several pages is boilerplate.
+const minBlockLength = 10
+
+// blockKey returns the key of a text block of a page of host.
+func blockKey(host, block string) uint64 {
+	h := fnv.New64a()
+	io.WriteString(h, host)
+	h.Write([]byte{0})
+	io.WriteString(h, block)
+	return h.Sum64()
+}
+
+// seenBlock reports whether a text block of key was counted on a page
+// already visited.
+func (s *Scraper) seenBlock(key uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.blocks[key]
+	return ok
+}
+
+// addBlocks marks the text blocks of a counted page as seen. Pages read at
+// the same time may both count a block.
+func (s *Scraper) addBlocks(page *scannedPage) {
+	if page.blocks == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range page.blocks {
+		s.blocks[key] = struct{}{}
+	}
+	s.repeatedBlocks += page.repeated
+}
+
+// duplicate reports whether page, visited at pageURL, duplicates a page
+// already visited: a page of the same canonical URL, or of the same text.
+// Pages without Japanese text are never duplicates.
+func (s *Scraper) duplicate(pageURL string, page *scannedPage) bool {
+	if s.opts.keepDuplicates || page.counter.characters() == 0 {
+		return false
+	}
+	canonical := visitKey(pageURL)
+	if page.canonical != "" {
+		canonical = visitKey(page.canonical)
+	}
+	text := "text:" + hex.EncodeToString(page.digest.Sum(nil))
+
+	// The keys of duplicates are kept too, so that a page duplicating the
+	// text of a skipped page is skipped as well.
+	if s.keys.add(canonical, text) {
+		return false
+	}
+	s.mu.Lock()
+	s.duplicatePages++
+	s.mu.Unlock()
+	return true
+}
+
+// keySet is a set of strings safe for concurrent use.
+type keySet struct {
+	mu   sync.Mutex
+	keys map[string]struct{}
+}
+
+func newKeySet() *keySet {
+	return &keySet{keys: make(map[string]struct{})}
+}
+
+// add adds keys to the set and reports whether none of them was in it.
+func (ks *keySet) add(keys ...string) bool {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	added := true
+	for _, key := range keys {
+		if _, ok := ks.keys[key]; ok {
+			added = false
+		}
+	}
+	for _, key := range keys {
+		ks.keys[key] = struct{}{}
+	}
+	return added
+}
+
+// readPage counts the characters of the page read from body and collects
+// its links. Unless a content selector or the main content extraction needs
+// the document tree, the page is streamed through the HTML tokenizer rather
+// than read into memory. It returns a nil page when the page is not HTML.
+func (s *Scraper) readPage(body io.Reader, pageURL, contentType string) (*scannedPage, error) {
+	br := bufio.NewReader(body)
+	head, err := br.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	if !isHTMLContent(contentType, head) {
+		return nil, nil
+	}
+
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	page := &scannedPage{
+		counter:      newCounter(s.countOpts),
+		base:         base,
+		skipNofollow: !s.opts.ignoreRobots,
+		links:        make(map[string]struct{}),
+		digest:       sha256.New(),
+	}
+	if s.opts.dedupBlocks {
+		page.blocks = make(map[uint64]struct{})
+		page.seenBlock = s.seenBlock
+	}
+
+	// Pages served in Shift_JIS, EUC-JP or ISO-2022-JP are transcoded to
+	// UTF-8 based on the Content-Type header and the <meta> charset.
+	reader, err := charset.NewReader(br, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("unable to detect page charset: %w", err)
+	}
+
+	if s.opts.selectors != nil || s.opts.mainContent {
+		doc, err := html.Parse(reader)
+		if err != nil {
+			return nil, err
+		}
+		if chinese := page.counter.chineseHandler(); chinese != nil {
+			removeElements(doc, func(n *html.Node) bool {
+				if !isChineseLang(attribute(n.Attr, "lang")) {
+					return false
+				}
+				chinese(visibleText(n))
+				return true
+			})
+		}
+		if s.opts.selectors != nil {
+			page.addText(selectedText(doc, s.opts.selectors), s.opts.pageHandler != nil)
+		} else {
+			// Pages without paragraphs long enough to find their
+			// article are counted in full.
+			article := mainContent(doc)
+			if article == nil {
+				article = doc
+			}
+			page.addText(visibleText(article), s.opts.pageHandler != nil)
+		}
+		walkTags(doc, page.tag)
+		if title := FindElement(doc, func(n *html.Node) bool { return n.DataAtom == atom.Title }); title != nil {
+			page.title = visibleText(title)
+		}
+		return page, nil
+	}
+
+	err = scanHTML(reader, func(text string) {
+		page.addText(text, s.opts.pageHandler != nil)
+	}, page.counter.chineseHandler(), page.tag)
+	return page, err
+}
+
+// addText counts text, and keeps it when keep is set. With
+// WithBoilerplateDedup, the lines of text counted on the pages already
+// visited are skipped.
+func (p *scannedPage) addText(text string, keep bool) {
+	if p.inTitle {
+		p.title += text
+	}
+	io.WriteString(p.digest, text)
+	if p.blocks == nil {
+		p.countText(text)
+	} else {
+		for _, line := range strings.SplitAfter(text, "\n") {
+			block := strings.TrimSpace(line)
+			if utf8.RuneCountInString(block) < minBlockLength {
+				p.countText(line)
+				continue
+			}
+			key := blockKey(p.base.Host, block)
+			if _, ok := p.blocks[key]; !ok && p.seenBlock(key) {
+				p.repeated++
+				continue
+			}
+			p.blocks[key] = struct{}{}
+			p.countText(line)
+		}
+	}
+	if keep {
+		p.text.WriteString(text)
+		p.text.WriteByte('\n')
+	}
+}
+
+// countText counts the characters of text and its letters and digits.
+func (p *scannedPage) countText(text string) {
+	p.counter.Count(text)
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			p.letters++
+		}
+	}
+}
+
+// japaneseRatio returns the share of Japanese characters among the letters
+// and digits of the page, or 1 for a page without text.
+func (p *scannedPage) japaneseRatio() float64 {
+	if p.letters == 0 {
+		return 1
+	}
+	return float64(p.counter.characters()) / float64(p.letters)
+}
+
+// tag reads the links of the <a> tags of the page, but the ones marked
+// rel="nofollow" when skipNofollow is set, its canonical URL, the
+// directives of its robots <meta> tags, and its metadata.
+func (p *scannedPage) tag(tok html.Token) {
+	if tok.Type == html.EndTagToken {
+		if tok.DataAtom == atom.Title {
+			p.inTitle = false
+		}
+		return
+	}
+	switch tok.DataAtom {
+	case atom.Html:
+		p.lang = strings.TrimSpace(attribute(tok.Attr, "lang"))
+	case atom.Title:
+		// Only the first title is read, as the ones of <svg> images may
+		// follow.
+		p.inTitle = p.title == "" && tok.Type == html.StartTagToken
+	case atom.A:
+		if p.skipNofollow && hasRel(tok.Attr, "nofollow") {
+			return
+		}
+		if link, ok := resolveLink(p.base, attribute(tok.Attr, "href")); ok {
+			p.links[link] = struct{}{}
+		}
+	case atom.Link:
+		if !hasRel(tok.Attr, "canonical") {
+			return
+		}
+		if ref, err := url.Parse(strings.TrimSpace(attribute(tok.Attr, "href"))); err == nil {
+			p.canonical = normalizeURL(p.base.ResolveReference(ref))
+		}
+	case atom.Meta:
+		key := attribute(tok.Attr, "property")
+		if key == "" {
+			key = attribute(tok.Attr, "name")
+		}
+		switch strings.ToLower(key) {
+		case "article:published_time", "og:article:published_time":
+			if p.published == "" {
+				p.published = strings.TrimSpace(attribute(tok.Attr, "content"))
+			}
+			return
+		case "robots":
+		default:
+			return
+		}
+		for _, directive := range strings.Split(attribute(tok.Attr, "content"), ",") {
+			switch strings.ToLower(strings.TrimSpace(directive)) {
+			case "noindex":
+				p.noindex = true
+			case "nofollow":
+				p.nofollow = true
+			case "none":
+				p.noindex, p.nofollow = true, true
+			}
+		}
+	}
+}
+
+// linkList returns the links of the page.
+func (p *scannedPage) linkList() []string {
+	links := make([]string, 0, len(p.links))
+	for link := range p.links {
+		links = append(links, link)
+	}
+	return links
+}
+
+// walkTags calls tag with the start tags of the reported elements of the
+// document rooted at n, as scanHTML does for a stream.
+func walkTags(n *html.Node, tag func(html.Token)) {
+	if _, ok := reportedTags[n.DataAtom]; ok && n.Type == html.ElementNode {
+		tag(html.Token{Type: html.StartTagToken, DataAtom: n.DataAtom, Data: n.Data, Attr: n.Attr})
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walkTags(c, tag)
+	}
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+// assetExtensions are the file extensions of the links that are not worth
+// fetching, as they never lead to an HTML page.
+var assetExtensions = map[string]struct{}{
+	".jpg": {}, ".jpeg": {}, ".png": {}, ".gif": {}, ".webp": {}, ".svg": {}, ".ico": {},
+	".css": {}, ".js": {}, ".json": {}, ".xml": {}, ".rss": {},
+	".pdf": {}, ".zip": {}, ".gz": {}, ".epub": {},
+	".mp3": {}, ".mp4": {}, ".m4a": {}, ".webm": {}, ".mov": {},
+}
+
+// isHTMLContent reports whether a page of the given Content-Type header is
+// an HTML page, sniffing the body when the header is missing.
+func isHTMLContent(contentType string, body []byte) bool {
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml")
+}
+
+// attribute returns the value of the attribute key among attrs.
+func attribute(attrs []html.Attribute, key string) string {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// hasRel reports whether the rel attribute among attrs lists value.
+func hasRel(attrs []html.Attribute, value string) bool {
+	for _, rel := range strings.Fields(attribute(attrs, "rel")) {
+		if strings.EqualFold(rel, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveLink returns the normalized absolute URL of href, resolved against
+// base, and whether it is worth following: links to images, scripts and
+// other assets are not. Links to pages that are not HTML are only skipped
+// once fetched.
+func resolveLink(base *url.URL, href string) (string, bool) {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") {
+		return "", false
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+	link := base.ResolveReference(ref)
+	if link.Scheme != "http" && link.Scheme != "https" {
+		return "", false
+	}
+	if _, ok := assetExtensions[strings.ToLower(path.Ext(link.Path))]; ok {
+		return "", false
+	}
+	return normalizeURL(link), true
+}
+
+// matchesAny reports whether s matches one of patterns.
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// followable reports whether the crawler is allowed to visit link.
+func (s *Scraper) followable(link string) bool {
+	if len(s.opts.linkPatterns) > 0 && !matchesAny(s.opts.linkPatterns, link) {
+		return false
+	}
+	if matchesAny(s.opts.excludes, link) {
+		return false
+	}
+	if !s.opts.sameDomainOnly && len(s.opts.allowedHosts) == 0 && len(s.opts.deniedHosts) == 0 {
+		return true
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return s.hostFollowable(u.Hostname())
+}
+
+// hostFollowable reports whether the pages of host may be visited, given the
+// same domain option and the allowed and denied hosts.
+func (s *Scraper) hostFollowable(host string) bool {
+	if matchesHost(s.opts.deniedHosts, host) {
+		return false
+	}
+	sameDomain := s.opts.sameDomainOnly && s.rootHost != ""
+	if !sameDomain && len(s.opts.allowedHosts) == 0 {
+		return true
+	}
+	return strings.EqualFold(host, s.rootHost) || matchesHost(s.opts.allowedHosts, host)
+}
@@ -0,0 +1,78 @@
+package kanjikana
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestScrapeCountsRedirectedPageOnce(t *testing.T) {
+	srv := newTestSite(t, map[string]string{
+		"/":     `<a href="/old">旧</a><a href="/page">新</a>`,
+		"/page": "漢字かな",
+	}, map[string]string{
+		"/old": "/page",
+	})
+
+	for _, keepDuplicates := range []bool{false, true} {
+		for _, concurrency := range []int{1, 4} {
+			options := []Option{WithSearchDepth(1), WithConcurrency(concurrency)}
+			if keepDuplicates {
+				options = append(options, WithDuplicatePages())
+			}
+			s, err := NewScraper(options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := s.Scrape(srv.URL + "/")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			name := fmt.Sprintf("keep duplicates %t, concurrency %d", keepDuplicates, concurrency)
+			if want := 2 + 4; res.AllCharactersCount != want {
+				t.Errorf("%s: AllCharactersCount = %d, want %d", name, res.AllCharactersCount, want)
+			}
+			if res.Kanjis["漢"] != 1 {
+				t.Errorf("%s: Kanjis[漢] = %d, want 1", name, res.Kanjis["漢"])
+			}
+			if len(res.Pages) != 2 {
+				t.Errorf("%s: pages = %s, want 2 pages", name, pageURLs(res.Pages))
+			}
+			if res.DuplicatePages != 0 {
+				t.Errorf("%s: DuplicatePages = %d, want 0", name, res.DuplicatePages)
+			}
+		}
+	}
+}
+
+func TestScrapeFollowsLinksToDepth(t *testing.T) {
+	srv := newTestSite(t, map[string]string{
+		"/":  `一<a href="/a">あ</a>`,
+		"/a": `二<a href="/b">い</a><a href="/">う</a>`,
+		"/b": `三<a href="/c">え</a>`,
+		"/c": "四",
+	}, nil)
+
+	tests := []struct {
+		depth int
+		want  string
+	}{
+		{0, "map[一:1]"},
+		{1, "map[一:1 二:1]"},
+		{2, "map[一:1 三:1 二:1]"},
+		{3, "map[一:1 三:1 二:1 四:1]"},
+	}
+	for _, tt := range tests {
+		s, err := NewScraper(WithSearchDepth(tt.depth), WithConcurrency(2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := s.Scrape(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(res.Kanjis); got != tt.want {
+			t.Errorf("depth %d: kanji = %s, want %s", tt.depth, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package kanjikana
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// simpleSelector matches elements by tag name, id and class, as in the CSS
+// selectors "div", "#main", ".article" or "div.article".
+type simpleSelector struct {
+	tag, id string
+	classes []string
+}
+
+// parseSelector parses a comma-separated list of simple selectors.
+func parseSelector(s string) ([]simpleSelector, error) {
+	var selectors []simpleSelector
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" || strings.ContainsAny(part, " >+~[]:*") {
+			return nil, fmt.Errorf("unsupported selector %q: want a tag name, #id and .class", part)
+		}
+
+		var sel simpleSelector
+		// Split before every # and . to get the tag name then the id and
+		// class parts.
+		rest := part
+		if i := strings.IndexAny(rest, "#."); i != 0 {
+			if i < 0 {
+				i = len(rest)
+			}
+			sel.tag = strings.ToLower(rest[:i])
+			rest = rest[i:]
+		}
+		for rest != "" {
+			kind := rest[0]
+			rest = rest[1:]
+			end := strings.IndexAny(rest, "#.")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			rest = rest[end:]
+			if name == "" {
+				return nil, fmt.Errorf("unsupported selector %q: empty id or class", part)
+			}
+			if kind == '#' {
+				sel.id = name
+			} else {
+				sel.classes = append(sel.classes, name)
+			}
+		}
+		selectors = append(selectors, sel)
+	}
+	return selectors, nil
+}
+
+func (sel simpleSelector) matches(n *html.Node) bool {
+	if n.Type != html.ElementNode || (sel.tag != "" && n.Data != sel.tag) {
+		return false
+	}
+	if sel.id != "" {
+		var id string
+		for _, attr := range n.Attr {
+			if attr.Key == "id" {
+				id = attr.Val
+			}
+		}
+		if id != sel.id {
+			return false
+		}
+	}
+	for _, class := range sel.classes {
+		if !hasClass(n, class) {
+			return false
+		}
+	}
+	return true
+}
+
+// selectedText concatenates the visible text of the outermost elements of
+// the document rooted at n matched by any of selectors.
+func selectedText(n *html.Node, selectors []simpleSelector) string {
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for _, sel := range selectors {
+			if sel.matches(n) {
+				writeVisibleText(&sb, n)
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
@@ -0,0 +1,80 @@
+package kanjikana
+
+import "encoding/json"
+
+// SentenceStats summarizes the sentences of a text, split on 。, ！ and ？.
+// The lengths are counted in Japanese characters, like
+// Result.AllCharactersCount, and sentences without any are ignored.
+type SentenceStats struct {
+	Count      int `json:"count"`
+	Characters int `json:"characters"`
+	Kanjis     int `json:"kanjis"`
+	// KanjiDensitySum is the sum of the kanji shares of every sentence,
+	// from which AverageKanjiDensity is computed.
+	KanjiDensitySum float64 `json:"kanji_density_sum"`
+}
+
+// AverageLength returns the average number of characters of a sentence.
+func (s SentenceStats) AverageLength() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return float64(s.Characters) / float64(s.Count)
+}
+
+// AverageKanjiDensity returns the average share of kanji in a sentence,
+// between 0 and 1. Unlike Kanjis divided by Characters, every sentence
+// weighs the same whatever its length.
+func (s SentenceStats) AverageKanjiDensity() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.KanjiDensitySum / float64(s.Count)
+}
+
+// MarshalJSON encodes the statistics along with their averages.
+func (s SentenceStats) MarshalJSON() ([]byte, error) {
+	type stats SentenceStats
+	return json.Marshal(struct {
+		stats
+		AverageLength       float64 `json:"average_length"`
+		AverageKanjiDensity float64 `json:"average_kanji_density"`
+	}{stats(s), s.AverageLength(), s.AverageKanjiDensity()})
+}
+
+// addSentence adds a sentence of the given numbers of characters and kanji.
+func (s *SentenceStats) addSentence(characters, kanjis int) {
+	if characters == 0 {
+		return
+	}
+	s.Count++
+	s.Characters += characters
+	s.Kanjis += kanjis
+	s.KanjiDensitySum += float64(kanjis) / float64(characters)
+}
+
+func (s *SentenceStats) add(other SentenceStats) {
+	s.Count += other.Count
+	s.Characters += other.Characters
+	s.Kanjis += other.Kanjis
+	s.KanjiDensitySum += other.KanjiDensitySum
+}
+
+// isSentenceEnd reports whether r ends a sentence.
+func isSentenceEnd(r rune) bool {
+	return r == '。' || r == '！' || r == '？'
+}
+
+// countSentence adds the characters counted for r, of the entry last, to the
+// current sentence, or ends it when r ends a sentence.
+func (c *Counter) countSentence(r rune, characters int, last entry) {
+	if isSentenceEnd(r) {
+		c.sentences.addSentence(c.sentenceCharacters, c.sentenceKanjis)
+		c.sentenceCharacters, c.sentenceKanjis = 0, 0
+		return
+	}
+	c.sentenceCharacters += characters
+	if last.category == CategoryKanji {
+		c.sentenceKanjis += characters
+	}
+}
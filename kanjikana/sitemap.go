@@ -0,0 +1,126 @@
+package kanjikana
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxSitemapIndexDepth bounds how deep nested sitemap index files are followed.
+const maxSitemapIndexDepth = 3
+
+// sitemapDocument matches both <urlset> sitemaps and <sitemapindex> files.
+type sitemapDocument struct {
+	URLs []struct {
+		Loc string `xml:"loc"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// sitemapURLs returns the page URLs listed in the sitemaps of the site of
+// rootURL. If rootURL points to an XML file it is used as the sitemap,
+// otherwise the sitemaps declared in robots.txt or /sitemap.xml are read.
+func (s *Scraper) sitemapURLs(ctx context.Context, rootURL string) ([]string, error) {
+	root, err := url.Parse(rootURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var sitemaps []string
+	if path := strings.ToLower(root.Path); strings.HasSuffix(path, ".xml") || strings.HasSuffix(path, ".xml.gz") {
+		sitemaps = []string{rootURL}
+	} else {
+		sitemaps = s.robotsSitemaps(ctx, root)
+		if len(sitemaps) == 0 {
+			sitemaps = []string{root.ResolveReference(&url.URL{Path: "/sitemap.xml"}).String()}
+		}
+	}
+
+	var pages []string
+	seen := make(map[string]struct{})
+	for _, sitemap := range sitemaps {
+		pages = s.readSitemap(ctx, sitemap, 0, seen, pages)
+	}
+	return pages, ctx.Err()
+}
+
+// robotsSitemaps lists the Sitemap entries of the robots.txt of root.
+func (s *Scraper) robotsSitemaps(ctx context.Context, root *url.URL) []string {
+	robotsURL := root.ResolveReference(&url.URL{Path: "/robots.txt"}).String()
+	resp, err := s.fetch(ctx, robotsURL, nil)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+
+	var sitemaps []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "sitemap") {
+			sitemaps = append(sitemaps, strings.TrimSpace(value))
+		}
+	}
+	return sitemaps
+}
+
+// readSitemap appends the pages listed in sitemapURL to pages, following
+// sitemap index files.
+func (s *Scraper) readSitemap(ctx context.Context, sitemapURL string, depth int, seen map[string]struct{}, pages []string) []string {
+	if _, ok := seen[sitemapURL]; ok || depth > maxSitemapIndexDepth {
+		return pages
+	}
+	seen[sitemapURL] = struct{}{}
+
+	resp, err := s.fetch(ctx, sitemapURL, nil)
+	if err != nil {
+		s.logger().Warn("unable to fetch sitemap", "url", sitemapURL, "error", err)
+		return pages
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		s.logger().Warn("unable to fetch sitemap", "url", sitemapURL, "status", resp.Status)
+		return pages
+	}
+
+	var body io.Reader = resp.Body
+	if strings.HasSuffix(strings.ToLower(sitemapURL), ".gz") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			s.logger().Warn("fail to read sitemap", "url", sitemapURL, "error", err)
+			return pages
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	var doc sitemapDocument
+	if err := xml.NewDecoder(body).Decode(&doc); err != nil {
+		s.logger().Warn("fail to parse sitemap", "url", sitemapURL, "error", err)
+		return pages
+	}
+
+	for _, u := range doc.URLs {
+		if loc := strings.TrimSpace(u.Loc); loc != "" {
+			pages = append(pages, loc)
+		}
+	}
+	for _, sm := range doc.Sitemaps {
+		if loc := strings.TrimSpace(sm.Loc); loc != "" {
+			pages = s.readSitemap(ctx, loc, depth+1, seen, pages)
+		}
+	}
+	return pages
+}
@@ -0,0 +1,137 @@
+package kanjikana
+
+import (
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// IsSubtitleFile reports whether path names a SubRip, WebVTT or Advanced
+// SubStation Alpha subtitle file.
+func IsSubtitleFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".srt", ".vtt", ".ass", ".ssa":
+		return true
+	}
+	return false
+}
+
+var (
+	// subtitleRuby matches the readings of WebVTT ruby text.
+	subtitleRuby = regexp.MustCompile(`(?s)<rt>.*?</rt>|<rp>.*?</rp>`)
+	// subtitleTag matches the styling tags of SubRip and WebVTT cues, such
+	// as <i>, <font color="..."> or <v Speaker>, and their timestamps.
+	subtitleTag = regexp.MustCompile(`</?[a-zA-Z0-9:.][^>]*>`)
+	// subtitleOverride matches the override blocks of SubStation Alpha,
+	// such as {\an8} or {\i1}, also found in SubRip files.
+	subtitleOverride = regexp.MustCompile(`\{[^}]*\}`)
+)
+
+// SubtitleText returns the dialogue of a SubRip (.srt), WebVTT (.vtt) or
+// SubStation Alpha (.ass, .ssa) subtitle file, one line per line of
+// dialogue, without cue numbers, timestamps, styling tags or comments. The
+// format is detected from the content.
+func SubtitleText(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	s := strings.TrimPrefix(string(data), "\ufeff")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+
+	var lines []string
+	if strings.Contains(s, "[Events]") || strings.HasPrefix(strings.TrimSpace(s), "[Script Info]") {
+		lines = assDialogue(s)
+	} else {
+		lines = cueText(s)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// CountSubtitles counts the Japanese characters of the dialogue of a
+// subtitle file. See SubtitleText.
+func CountSubtitles(r io.Reader, options ...CountOption) (*Result, error) {
+	c, err := NewCounter(options...)
+	if err != nil {
+		return nil, err
+	}
+	text, err := SubtitleText(r)
+	if err != nil {
+		return nil, err
+	}
+	c.Count(text)
+	return c.Result(), nil
+}
+
+// cueText returns the text lines of the cues of a SubRip or WebVTT file.
+// Cues are blocks of lines separated by blank lines whose timing line holds
+// an arrow; other blocks, such as the WebVTT header, NOTE, STYLE and REGION
+// blocks, are skipped.
+func cueText(s string) []string {
+	var lines []string
+	for _, block := range strings.Split(s, "\n\n") {
+		blockLines := strings.Split(strings.Trim(block, "\n"), "\n")
+		timing := -1
+		for i, line := range blockLines {
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		if timing < 0 || strings.HasPrefix(blockLines[0], "NOTE") {
+			continue
+		}
+		for _, line := range blockLines[timing+1:] {
+			line = subtitleRuby.ReplaceAllString(line, "")
+			line = subtitleTag.ReplaceAllString(line, "")
+			line = subtitleOverride.ReplaceAllString(line, "")
+			if line = strings.TrimSpace(html.UnescapeString(line)); line != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return lines
+}
+
+// assDialogue returns the text of the Dialogue events of a SubStation
+// Alpha file. The text is the last field of an event, following the order
+// given by the Format line of the [Events] section.
+func assDialogue(s string) []string {
+	var lines []string
+	fields := 10
+	inEvents := false
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inEvents = strings.EqualFold(line, "[Events]")
+			continue
+		}
+		if !inEvents {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Format":
+			fields = len(strings.Split(value, ","))
+		case "Dialogue":
+			parts := strings.SplitN(value, ",", fields)
+			if len(parts) < fields {
+				continue
+			}
+			text := subtitleOverride.ReplaceAllString(parts[fields-1], "")
+			text = strings.NewReplacer(`\N`, "\n", `\n`, "\n", `\h`, " ").Replace(text)
+			for _, textLine := range strings.Split(text, "\n") {
+				if textLine = strings.TrimSpace(textLine); textLine != "" {
+					lines = append(lines, textLine)
+				}
+			}
+		}
+	}
+	return lines
+}
@@ -0,0 +1,173 @@
+package kanjikana
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// invisibleElements lists the elements whose text content is never rendered.
+var invisibleElements = map[atom.Atom]struct{}{
+	atom.Script:   {},
+	atom.Style:    {},
+	atom.Noscript: {},
+	atom.Template: {},
+	atom.Iframe:   {},
+	atom.Object:   {},
+	atom.Svg:      {},
+}
+
+// visibleText concatenates the text nodes of the document rooted at n,
+// skipping scripts, styles and other non-rendered elements.
+func visibleText(n *html.Node) string {
+	var sb strings.Builder
+	writeVisibleText(&sb, n)
+	return sb.String()
+}
+
+func writeVisibleText(sb *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		sb.WriteString(n.Data)
+		sb.WriteByte('\n')
+		return
+	case html.ElementNode:
+		if _, ok := invisibleElements[n.DataAtom]; ok {
+			return
+		}
+	case html.CommentNode, html.DoctypeNode:
+		return
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeVisibleText(sb, c)
+	}
+}
+
+// scanHTML streams the HTML document read from r through the tokenizer,
+// without building its tree, so that large pages are never held in memory.
+// It calls text with every visible text token, as visibleText would find
+// them, and tag, when not nil, with every start and end tag of reportedTags.
+// When chinese is not nil, it gets the text of the elements marked as
+// Chinese by their lang attribute instead of text.
+func scanHTML(r io.Reader, text, chinese func(string), tag func(html.Token)) error {
+	z := html.NewTokenizer(r)
+	// hidden counts the invisible elements the tokenizer is in.
+	hidden := 0
+	// chineseTag is the name of the outermost Chinese element the tokenizer
+	// is in, and chineseDepth the number of elements of that name it is in.
+	var chineseTag string
+	chineseDepth := 0
+	for {
+		switch tt := z.Next(); tt {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				return err
+			}
+			return nil
+		case html.TextToken:
+			switch {
+			case hidden > 0:
+			case chineseDepth > 0:
+				chinese(string(z.Text()))
+			default:
+				text(string(z.Text()))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			a := atom.Lookup(name)
+			if _, ok := invisibleElements[a]; ok && tt == html.StartTagToken {
+				hidden++
+			}
+			if chineseDepth > 0 && tt == html.StartTagToken && string(name) == chineseTag {
+				chineseDepth++
+			}
+			// The language of void elements does not apply to any text.
+			_, void := voidElements[a]
+			checkLang := chinese != nil && chineseDepth == 0 && tt == html.StartTagToken && !void
+			_, reported := reportedTags[a]
+			if !checkLang && (tag == nil || !reported) {
+				continue
+			}
+			tok := html.Token{Type: tt, DataAtom: a, Data: string(name)}
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				tok.Attr = append(tok.Attr, html.Attribute{Key: string(key), Val: string(val)})
+			}
+			if checkLang && isChineseLang(attribute(tok.Attr, "lang")) {
+				chineseTag, chineseDepth = tok.Data, 1
+			}
+			if tag != nil && reported {
+				tag(tok)
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			a := atom.Lookup(name)
+			if _, ok := invisibleElements[a]; ok && hidden > 0 {
+				hidden--
+			}
+			if _, reported := reportedTags[a]; reported && tag != nil {
+				tag(html.Token{Type: tt, DataAtom: a, Data: string(name)})
+			}
+			if chineseDepth > 0 && string(name) == chineseTag {
+				chineseDepth--
+			}
+		}
+	}
+}
+
+// reportedTags lists the tags given to the tag function of scanHTML, which
+// hold the links, the robots and canonical metadata, the title, the
+// publication date and the language of a page.
+var reportedTags = map[atom.Atom]struct{}{
+	atom.A:     {},
+	atom.Link:  {},
+	atom.Meta:  {},
+	atom.Html:  {},
+	atom.Title: {},
+}
+
+// voidElements lists the elements that have no content.
+var voidElements = map[atom.Atom]struct{}{
+	atom.Area:   {},
+	atom.Base:   {},
+	atom.Br:     {},
+	atom.Col:    {},
+	atom.Embed:  {},
+	atom.Hr:     {},
+	atom.Img:    {},
+	atom.Input:  {},
+	atom.Link:   {},
+	atom.Meta:   {},
+	atom.Source: {},
+	atom.Track:  {},
+	atom.Wbr:    {},
+}
+
+// VisibleText returns the visible text of an HTML document, the text that
+// CountHTML counts.
+func VisibleText(r io.Reader) (string, error) {
+	var sb strings.Builder
+	err := scanHTML(r, func(text string) {
+		sb.WriteString(text)
+		sb.WriteByte('\n')
+	}, nil, nil)
+	return sb.String(), err
+}
+
+// CountHTML counts the Japanese characters of the visible text of an HTML
+// document, which it reads as a stream.
+func CountHTML(r io.Reader, options ...CountOption) (*Result, error) {
+	c, err := NewCounter(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := scanHTML(r, c.Count, c.chineseHandler(), nil); err != nil {
+		return nil, err
+	}
+	return c.Result(), nil
+}
@@ -0,0 +1,110 @@
+package kanjikana
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestScanHTML(t *testing.T) {
+	tests := []struct {
+		name    string
+		page    string
+		chinese bool
+		// text and skipped are the visible text tokens given to the text
+		// and chinese functions, joined with "|".
+		text, skipped string
+	}{
+		{
+			name: "plain text",
+			page: "<p>日本<b>語</b>です</p>",
+			text: "日本|語|です",
+		},
+		{
+			name: "hidden elements",
+			page: "<p>あ<script>var s = 'い';</script>う<style>p { color: red }</style><noscript>え</noscript>お</p>",
+			text: "あ|う|お",
+		},
+		{
+			name: "nested hidden elements",
+			page: "<svg><title>い</title><g><text>ろ</text></g></svg><object><iframe>に</iframe>ほ</object>は",
+			text: "は",
+		},
+		{
+			name: "nested templates",
+			page: "<template><template>い</template>ろ</template>は",
+			text: "は",
+		},
+		{
+			name:    "Chinese element",
+			page:    `<p>日本</p><div lang="zh-CN">中文</div><p>終わり</p>`,
+			chinese: true,
+			text:    "日本|終わり",
+			skipped: "中文",
+		},
+		{
+			name:    "nested elements of a Chinese element",
+			page:    `<div lang="zh"><div>一<div>二</div>三</div><span lang="ja">四</span>五</div>六`,
+			chinese: true,
+			text:    "六",
+			skipped: "一|二|三|四|五",
+		},
+		{
+			name:    "hidden element in a Chinese element",
+			page:    `<section lang="zh-Hant">甲<script>乙</script>丙</section>丁`,
+			chinese: true,
+			text:    "丁",
+			skipped: "甲|丙",
+		},
+		{
+			name:    "lang of a void element",
+			page:    `<p>あ<br lang="zh">い<img lang="zh">う</p>`,
+			chinese: true,
+			text:    "あ|い|う",
+		},
+		{
+			name: "lang without Chinese handler",
+			page: `<div lang="zh">中文</div>日本`,
+			text: "中文|日本",
+		},
+	}
+	for _, tt := range tests {
+		var text, skipped []string
+		var chinese func(string)
+		if tt.chinese {
+			chinese = func(s string) { skipped = append(skipped, s) }
+		}
+		err := scanHTML(strings.NewReader(tt.page), func(s string) { text = append(text, s) }, chinese, nil)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := strings.Join(text, "|"); got != tt.text {
+			t.Errorf("%s: text = %q, want %q", tt.name, got, tt.text)
+		}
+		if got := strings.Join(skipped, "|"); got != tt.skipped {
+			t.Errorf("%s: Chinese text = %q, want %q", tt.name, got, tt.skipped)
+		}
+	}
+}
+
+func TestScanHTMLTags(t *testing.T) {
+	page := `<html lang="ja"><head><title>題</title><meta name="robots" content="noindex">` +
+		`<link rel="canonical" href="/a"></head><body><p>本文</p><a href="/b">リンク</a><br></body></html>`
+	var tags []string
+	err := scanHTML(strings.NewReader(page), func(string) {}, nil, func(tok html.Token) {
+		tag := tok.Data
+		if tok.Type == html.EndTagToken {
+			tag = "/" + tag
+		}
+		tags = append(tags, tag)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "html title /title meta link a /a /html"
+	if got := strings.Join(tags, " "); got != want {
+		t.Errorf("tags = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,111 @@
+package kanjikana
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// trackingParams lists the query parameters that only track where a visitor
+// comes from, besides the utm_ ones.
+var trackingParams = map[string]struct{}{
+	"fbclid":  {},
+	"gclid":   {},
+	"dclid":   {},
+	"gbraid":  {},
+	"wbraid":  {},
+	"msclkid": {},
+	"yclid":   {},
+	"twclid":  {},
+	"igshid":  {},
+	"mc_cid":  {},
+	"mc_eid":  {},
+	"_ga":     {},
+	"_gl":     {},
+}
+
+// normalizeURL returns u in the form used to fetch and cache pages: without
+// fragment, tracking parameters, default port nor dot segments, with a
+// lowercase host and a path of at least "/". The query is only rewritten
+// when tracking parameters are removed.
+func normalizeURL(u *url.URL) string {
+	n := u.ResolveReference(&url.URL{})
+	n.Fragment, n.RawFragment = "", ""
+	host, port := strings.ToLower(n.Hostname()), n.Port()
+	if (n.Scheme == "http" && port == "80") || (n.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	switch {
+	case port != "":
+		host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		// An IPv6 address.
+		host = "[" + host + "]"
+	}
+	n.Host = host
+	if n.Path == "" && n.Host != "" {
+		n.Path = "/"
+	}
+
+	if n.RawQuery != "" {
+		query := n.Query()
+		removed := false
+		for key := range query {
+			if _, ok := trackingParams[strings.ToLower(key)]; ok || strings.HasPrefix(strings.ToLower(key), "utm_") {
+				query.Del(key)
+				removed = true
+			}
+		}
+		if removed {
+			n.RawQuery = query.Encode()
+		}
+	}
+	return n.String()
+}
+
+// normalizeRawURL normalizes rawURL, which is returned as is when it cannot be
+// parsed.
+func normalizeRawURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return normalizeURL(u)
+}
+
+// visitKey returns the key of a normalized URL in the visited set, which
+// ignores a trailing slash: /news and /news/ are the same page on most
+// sites. The URL is still fetched as found, since relative links resolve
+// differently against both.
+func visitKey(normalized string) string {
+	u, err := url.Parse(normalized)
+	if err != nil || u.Path == "/" {
+		return normalized
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = ""
+	return u.String()
+}
+
+// hostPattern returns host in the form matched by matchesHost: lowercase and
+// without a leading "*." or ".".
+func hostPattern(host string) (string, error) {
+	host = strings.ToLower(strings.TrimSpace(host))
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "*"), ".")
+	if host == "" || strings.ContainsAny(host, "/:?#@ ") {
+		return "", fmt.Errorf("invalid host %q", host)
+	}
+	return host, nil
+}
+
+// matchesHost reports whether host is one of hosts or a subdomain of one.
+func matchesHost(hosts []string, host string) bool {
+	host = strings.ToLower(host)
+	for _, h := range hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
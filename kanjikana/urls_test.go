@@ -0,0 +1,49 @@
+package kanjikana
+
+import "testing"
+
+func TestNormalizeRawURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"http://example.com", "http://example.com/"},
+		{"http://Example.COM/Path", "http://example.com/Path"},
+		{"http://example.com:80/a", "http://example.com/a"},
+		{"https://example.com:443/a", "https://example.com/a"},
+		{"http://example.com:8080/a", "http://example.com:8080/a"},
+		{"https://example.com:80/a", "https://example.com:80/a"},
+		{"http://[::1]:80/a", "http://[::1]/a"},
+		{"http://[::1]:8080/a", "http://[::1]:8080/a"},
+		{"http://example.com/a#section", "http://example.com/a"},
+		{"http://example.com/a/./b/../c", "http://example.com/a/c"},
+		{"http://example.com/a?utm_source=x&utm_medium=y", "http://example.com/a"},
+		{"http://example.com/a?id=1&UTM_Campaign=x&fbclid=y", "http://example.com/a?id=1"},
+		{"http://example.com/a?b=2&a=1", "http://example.com/a?b=2&a=1"},
+		{"http://example.com/a?b=2&a=1&gclid=x", "http://example.com/a?a=1&b=2"},
+		{"http://example.com/%E6%97%A5%E6%9C%AC", "http://example.com/%E6%97%A5%E6%9C%AC"},
+		{"http://example.com/a?q=1#top", "http://example.com/a?q=1"},
+		{"://invalid", "://invalid"},
+	}
+	for _, tt := range tests {
+		if got := normalizeRawURL(tt.in); got != tt.want {
+			t.Errorf("normalizeRawURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestVisitKey(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"http://example.com/", "http://example.com/"},
+		{"http://example.com/news/", "http://example.com/news"},
+		{"http://example.com/news", "http://example.com/news"},
+		{"http://example.com/news/?page=2", "http://example.com/news?page=2"},
+		{"http://example.com/a/b/", "http://example.com/a/b"},
+	}
+	for _, tt := range tests {
+		if got := visitKey(tt.in); got != tt.want {
+			t.Errorf("visitKey(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package kanjikana
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// VariantTable maps kanji variants, such as the kyūjitai 國 and 學, to their
+// standard forms, 国 and 学.
+type VariantTable struct {
+	standard map[rune]rune
+}
+
+// ParseVariantTable reads a variant table with one variant per line followed
+// by its standard form. Blank lines and lines starting with # are ignored.
+func ParseVariantTable(r io.Reader) (*VariantTable, error) {
+	vt := &VariantTable{standard: make(map[rune]rune)}
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a variant and its standard form", lineNumber)
+		}
+		for _, field := range fields {
+			if utf8.RuneCountInString(field) != 1 {
+				return nil, fmt.Errorf("line %d: %q is not a single character", lineNumber, field)
+			}
+		}
+		variant, _ := utf8.DecodeRuneInString(fields[0])
+		standard, _ := utf8.DecodeRuneInString(fields[1])
+		vt.standard[variant] = standard
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return vt, nil
+}
+
+// Extend returns a table with the variants of both tables. The standard
+// forms of other take precedence.
+func (vt *VariantTable) Extend(other *VariantTable) *VariantTable {
+	extended := &VariantTable{standard: make(map[rune]rune, len(vt.standard)+len(other.standard))}
+	for variant, standard := range vt.standard {
+		extended.standard[variant] = standard
+	}
+	for variant, standard := range other.standard {
+		extended.standard[variant] = standard
+	}
+	return extended
+}
+
+// Standard returns the standard form of kanji, or kanji itself when the
+// table does not list it as a variant.
+func (vt *VariantTable) Standard(kanji rune) rune {
+	if standard, ok := vt.standard[kanji]; ok {
+		return standard
+	}
+	return kanji
+}
+
+// Normalize replaces the variants of s by their standard forms.
+func (vt *VariantTable) Normalize(s string) string {
+	if strings.IndexFunc(s, func(r rune) bool { _, ok := vt.standard[r]; return ok }) < 0 {
+		return s
+	}
+	return strings.Map(vt.Standard, s)
+}
+
+//go:embed data/variants.txt
+var variantsData string
+
+var bundledVariants = func() *VariantTable {
+	vt, err := ParseVariantTable(strings.NewReader(variantsData))
+	if err != nil {
+		panic(err)
+	}
+	return vt
+}()
+
+// BundledVariants returns the bundled variant table, which maps the kyūjitai
+// of the jōyō and jinmeiyō kanji and a few common variants to their
+// shinjitai.
+func BundledVariants() *VariantTable {
+	return bundledVariants
+}
@@ -0,0 +1,248 @@
+package kanjikana
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IsWARCFile reports whether path names a WARC archive, compressed or not.
+func IsWARCFile(path string) bool {
+	path = strings.ToLower(path)
+	return strings.HasSuffix(path, ".warc") || strings.HasSuffix(path, ".warc.gz")
+}
+
+// ScrapeWARC counts the HTML pages archived in the WARC file read from r, as
+// written by wget or Heritrix, instead of fetching them: every successful
+// response record is counted as a visited page, without following links.
+// The options filtering and counting pages apply, as WithLinkPattern,
+// WithContentSelector or WithMinJapaneseRatio. Archives compressed with
+// gzip are decompressed. It stops reading once ctx is done, returning the
+// counts gathered so far together with the context error.
+func (s *Scraper) ScrapeWARC(ctx context.Context, r io.Reader) (*Result, error) {
+	warc, err := newWARCReader(r)
+	if err != nil {
+		return nil, err
+	}
+	s.reset()
+	// Archives have no root URL to restrict the pages to with
+	// WithSameDomainOnly.
+	s.rootURL, s.rootHost = "", ""
+
+	start := time.Now()
+	records := 0
+	for ctx.Err() == nil {
+		record, err := warc.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return s.result(), err
+		}
+		fetched, ok := record.page()
+		if !ok || !s.followable(fetched.url) {
+			continue
+		}
+		records++
+		s.countPage(crawlTask{url: normalizeRawURL(fetched.url)}, fetched)
+		fetched.body.Close()
+
+		if s.opts.progress != nil {
+			s.opts.progress(Progress{
+				Fetched:    records,
+				Characters: s.counter.characters(),
+				Elapsed:    time.Since(start),
+			})
+		}
+		if (s.opts.maxPages > 0 && records >= s.opts.maxPages) || s.enough() {
+			break
+		}
+	}
+	return s.result(), ctx.Err()
+}
+
+// warcReader reads the records of a WARC file.
+type warcReader struct {
+	br *bufio.Reader
+	// content is the content of the last record, discarded by next.
+	content io.Reader
+}
+
+func newWARCReader(r io.Reader) (*warcReader, error) {
+	br := bufio.NewReader(r)
+	// Compressed archives are made of a gzip member per record, which the
+	// gzip reader reads as a single stream.
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(gz)
+	}
+	return &warcReader{br: br}, nil
+}
+
+// warcRecord is a record of a WARC file.
+type warcRecord struct {
+	header  textproto.MIMEHeader
+	content io.Reader
+}
+
+// next returns the next record, or io.EOF after the last one. The content of
+// the record is only valid until the next call.
+func (w *warcReader) next() (*warcRecord, error) {
+	if w.content != nil {
+		if _, err := io.Copy(io.Discard, w.content); err != nil {
+			return nil, err
+		}
+	}
+
+	tp := textproto.NewReader(w.br)
+	// Records are separated by blank lines.
+	var version string
+	for version == "" {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		version = strings.TrimSpace(line)
+	}
+	if !strings.HasPrefix(version, "WARC/") {
+		return nil, fmt.Errorf("invalid WARC record: %q", version)
+	}
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		return nil, fmt.Errorf("invalid WARC record: %w", err)
+	}
+	length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid WARC record length: %q", header.Get("Content-Length"))
+	}
+	w.content = io.LimitReader(w.br, length)
+	return &warcRecord{header: header, content: w.content}, nil
+}
+
+// page returns the page archived by the record: the body of a successful
+// HTTP response, or the content of a resource. It reports false for the
+// other records.
+func (r *warcRecord) page() (*fetchedPage, bool) {
+	// Target URIs are enclosed in angle brackets in WARC 1.0 files written
+	// by some tools.
+	target := strings.Trim(r.header.Get("WARC-Target-URI"), "<>")
+	if target == "" {
+		return nil, false
+	}
+	contentType := r.header.Get("Content-Type")
+	switch r.header.Get("WARC-Type") {
+	case "resource":
+		return &fetchedPage{body: io.NopCloser(r.content), contentType: contentType, url: target}, true
+	case "response":
+		if !strings.HasPrefix(contentType, "application/http") {
+			return nil, false
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(r.content), nil)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			return nil, false
+		}
+		decodeBody(resp)
+		return &fetchedPage{body: resp.Body, contentType: resp.Header.Get("Content-Type"), url: target}, true
+	}
+	return nil, false
+}
+
+// warcWriter writes the pages fetched by crawls as the records of a WARC
+// file, each compressed as its own gzip member when compress is set, as in
+// .warc.gz files. It is shared by the scrapers built with the same options.
+type warcWriter struct {
+	mu       sync.Mutex
+	w        io.Writer
+	compress bool
+	// started reports whether the warcinfo record opening the file was
+	// written.
+	started bool
+}
+
+// writeResponse records the response of a request to targetURI with its
+// decoded body.
+func (w *warcWriter) writeResponse(targetURI string, resp *http.Response, body []byte) error {
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "HTTP/%d.%d %s\r\n", resp.ProtoMajor, resp.ProtoMinor, resp.Status)
+	header := resp.Header.Clone()
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	header.Write(&content)
+	content.WriteString("\r\n")
+	content.Write(body)
+	return w.writeRecord("response", targetURI, "application/http; msgtype=response", content.Bytes())
+}
+
+// writeResource records a page of the given content type loaded without a
+// response to record, as the pages read from the page cache.
+func (w *warcWriter) writeResource(targetURI, contentType string, body []byte) error {
+	return w.writeRecord("resource", targetURI, contentType, body)
+}
+
+func (w *warcWriter) writeRecord(recordType, targetURI, contentType string, content []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.started {
+		info := []byte("software: kanji-kana-frequency-counter\r\nformat: WARC File Format 1.1\r\n")
+		if err := w.write("warcinfo", "", "application/warc-fields", info); err != nil {
+			return err
+		}
+		w.started = true
+	}
+	return w.write(recordType, targetURI, contentType, content)
+}
+
+func (w *warcWriter) write(recordType, targetURI, contentType string, content []byte) error {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	// A random UUID, of version 4.
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+
+	var record bytes.Buffer
+	record.WriteString("WARC/1.1\r\n")
+	fmt.Fprintf(&record, "WARC-Type: %s\r\n", recordType)
+	fmt.Fprintf(&record, "WARC-Record-ID: <urn:uuid:%x-%x-%x-%x-%x>\r\n", id[0:4], id[4:6], id[6:8], id[8:10], id[10:])
+	fmt.Fprintf(&record, "WARC-Date: %s\r\n", time.Now().UTC().Format(time.RFC3339))
+	if targetURI != "" {
+		fmt.Fprintf(&record, "WARC-Target-URI: %s\r\n", targetURI)
+	}
+	fmt.Fprintf(&record, "Content-Type: %s\r\n", contentType)
+	fmt.Fprintf(&record, "Content-Length: %d\r\n\r\n", len(content))
+	record.Write(content)
+	record.WriteString("\r\n\r\n")
+
+	if !w.compress {
+		_, err := w.w.Write(record.Bytes())
+		return err
+	}
+	gz := gzip.NewWriter(w.w)
+	if _, err := gz.Write(record.Bytes()); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// archive records a page that was not fetched, when WithWARCOutput is set.
+func (s *Scraper) archive(targetURI, contentType string, body []byte) {
+	if s.opts.warc == nil {
+		return
+	}
+	if err := s.opts.warc.writeResource(targetURI, contentType, body); err != nil {
+		s.logger().Warn("unable to record page", "url", targetURI, "error", err)
+	}
+}
@@ -0,0 +1,85 @@
+package kanjikana
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// waybackPrefix is the address of the snapshots of the Wayback Machine.
+const waybackPrefix = "https://web.archive.org/web/"
+
+// waybackURL returns the URL of the snapshot of pageURL closest to
+// timestamp, as first archived: the id_ flag asks for the page without the
+// Wayback Machine toolbar nor rewritten links.
+func waybackURL(pageURL, timestamp string) string {
+	return waybackPrefix + timestamp + "id_/" + pageURL
+}
+
+// ParseWaybackURL returns the archived URL and the timestamp of a Wayback
+// Machine URL, such as
+// https://web.archive.org/web/20050401000000/http://www.yomiuri.co.jp/.
+// It reports false when rawURL is not the URL of a snapshot.
+func ParseWaybackURL(rawURL string) (pageURL, timestamp string, ok bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Hostname() != "web.archive.org" && u.Hostname() != "wayback.archive.org") {
+		return "", "", false
+	}
+	// The archived URL is taken from the raw URL, as its query belongs to
+	// it.
+	_, rest, found := strings.Cut(rawURL, "/web/")
+	if !found {
+		return "", "", false
+	}
+	timestamp, pageURL, found = strings.Cut(rest, "/")
+	// Timestamps may be followed by a flag, as id_ or if_.
+	timestamp = strings.TrimRightFunc(timestamp, func(r rune) bool {
+		return r == '_' || r == '*' || ('a' <= r && r <= 'z')
+	})
+	if !found || validWaybackTimestamp(timestamp) != nil || pageURL == "" {
+		return "", "", false
+	}
+
+	// Archived URLs may lack their scheme, or have the slashes following it
+	// merged.
+	switch {
+	case strings.HasPrefix(pageURL, "http://"), strings.HasPrefix(pageURL, "https://"):
+	case strings.HasPrefix(pageURL, "http:/"), strings.HasPrefix(pageURL, "https:/"):
+		scheme, rest, _ := strings.Cut(pageURL, ":/")
+		pageURL = scheme + "://" + rest
+	default:
+		pageURL = "http://" + pageURL
+	}
+	return pageURL, timestamp, true
+}
+
+// validWaybackTimestamp checks that timestamp is a date of the form
+// yyyyMMddhhmmss, possibly truncated down to its year.
+func validWaybackTimestamp(timestamp string) error {
+	if len(timestamp) < 4 || len(timestamp) > 14 {
+		return errors.New("timestamp of the Wayback Machine should have 4 to 14 digits, as 2005 or 20050401")
+	}
+	for _, r := range timestamp {
+		if r < '0' || r > '9' {
+			return errors.New("timestamp of the Wayback Machine should only have digits, as 2005 or 20050401")
+		}
+	}
+	return nil
+}
+
+// originalURL returns the URL archived by u when crawling snapshots of the
+// Wayback Machine, and u otherwise.
+func (s *Scraper) originalURL(u *url.URL) *url.URL {
+	if s.opts.wayback == "" {
+		return u
+	}
+	pageURL, _, ok := ParseWaybackURL(u.String())
+	if !ok {
+		return u
+	}
+	original, err := url.Parse(pageURL)
+	if err != nil {
+		return u
+	}
+	return original
+}
@@ -0,0 +1,186 @@
+package kanjikana
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// wikipediaPage is a <page> element of a MediaWiki XML dump.
+type wikipediaPage struct {
+	Title    string `xml:"title"`
+	NS       int    `xml:"ns"`
+	Redirect *struct {
+		Title string `xml:"title,attr"`
+	} `xml:"redirect"`
+	Text string `xml:"revision>text"`
+}
+
+// ReadWikipediaDump streams the articles of a MediaWiki XML dump, such as
+// jawiki-latest-pages-articles.xml.bz2, compressed with bzip2 or not, and
+// calls handle with the title and the text of every article, stripped of
+// its wiki markup. Redirects and pages outside of the main namespace are
+// skipped. Only one article is held in memory at a time.
+func ReadWikipediaDump(r io.Reader, handle func(title, text string) error) error {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(3); err == nil && string(magic) == "BZh" {
+		r = bzip2.NewReader(br)
+	} else {
+		r = br
+	}
+
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "page" {
+			continue
+		}
+
+		var page wikipediaPage
+		if err := decoder.DecodeElement(&page, &start); err != nil {
+			return err
+		}
+		if page.NS != 0 || page.Redirect != nil {
+			continue
+		}
+		if err := handle(page.Title, StripWikiMarkup(page.Text)); err != nil {
+			return err
+		}
+	}
+}
+
+// CountWikipediaDump counts the Japanese characters of the articles of a
+// MediaWiki XML dump. See ReadWikipediaDump.
+func CountWikipediaDump(r io.Reader, options ...CountOption) (*Result, error) {
+	c, err := NewCounter(options...)
+	if err != nil {
+		return nil, err
+	}
+	err = ReadWikipediaDump(r, func(title, text string) error {
+		c.Count(text)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.Result(), nil
+}
+
+var (
+	wikiComment = regexp.MustCompile(`(?s)<!--.*?-->`)
+	// wikiRef matches footnotes, which mostly hold citations.
+	wikiRef = regexp.MustCompile(`(?is)<ref[^>/]*/>|<ref[^>]*>.*?</ref>`)
+	// wikiIgnoredTags matches elements whose content is not prose.
+	wikiIgnoredTags  = regexp.MustCompile(`(?is)<(math|gallery|timeline|syntaxhighlight|source|pre|score|graph)[^>]*>.*?</(math|gallery|timeline|syntaxhighlight|source|pre|score|graph)>`)
+	wikiTag          = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	wikiExternalLink = regexp.MustCompile(`\[(?:https?:)?//[^\s\]]+\s*([^\]]*)\]`)
+	// wikiMediaLink matches links to files and categories, in English and
+	// in Japanese.
+	wikiMediaLink = regexp.MustCompile(`(?i)^(file|image|category|ファイル|画像|カテゴリ|media):`)
+	wikiEmphasis  = regexp.MustCompile(`'{2,}`)
+	wikiHeading   = regexp.MustCompile(`(?m)^=+\s*(.*?)\s*=+\s*$`)
+	wikiListItem  = regexp.MustCompile(`(?m)^[*#:;]+\s*`)
+)
+
+// StripWikiMarkup returns the prose of a MediaWiki article: templates,
+// tables, footnotes, comments, file and category links and HTML tags are
+// removed, and links are replaced by their label.
+func StripWikiMarkup(s string) string {
+	s = wikiComment.ReplaceAllString(s, "")
+	s = wikiRef.ReplaceAllString(s, "")
+	s = wikiIgnoredTags.ReplaceAllString(s, "")
+	s = removeNested(s, "{{", "}}")
+	s = removeNested(s, "{|", "|}")
+	s = replaceWikiLinks(s)
+	s = wikiExternalLink.ReplaceAllString(s, "$1")
+	s = wikiTag.ReplaceAllString(s, "")
+	s = wikiEmphasis.ReplaceAllString(s, "")
+	s = wikiHeading.ReplaceAllString(s, "$1")
+	s = wikiListItem.ReplaceAllString(s, "")
+	return htmlEntities.Replace(s)
+}
+
+// htmlEntities decodes the entities commonly found in wiki text.
+var htmlEntities = strings.NewReplacer("&nbsp;", " ", "&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`)
+
+// removeNested removes the text between open and close delimiters, which
+// may be nested, such as templates.
+func removeNested(s, open, close string) string {
+	if !strings.Contains(s, open) {
+		return s
+	}
+	var sb strings.Builder
+	depth := 0
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], open):
+			depth++
+			i += len(open)
+		case depth > 0 && strings.HasPrefix(s[i:], close):
+			depth--
+			i += len(close)
+		default:
+			if depth == 0 {
+				sb.WriteByte(s[i])
+			}
+			i++
+		}
+	}
+	return sb.String()
+}
+
+// replaceWikiLinks replaces [[target|label]] links by their label, or by
+// their target without label, and removes file and category links, whose
+// captions may contain links.
+func replaceWikiLinks(s string) string {
+	var sb strings.Builder
+	for {
+		start := strings.Index(s, "[[")
+		if start < 0 {
+			sb.WriteString(s)
+			return sb.String()
+		}
+		sb.WriteString(s[:start])
+
+		// Find the matching ]], skipping nested links.
+		depth, end := 0, -1
+		for i := start; i < len(s)-1; i++ {
+			if s[i] == '[' && s[i+1] == '[' {
+				depth++
+				i++
+			} else if s[i] == ']' && s[i+1] == ']' {
+				depth--
+				i++
+				if depth == 0 {
+					end = i + 1
+					break
+				}
+			}
+		}
+		if end < 0 {
+			// Unbalanced link: drop the brackets.
+			s = s[start+2:]
+			continue
+		}
+
+		link := s[start+2 : end-2]
+		s = s[end:]
+		if wikiMediaLink.MatchString(strings.TrimSpace(link)) {
+			continue
+		}
+		if i := strings.LastIndex(link, "|"); i >= 0 {
+			link = link[i+1:]
+		}
+		sb.WriteString(replaceWikiLinks(link))
+	}
+}
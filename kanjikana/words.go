@@ -0,0 +1,88 @@
+package kanjikana
+
+import (
+	"unicode"
+)
+
+// Token is a word found by a Tokenizer.
+type Token struct {
+	// Surface is the word as written in the text.
+	Surface string
+	// BaseForm is the dictionary form of the word, e.g. 食べる for 食べた.
+	// It is empty when unknown.
+	BaseForm string
+	// Reading is the reading of the surface in hiragana or katakana, e.g.
+	// たべた for 食べた. It is empty when unknown.
+	Reading string
+	// PartOfSpeech is the class of the word, such as PartOfSpeechNoun or
+	// a tag of the tokenizer. It is empty when unknown.
+	PartOfSpeech string
+}
+
+// Word returns the form under which the token is counted: its base form
+// when known, its surface otherwise.
+func (t Token) Word() string {
+	if t.BaseForm != "" {
+		return t.BaseForm
+	}
+	return t.Surface
+}
+
+// Tokenizer splits text into words. A morphological analyzer such as kagome
+// can be plugged in with WithTokenizer to get real word and lemma counts.
+type Tokenizer interface {
+	Tokenize(text string) []Token
+}
+
+// ScriptTokenizer is a dictionary-free Tokenizer that splits text into runs
+// of the same script. It finds kanji compounds and katakana loanwords
+// reliably, but hiragana runs mix particles and inflections and no base
+// form is given.
+type ScriptTokenizer struct{}
+
+type script int
+
+const (
+	otherScript script = iota
+	kanjiScript
+	hiraganaScript
+	katakanaScript
+)
+
+func scriptOf(r rune) script {
+	switch {
+	case runeCategory(r) == CategoryKanji, r == iterationMark:
+		return kanjiScript
+	case unicode.Is(unicode.Hiragana, r):
+		return hiraganaScript
+	case unicode.Is(unicode.Katakana, r):
+		return katakanaScript
+	default:
+		return otherScript
+	}
+}
+
+func (ScriptTokenizer) Tokenize(text string) []Token {
+	runes := []rune(text)
+	scripts := make([]script, len(runes))
+	for i, r := range runes {
+		scripts[i] = scriptOf(r)
+		// The long vowel mark extends the kana run it follows.
+		if r == 'ー' && i > 0 && (scripts[i-1] == hiraganaScript || scripts[i-1] == katakanaScript) {
+			scripts[i] = scripts[i-1]
+		}
+	}
+
+	var tokens []Token
+	start := 0
+	for i := 1; i <= len(runes); i++ {
+		if i < len(runes) && scripts[i] == scripts[start] {
+			continue
+		}
+		if scripts[start] != otherScript {
+			tokens = append(tokens, Token{Surface: string(runes[start:i])})
+		}
+		start = i
+	}
+	return tokens
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// logFlags are the logging flags shared by every command.
+type logFlags struct {
+	verbose     bool
+	veryVerbose bool
+	quiet       bool
+	format      string
+}
+
+func (lf *logFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&lf.verbose, "v", false, "log the crawl settings and retries")
+	fs.BoolVar(&lf.veryVerbose, "vv", false, "also log every visited page")
+	fs.BoolVar(&lf.quiet, "quiet", false, "only write the result: no progress, no warnings, no timing (errors are still reported)")
+	fs.StringVar(&lf.format, "log-format", "text", "log format (text, json)")
+}
+
+// setup makes the logger configured by the flags, writing to w, the default
+// logger and returns it.
+func (lf *logFlags) setup(w io.Writer) *slog.Logger {
+	if lf.quiet && (lf.verbose || lf.veryVerbose) {
+		fatal("-quiet cannot be combined with -v or -vv")
+	}
+	level := slog.LevelWarn
+	switch {
+	case lf.quiet:
+		level = slog.LevelError
+	case lf.veryVerbose:
+		level = slog.LevelDebug
+	case lf.verbose:
+		level = slog.LevelInfo
+	}
+	logger, err := newLogger(w, lf.format, level)
+	if err != nil {
+		fatal(err)
+	}
+	slog.SetDefault(logger)
+	return logger
+}
+
+// newLogger returns a logger writing records of at least level to w, as
+// text or JSON depending on format.
+func newLogger(w io.Writer, format string, level slog.Level) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("unknown log format: %s", format)
+}
+
+// fatal logs an error and exits.
+func fatal(err any) {
+	slog.Error(fmt.Sprint(err))
+	os.Exit(1)
+}
+
+// fatalf is like fatal with a format string.
+func fatalf(format string, args ...any) {
+	slog.Error(fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+)
+
+const lookupCommand = "lookup"
+
+func runLookup(args []string) {
+	fs := flag.NewFlagSet(lookupCommand, flag.ExitOnError)
+	kanjidicFile := fs.String("kanjidic", "", "KANJIDIC2 XML file (optionally gzipped) with kanji readings, meanings and stroke counts")
+	jmdictFile := fs.String("jmdict", "", "JMdict XML file (optionally gzipped) to look words up")
+	jlptFile := fs.String("jlpt-file", "", "load JLPT kanji levels from a file instead of the bundled list")
+	var kradfiles []string
+	fs.Func("kradfile", "KRADFILE decomposition file listing kanji components (can be repeated)", func(path string) error {
+		kradfiles = append(kradfiles, path)
+		return nil
+	})
+	var lf logFlags
+	lf.register(fs)
+	setCommandUsage(fs, "lookup [flags] text...", "Show what is known about every kanji of text, and about text itself as a word with -jmdict.")
+	fs.Parse(args)
+	lf.setup(os.Stderr)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	rep := &report{
+		readings: kanjikana.BundledReadings(),
+		jlpt:     kanjikana.JLPTLevels(),
+		grades:   kanjikana.SchoolGrades(),
+	}
+	var err error
+	if *jlptFile != "" {
+		if rep.jlpt, err = loadKanjiLevels(*jlptFile); err != nil {
+			fatal(err)
+		}
+	}
+	if *kanjidicFile != "" {
+		if rep.kanjidic, err = loadKanjidic(*kanjidicFile); err != nil {
+			fatal(err)
+		}
+		rep.strokes = true
+	}
+	if *jmdictFile != "" {
+		if rep.jmdict, err = loadJMdict(*jmdictFile); err != nil {
+			fatal(err)
+		}
+	}
+	if len(kradfiles) > 0 {
+		if rep.kradfile, err = loadKradfiles(kradfiles); err != nil {
+			fatal(err)
+		}
+	}
+
+	joyo := kanjikana.JoyoKanji()
+	for _, text := range fs.Args() {
+		if rep.jmdict != nil && rep.jmdict.Lookup(text) != nil {
+			printEntry(os.Stdout, text, rep.wordColumns(), rep.wordValues(text))
+		}
+		for _, r := range text {
+			if !unicode.Is(unicode.Han, r) {
+				continue
+			}
+			c := string(r)
+			columns := append(rep.kanjiColumns(), "joyo")
+			values := append(rep.kanjiValues(c), fmt.Sprint(joyo.Level(c) != ""))
+			if rep.kradfile != nil {
+				columns = append(columns, "components")
+				values = append(values, strings.Join(rep.kradfile.Components(c), " "))
+			}
+			printEntry(os.Stdout, c, columns, values)
+		}
+	}
+}
+
+// printEntry prints the non-empty values of an entry, one per line.
+func printEntry(w io.Writer, entry string, columns, values []string) {
+	fmt.Fprintln(w, entry)
+	for i, column := range columns {
+		if values[i] != "" {
+			fmt.Fprintf(w, "  %-10s %s\n", column+":", values[i])
+		}
+	}
+	fmt.Fprintln(w)
+}
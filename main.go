@@ -1,280 +1,253 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
-	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"log"
-	"net/http"
-	"sort"
+	"os"
+	"strconv"
 	"strings"
-	"time"
+	"unicode/utf8"
 
-	"github.com/gojp/kana"
-	"golang.org/x/net/html"
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/term"
 )
 
 const (
-	defaultURL         = "https://www.yomiuri.co.jp"
-	defaultSearchDepth = 1
-	maxSearchDepth     = 10
 	defaultRankingSize = 100
+	stdinInput         = "-"
 )
 
-type scraperOptions struct {
-	searchDepth *int
-	loggingMode bool
-}
-
-type Option func(*scraperOptions) error
-
-// kanjiKanaFrequencyCounter describes the counting of Kanji, kanas e hiraganas.
-type kanjiKanaFrequencyCounter struct {
-	allCharacteresCount int
-	uniqueCount         int
-	kanjiUniqueCount    int
-	kanaUniqueCount     int
-	hiraganaUniqueCount int
-	katakanaUniqueCount int
-	kanjis              map[string]int
-	hiraganas           map[string]int
-	katakanas           map[string]int
+// commands maps the name of every subcommand to the function running it
+// with its arguments.
+var commands = map[string]func(args []string){
+	crawlCommand:     runCrawl,
+	fileCommand:      runFile,
+	aozoraCommand:    runAozora,
+	wikipediaCommand: runWikipedia,
+	youtubeCommand:   runYouTube,
+	serveCommand:     runServe,
+	diffCommand:      runDiff,
+	exportCommand:    runExport,
+	mergeCommand:     runMerge,
+	lookupCommand:    runLookup,
 }
 
 func main() {
-
-	var (
-		url         string
-		searchDepth int
-		rankingSize int
-	)
-
-	flag.StringVar(&url, "url", defaultURL, "target website")
-	flag.IntVar(&searchDepth, "depth", defaultSearchDepth, "search depth")
-	flag.IntVar(&rankingSize, "ranksize", defaultRankingSize, "ranking size")
-	flag.Parse()
-
-	startExecTime := time.Now()
-	res, err := newKanjiKanaScraper(url, WithSearchDepth(searchDepth), WithLogging())
-	if err != nil {
-		log.Println(err)
+	if len(os.Args) > 1 {
+		if run, ok := commands[os.Args[1]]; ok {
+			run(os.Args[2:])
+			return
+		}
 	}
+	runCount(legacyCommand, os.Args[1:])
+}
 
-	mostCommonKanjis := getMostCommonCharactersList(res.kanjis)
-	mostCommonKatakana := getMostCommonCharactersList(res.katakanas)
-	mostCommonHiragana := getMostCommonCharactersList(res.hiraganas)
-
-	fmt.Println("All Japanese characters found:", res.allCharacteresCount)
-	fmt.Println("Kanji unique count:", res.kanjiUniqueCount)
+// usage prints the subcommands and the flags accepted without a subcommand.
+func usage(fs *flag.FlagSet) {
+	w := fs.Output()
+	fmt.Fprintf(w, "Usage: %s <command> [flags] [arguments]\n\n", os.Args[0])
+	fmt.Fprintln(w, "Commands:")
+	fmt.Fprintln(w, "  crawl      crawl a website and count its Japanese characters")
+	fmt.Fprintln(w, "  file       count the Japanese characters of local files or stdin")
+	fmt.Fprintln(w, "  aozora     count books downloaded from Aozora Bunko")
+	fmt.Fprintln(w, "  wikipedia  count the articles of a Wikipedia dump")
+	fmt.Fprintln(w, "  youtube    count the Japanese captions of YouTube videos")
+	fmt.Fprintln(w, "  serve      count characters over HTTP")
+	fmt.Fprintln(w, "  diff       compare two saved results")
+	fmt.Fprintln(w, "  export     write a result stored in a SQLite database")
+	fmt.Fprintln(w, "  merge      sum the counts of several saved results")
+	fmt.Fprintln(w, "  lookup     show what is known about kanji and words")
+	fmt.Fprintf(w, "\nRun %s <command> -h for the flags of a command.\n", os.Args[0])
+	fmt.Fprintln(w, "Without a command, the flags of crawl and file are accepted:")
+	fs.PrintDefaults()
+}
 
-	kanjiRankingSize := min(res.kanjiUniqueCount, rankingSize)
-	if res.kanjiUniqueCount > 0 {
-		fmt.Println(kanjiRankingSize, "most common Kanji characters:")
-		printCharactersRanking(res.kanjis, mostCommonKanjis, kanjiRankingSize)
+func scrape(ctx context.Context, url string, options ...kanjikana.Option) (*kanjikana.Result, error) {
+	scraper, err := kanjikana.NewScraper(options...)
+	if err != nil {
+		return nil, err
 	}
+	return scraper.ScrapeContext(ctx, url)
+}
 
-	fmt.Println("Kana unique count:", res.kanaUniqueCount)
-	fmt.Println("Katakana unique count:", res.katakanaUniqueCount)
-	fmt.Println("Hiragana unique count:", res.hiraganaUniqueCount)
-
-	katakanaRankingSize := min(res.katakanaUniqueCount, rankingSize)
-	if res.katakanaUniqueCount > 0 {
-		fmt.Println(katakanaRankingSize, "most common Katakana characters:")
-		printCharactersRanking(res.katakanas, mostCommonKatakana, katakanaRankingSize)
+func scrapeWARC(ctx context.Context, path string, options ...kanjikana.Option) (*kanjikana.Result, error) {
+	scraper, err := kanjikana.NewScraper(options...)
+	if err != nil {
+		return nil, err
 	}
-
-	hiraganaRankingSize := min(res.hiraganaUniqueCount, rankingSize)
-	if res.hiraganaUniqueCount > 0 {
-		fmt.Println(hiraganaRankingSize, "most common Hiragana characters:")
-		printCharactersRanking(res.hiraganas, mostCommonHiragana, hiraganaRankingSize)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
 	}
-
-	log.Printf("total time: %v ms\n", time.Since(startExecTime))
+	defer f.Close()
+	return scraper.ScrapeWARC(ctx, f)
 }
 
-func printCharactersRanking(m map[string]int, rankingList []string, rankingSize int) {
-	minRankingSize := rankingSize
-	if len(rankingList) < minRankingSize {
-		minRankingSize = len(rankingList)
+func countFile(path string, options ...kanjikana.CountOption) (*kanjikana.Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
 	}
-	for i := 0; i < minRankingSize; i++ {
-		if kana.IsKana(rankingList[i]) {
-			romaji := kana.KanaToRomaji(rankingList[i])
-			fmt.Printf("%4d. %v %v (%v)\n", i+1, rankingList[i], romaji, m[rankingList[i]])
-		} else {
-			fmt.Printf("%4d. %v (%v)\n", i+1, rankingList[i], m[rankingList[i]])
+	defer f.Close()
+	if kanjikana.IsPDFFile(path) {
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		return kanjikana.CountPDF(f, info.Size(), options...)
+	}
+	if kanjikana.IsEPUBFile(path) {
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
 		}
+		return kanjikana.CountEPUB(f, info.Size(), options...)
+	}
+	if kanjikana.IsSubtitleFile(path) {
+		return kanjikana.CountSubtitles(f, options...)
 	}
-	fmt.Println()
+	if kanjikana.IsHTMLFile(path) {
+		return kanjikana.CountHTML(f, options...)
+	}
+	return kanjikana.CountReader(f, options...)
 }
 
-func getMostCommonCharactersList(m map[string]int) []string {
-	var i int
-	charactersList := make([]string, len(m))
-	for k := range m {
-		charactersList[i] = k
-		i += 1
+func loadKanjiLevels(path string) (*kanjikana.KanjiLevels, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
 	}
-
-	sort.SliceStable(charactersList, func(i, j int) bool {
-		return m[charactersList[i]] > m[charactersList[j]]
-	})
-
-	return charactersList
+	defer f.Close()
+	return kanjikana.ParseKanjiLevels(f)
 }
 
-func (fc *kanjiKanaFrequencyCounter) routine(ctx context.Context, url string, layer int) {
-	if layer < 0 {
-		return
+func loadVariantTable(path string) (*kanjikana.VariantTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
 	}
+	defer f.Close()
+	return kanjikana.ParseVariantTable(f)
+}
 
-	resp, err := http.Get(url)
+func loadKanjidic(path string) (*kanjikana.Kanjidic, error) {
+	r, err := openDictionary(path)
 	if err != nil {
-		fmt.Println("unable to fetch url", err)
-		return
+		return nil, err
 	}
-	defer resp.Body.Close()
+	defer r.Close()
+	return kanjikana.ParseKanjidic(r)
+}
 
-	body, err := io.ReadAll(resp.Body)
+func loadJMdict(path string) (*kanjikana.JMdict, error) {
+	r, err := openDictionary(path)
 	if err != nil {
-		fmt.Println("fail to read response body", err)
-		return
+		return nil, err
 	}
-	text := string(body)
-	for _, r := range text {
-		c := string(r)
-		if kana.IsKanji(c) || kana.IsKatakana(c) || kana.IsHiragana(c) {
-			fc.allCharacteresCount += 1
-			if kana.IsKanji(c) {
-				fc.kanjis[c] += 1
-			}
-			if kana.IsKatakana(c) {
-				fc.katakanas[c] += 1
-			}
-			if kana.IsHiragana(c) {
-				fc.hiraganas[c] += 1
-			}
-		}
-	}
-
-	links := make(map[string]struct{})
-	reader := strings.NewReader(text)
-	tokenizer := html.NewTokenizer(reader)
+	defer r.Close()
+	return kanjikana.ParseJMdict(r)
+}
 
-	for {
-		tokenType := tokenizer.Next()
-		if tokenType == html.ErrorToken {
-			break
+// loadKradfiles parses the KRADFILE files at paths into one Kradfile. The
+// files are distributed in EUC-JP; files that are not valid UTF-8 are
+// transcoded from EUC-JP.
+func loadKradfiles(paths []string) (*kanjikana.Kradfile, error) {
+	var kf *kanjikana.Kradfile
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
 		}
 
-		token := tokenizer.Token()
-		if tokenType == html.StartTagToken && token.Data == "a" {
-			for _, attr := range token.Attr {
-				if attr.Key == "href" {
-					check := len(attr.Val) > 0 && !strings.HasPrefix(attr.Val, "http")
-					check = check && !strings.HasPrefix(attr.Val, "#")
-					check = check && !strings.HasPrefix(attr.Val, "..")
-					check = check && strings.HasSuffix(attr.Val, ".html")
-					if check {
-						links[url+"/"+attr.Val] = struct{}{}
-					}
-				}
+		var r io.Reader = bytes.NewReader(data)
+		if !utf8.Valid(data) {
+			r, err = charset.NewReaderLabel("euc-jp", r)
+			if err != nil {
+				return nil, err
 			}
 		}
-	}
-
-	for nextURL := range links {
-		fc.routine(ctx, nextURL, layer-1)
-	}
-}
 
-func newKanjiKanaScraper(rootURL string, options ...Option) (*kanjiKanaFrequencyCounter, error) {
-
-	var opts scraperOptions
-	for _, opt := range options {
-		err := opt(&opts)
+		if kf == nil {
+			kf, err = kanjikana.ParseKradfile(r)
+		} else {
+			err = kf.Parse(r)
+		}
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("%s: %w", path, err)
 		}
 	}
+	return kf, nil
+}
 
-	if !validateURL(rootURL) {
-		rootURL = defaultURL
-		if opts.loggingMode {
-			log.Printf("invalid URL: setting to default URL: %s\n", rootURL)
-		}
+// openDictionary opens a dictionary file, decompressing it when its name
+// ends in .gz, as the EDRDG files are distributed.
+func openDictionary(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
 	}
-
-	var searchDepth int
-	if opts.searchDepth == nil {
-		searchDepth = defaultSearchDepth
-	} else {
-		searchDepth = *opts.searchDepth
+	if !strings.HasSuffix(path, ".gz") {
+		return f, nil
 	}
-
-	if opts.loggingMode {
-		log.Printf("search depth set to %v\n", searchDepth)
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
 	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{gz, f}, nil
+}
 
-	frequencyCounter := &kanjiKanaFrequencyCounter{
-		kanjis:    make(map[string]int),
-		katakanas: make(map[string]int),
-		hiraganas: make(map[string]int),
+func parseCrawlStrategy(name string) (kanjikana.CrawlStrategy, error) {
+	switch strings.ToLower(name) {
+	case "bfs":
+		return kanjikana.BFS, nil
+	case "dfs":
+		return kanjikana.DFS, nil
+	case "best":
+		return kanjikana.BestFirst, nil
+	default:
+		return 0, fmt.Errorf("unknown crawl strategy: %s", name)
 	}
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	frequencyCounter.routine(ctx, rootURL, *opts.searchDepth)
-
-	<-ctx.Done()
-
-	frequencyCounter.uniqueCount += len(frequencyCounter.kanjis)
-	frequencyCounter.uniqueCount += len(frequencyCounter.katakanas)
-	frequencyCounter.uniqueCount += len(frequencyCounter.hiraganas)
-
-	frequencyCounter.kanjiUniqueCount = len(frequencyCounter.kanjis)
-	frequencyCounter.katakanaUniqueCount = len(frequencyCounter.katakanas)
-	frequencyCounter.hiraganaUniqueCount = len(frequencyCounter.hiraganas)
-
-	kanas := make(map[string]struct{})
-	for c := range frequencyCounter.katakanas {
-		kanas[c] = struct{}{}
+// terminalWidth returns the width of the terminal w writes to, or of $COLUMNS
+// or 80 columns when w is not a terminal.
+func terminalWidth(w io.Writer) int {
+	if f, ok := w.(*os.File); ok {
+		if width, _, err := term.GetSize(int(f.Fd())); err == nil && width > 0 {
+			return width
+		}
 	}
-
-	for c := range frequencyCounter.hiraganas {
-		kanas[c] = struct{}{}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
 	}
-
-	frequencyCounter.kanaUniqueCount = len(kanas)
-
-	return frequencyCounter, nil
+	return 80
 }
 
-func WithSearchDepth(depth int) Option {
-	return func(opts *scraperOptions) error {
-		if depth < 0 {
-			return errors.New("search depth should be positive")
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
 		}
-		if depth >= maxSearchDepth {
-			return errors.New("search depth exceeds default maximum depth")
-		}
-		opts.searchDepth = &depth
-		return nil
-	}
+	})
+	return set
 }
 
-func WithLogging() Option {
-	return func(opts *scraperOptions) error {
-		opts.loggingMode = true
-		log.Println("logging mode is set")
-		return nil
+// isStdinPiped reports whether stdin is redirected from a file or a pipe
+// rather than attached to a terminal.
+func isStdinPiped() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
 	}
-}
-
-func validateURL(url string) bool {
-	// TODO weak test that needs to be improved
-	return strings.HasPrefix(url, "http") && strings.Count(url, "://www.") == 1
+	return info.Mode()&os.ModeCharDevice == 0
 }
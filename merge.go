@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+)
+
+const mergeCommand = "merge"
+
+func runMerge(args []string) {
+	fs := flag.NewFlagSet(mergeCommand, flag.ExitOnError)
+	rankingSize := fs.Int("ranksize", defaultRankingSize, "ranking size")
+	outputFormat := fs.String("output", textOutput, "output format (text, json, csv, tsv)")
+	outputFile := fs.String("outfile", "", "write output to file instead of stdout")
+	var lf logFlags
+	lf.register(fs)
+	setCommandUsage(fs, "merge [flags] result...", "Sum the counts of results saved with -output json or -db (their latest crawl) into one result.")
+	fs.Parse(args)
+	lf.setup(os.Stderr)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if _, ok := outputFormats[*outputFormat]; !ok {
+		fatalf("unknown output format: %s", *outputFormat)
+	}
+
+	res, err := readResultFile(fs.Arg(0), 0)
+	if err != nil {
+		fatal(err)
+	}
+	for _, path := range fs.Args()[1:] {
+		other, err := readResultFile(path, 0)
+		if err != nil {
+			fatal(err)
+		}
+		if err := res.Merge(other); err != nil {
+			fatalf("%s: %v", path, err)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *outputFile != "" {
+		f, err := os.Create(*outputFile)
+		if err != nil {
+			fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	rep := &report{res: res, rankingSize: *rankingSize}
+	if err := writeResult(w, *outputFormat, rep); err != nil {
+		fatal(err)
+	}
+}
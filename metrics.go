@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+)
+
+// crawlDurationBuckets are the upper bounds, in seconds, of the buckets of
+// the crawl duration histogram.
+var crawlDurationBuckets = []float64{1, 5, 10, 30, 60, 300, 600, 1800}
+
+// metrics are the counters of the serve command, exposed at /metrics in the
+// Prometheus text format.
+type metrics struct {
+	mu           sync.Mutex
+	pagesFetched int
+	fetchErrors  int
+	// bytesDecoded counts the bytes of the page bodies, after their
+	// Content-Encoding is decoded.
+	bytesDecoded int
+	// characters counts the characters of every category counted by the
+	// crawls and the /count endpoint.
+	characters map[string]int
+	// crawlDurations counts the crawls of at most each bucket's duration.
+	crawlDurations []int
+	crawls         int
+	crawlSeconds   float64
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		characters:     make(map[string]int),
+		crawlDurations: make([]int, len(crawlDurationBuckets)),
+	}
+}
+
+// observeFetch is a kanjikana fetch observer.
+func (m *metrics) observeFetch(pageURL string, size int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.fetchErrors++
+		return
+	}
+	m.pagesFetched++
+	m.bytesDecoded += size
+}
+
+// observeResult adds the characters of res.
+func (m *metrics) observeResult(res *kanjikana.Result) {
+	if res == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	categories := []struct {
+		name   string
+		counts map[string]int
+	}{
+		{kanjikana.CategoryKanji, res.Kanjis},
+		{kanjikana.CategoryHiragana, res.Hiraganas},
+		{kanjikana.CategoryKatakana, res.Katakanas},
+	}
+	for _, category := range categories {
+		for _, count := range category.counts {
+			m.characters[category.name] += count
+		}
+	}
+}
+
+// observeCrawl records the duration of a crawl.
+func (m *metrics) observeCrawl(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seconds := d.Seconds()
+	for i, bound := range crawlDurationBuckets {
+		if seconds <= bound {
+			m.crawlDurations[i]++
+		}
+	}
+	m.crawls++
+	m.crawlSeconds += seconds
+}
+
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.writeTo(w)
+}
+
+func (m *metrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP kanjikana_pages_fetched_total Pages fetched by crawls.")
+	fmt.Fprintln(w, "# TYPE kanjikana_pages_fetched_total counter")
+	fmt.Fprintln(w, "kanjikana_pages_fetched_total", m.pagesFetched)
+
+	fmt.Fprintln(w, "# HELP kanjikana_fetch_errors_total Page fetches that failed.")
+	fmt.Fprintln(w, "# TYPE kanjikana_fetch_errors_total counter")
+	fmt.Fprintln(w, "kanjikana_fetch_errors_total", m.fetchErrors)
+
+	fmt.Fprintln(w, "# HELP kanjikana_bytes_decoded_total Bytes of the pages fetched by crawls, after decompression.")
+	fmt.Fprintln(w, "# TYPE kanjikana_bytes_decoded_total counter")
+	fmt.Fprintln(w, "kanjikana_bytes_decoded_total", m.bytesDecoded)
+
+	fmt.Fprintln(w, "# HELP kanjikana_characters_total Japanese characters counted, by category.")
+	fmt.Fprintln(w, "# TYPE kanjikana_characters_total counter")
+	for _, category := range []string{kanjikana.CategoryKanji, kanjikana.CategoryHiragana, kanjikana.CategoryKatakana} {
+		fmt.Fprintf(w, "kanjikana_characters_total{category=%q} %d\n", category, m.characters[category])
+	}
+
+	fmt.Fprintln(w, "# HELP kanjikana_crawl_duration_seconds Duration of the crawls.")
+	fmt.Fprintln(w, "# TYPE kanjikana_crawl_duration_seconds histogram")
+	for i, bound := range crawlDurationBuckets {
+		fmt.Fprintf(w, "kanjikana_crawl_duration_seconds_bucket{le=\"%g\"} %d\n", bound, m.crawlDurations[i])
+	}
+	fmt.Fprintf(w, "kanjikana_crawl_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.crawls)
+	fmt.Fprintf(w, "kanjikana_crawl_duration_seconds_sum %g\n", m.crawlSeconds)
+	fmt.Fprintf(w, "kanjikana_crawl_duration_seconds_count %d\n", m.crawls)
+}
@@ -0,0 +1,1049 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gojp/kana"
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+)
+
+const (
+	textOutput = "text"
+	jsonOutput = "json"
+	csvOutput  = "csv"
+	tsvOutput  = "tsv"
+)
+
+var outputFormats = map[string]struct{}{
+	textOutput: {},
+	jsonOutput: {},
+	csvOutput:  {},
+	tsvOutput:  {},
+}
+
+// report is a result along with the annotations requested on the command
+// line.
+type report struct {
+	res         *kanjikana.Result
+	rankingSize int
+	jlpt        *kanjikana.KanjiLevels
+	grades      *kanjikana.KanjiLevels
+	kanjidic    *kanjikana.Kanjidic
+	jmdict      *kanjikana.JMdict
+	kradfile    *kanjikana.Kradfile
+	readings    *kanjikana.ReadingTable
+	joyo        bool
+	strokes     bool
+	chapters    bool
+	coverage    bool
+	// distribution enables the statistics of the kanji distribution.
+	distribution bool
+	// blocks enables the counts by Unicode block.
+	blocks bool
+	// wanikani holds the kanji learned on WaniKani.
+	wanikani map[string]bool
+	// histogramWidth is the width of the text ranking lines when they end
+	// with a bar chart, 0 when histograms are disabled.
+	histogramWidth int
+	// color enables ANSI colors in the text output.
+	color bool
+}
+
+func writeResult(w io.Writer, format string, rep *report) error {
+	switch format {
+	case textOutput:
+		writeText(w, rep)
+		return nil
+	case jsonOutput:
+		return writeJSON(w, rep)
+	case csvOutput:
+		return writeCSV(w, ',', rep)
+	case tsvOutput:
+		return writeCSV(w, '\t', rep)
+	default:
+		return fmt.Errorf("unknown output format: %s", format)
+	}
+}
+
+// kanjiColumns returns the names of the extra columns describing a kanji.
+func (rep *report) kanjiColumns() []string {
+	var columns []string
+	if rep.readings != nil {
+		columns = append(columns, "reading")
+	}
+	if rep.kanjidic != nil {
+		columns = append(columns, "on", "kun", "meaning")
+	}
+	if rep.strokes {
+		columns = append(columns, "strokes")
+	}
+	if rep.jlpt != nil {
+		columns = append(columns, "jlpt")
+	}
+	if rep.grades != nil {
+		columns = append(columns, "grade")
+	}
+	if rep.wanikani != nil {
+		columns = append(columns, "wanikani")
+	}
+	return columns
+}
+
+// kanjiValues returns the values of the extra columns describing c. It
+// returns empty values for kana.
+func (rep *report) kanjiValues(c string) []string {
+	var values []string
+	if rep.readings != nil {
+		values = append(values, rep.readings.Reading(c))
+	}
+	if rep.kanjidic != nil {
+		if info := rep.kanjidic.Lookup(c); info != nil {
+			values = append(values,
+				strings.Join(info.OnReadings, " "),
+				strings.Join(info.KunReadings, " "),
+				strings.Join(info.Meanings, "; "))
+		} else {
+			values = append(values, "", "", "")
+		}
+	}
+	if rep.strokes {
+		strokes := ""
+		if info := rep.kanjidic.Lookup(c); info != nil && info.StrokeCount > 0 {
+			strokes = strconv.Itoa(info.StrokeCount)
+		}
+		values = append(values, strokes)
+	}
+	if rep.jlpt != nil {
+		values = append(values, rep.jlpt.Level(c))
+	}
+	if rep.grades != nil {
+		values = append(values, rep.grades.Level(c))
+	}
+	if rep.wanikani != nil {
+		values = append(values, wanikaniStatus(rep.wanikani[c]))
+	}
+	return values
+}
+
+// describeKanji returns the annotations shown next to c in the text ranking.
+func (rep *report) describeKanji(c string) string {
+	var parts []string
+	if rep.readings != nil {
+		if reading := rep.readings.Reading(c); reading != "" {
+			parts = append(parts, reading, kana.KanaToRomaji(reading))
+		}
+	}
+	if rep.kanjidic != nil {
+		if info := rep.kanjidic.Lookup(c); info != nil {
+			parts = append(parts, describeKanjiInfo(info)...)
+			if rep.strokes && info.StrokeCount > 0 {
+				parts = append(parts, fmt.Sprintf("[%d strokes]", info.StrokeCount))
+			}
+		}
+	}
+	if rep.jlpt != nil {
+		if level := rep.jlpt.Level(c); level != "" {
+			parts = append(parts, "["+level+"]")
+		}
+	}
+	if rep.grades != nil {
+		if level := rep.grades.Level(c); level != "" {
+			parts = append(parts, "["+level+"]")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// describeKanjiInfo returns the main on and kun readings of a kanji, e.g.
+// "ニチ/ひ", and its first meanings, e.g. "(day, sun)".
+func describeKanjiInfo(info *kanjikana.KanjiInfo) []string {
+	const maxMeanings = 2
+
+	var parts []string
+	var readings []string
+	if len(info.OnReadings) > 0 {
+		readings = append(readings, info.OnReadings[0])
+	}
+	if len(info.KunReadings) > 0 {
+		readings = append(readings, info.KunReadings[0])
+	}
+	if len(readings) > 0 {
+		parts = append(parts, strings.Join(readings, "/"))
+	}
+	if len(info.Meanings) > 0 {
+		meanings := info.Meanings[:min(len(info.Meanings), maxMeanings)]
+		parts = append(parts, "("+strings.Join(meanings, ", ")+")")
+	}
+	return parts
+}
+
+// wordColumns returns the names of the extra columns describing a word.
+func (rep *report) wordColumns() []string {
+	if rep.jmdict == nil {
+		return nil
+	}
+	return []string{"reading", "gloss", "common"}
+}
+
+// wordValues returns the values of the extra columns describing word.
+func (rep *report) wordValues(word string) []string {
+	if rep.jmdict == nil {
+		return nil
+	}
+	info := rep.jmdict.Lookup(word)
+	if info == nil {
+		return []string{"", "", ""}
+	}
+	return []string{
+		strings.Join(info.Readings, " "),
+		strings.Join(info.Glosses, "; "),
+		strconv.FormatBool(info.Common),
+	}
+}
+
+// describeWord returns the annotations shown next to word in the text
+// ranking, e.g. "せいふ (government; administration) [common]".
+func (rep *report) describeWord(word string) string {
+	const maxGlosses = 3
+
+	if rep.jmdict == nil {
+		return ""
+	}
+	info := rep.jmdict.Lookup(word)
+	if info == nil {
+		return ""
+	}
+
+	var parts []string
+	if len(info.Readings) > 0 && info.Readings[0] != word {
+		parts = append(parts, info.Readings[0])
+	}
+	if len(info.Glosses) > 0 {
+		glosses := info.Glosses[:min(len(info.Glosses), maxGlosses)]
+		parts = append(parts, "("+strings.Join(glosses, "; ")+")")
+	}
+	if info.Common {
+		parts = append(parts, "[common]")
+	}
+	return strings.Join(parts, " ")
+}
+
+func writeJSON(w io.Writer, rep *report) error {
+	data, err := json.Marshal(rep.res)
+	if err != nil {
+		return err
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	sections := make(map[string]any)
+	if rep.kanjidic != nil {
+		entries := make(map[string]*kanjikana.KanjiInfo)
+		for c := range rep.res.Kanjis {
+			if info := rep.kanjidic.Lookup(c); info != nil {
+				entries[c] = info
+			}
+		}
+		sections["kanjidic"] = entries
+	}
+	if rep.jmdict != nil {
+		entries := make(map[string]*kanjikana.WordInfo)
+		for word := range rep.res.Words {
+			if info := rep.jmdict.Lookup(word); info != nil {
+				entries[word] = info
+			}
+		}
+		sections["jmdict"] = entries
+	}
+	if rep.jlpt != nil {
+		sections["jlpt"] = newLevelsSection(rep.jlpt, rep.res.Kanjis)
+	}
+	if rep.grades != nil {
+		sections["grades"] = newLevelsSection(rep.grades, rep.res.Kanjis)
+	}
+	if rep.strokes {
+		sections["strokes"] = rep.kanjidic.StrokeHistogram(rep.res.Kanjis)
+	}
+	if rep.kradfile != nil {
+		components := kanjikana.ComponentRanking(rep.kradfile.CountComponents(rep.res.Kanjis))
+		for i := range components {
+			components[i].PerThousand = kanjikana.PerThousand(components[i].Count, rep.res.AllCharactersCount)
+		}
+		sections["components"] = components
+	}
+	if rep.wanikani != nil {
+		learned, unknown := splitLearned(kanjikana.MostCommonCharacters(rep.res.Kanjis), rep.wanikani)
+		sections["wanikani"] = struct {
+			Learned []string `json:"learned"`
+			Unknown []string `json:"unknown"`
+		}{learned, unknown}
+	}
+	if rep.distribution {
+		sections["distribution"] = kanjikana.Distribution(rep.res.Kanjis)
+	}
+	if rep.blocks {
+		blocks := kanjikana.BlockCounts(rep.res.Kanjis, rep.res.Hiraganas, rep.res.Katakanas)
+		if blocks == nil {
+			blocks = []kanjikana.BlockCount{}
+		}
+		sections["blocks"] = blocks
+	}
+	if rep.coverage {
+		sections["coverage"] = newCoverageSection(kanjikana.Coverage(rep.res.Kanjis))
+	}
+	if rep.joyo {
+		joyo := kanjikana.JoyoKanji()
+		unseen := joyo.Unseen(rep.res.Kanjis)
+		if unseen == nil {
+			unseen = []string{}
+		}
+		sections["joyo"] = struct {
+			kanjikana.LevelStats
+			Unseen []string `json:"unseen"`
+		}{joyo.Stats(rep.res.Kanjis)[0], unseen}
+	}
+
+	for key, section := range sections {
+		raw, err := json.Marshal(section)
+		if err != nil {
+			return err
+		}
+		doc[key] = raw
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// splitLearned splits the ranked kanji into the ones learned on WaniKani and
+// the others, keeping their order.
+func splitLearned(ranking []string, learned map[string]bool) ([]string, []string) {
+	known, unknown := []string{}, []string{}
+	for _, c := range ranking {
+		if learned[c] {
+			known = append(known, c)
+		} else {
+			unknown = append(unknown, c)
+		}
+	}
+	return known, unknown
+}
+
+func wanikaniStatus(learned bool) string {
+	if learned {
+		return "learned"
+	}
+	return "unknown"
+}
+
+// levelsSection is the JSON section describing how the kanji counts of a
+// result fall into the levels of a classification.
+type levelsSection struct {
+	Levels []kanjikana.LevelStats `json:"levels"`
+	Kanjis map[string]string      `json:"kanjis"`
+}
+
+func newLevelsSection(kl *kanjikana.KanjiLevels, m map[string]int) levelsSection {
+	levels := make(map[string]string)
+	for c := range m {
+		if level := kl.Level(c); level != "" {
+			levels[c] = level
+		}
+	}
+	return levelsSection{Levels: kl.Stats(m), Kanjis: levels}
+}
+
+func writeCSV(w io.Writer, comma rune, rep *report) error {
+	res := rep.res
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+
+	kanjiColumns := rep.kanjiColumns()
+	wordColumns := rep.wordColumns()
+	header := []string{"character", "category", "count", "per_thousand", "rank", "romaji"}
+	if rep.coverage {
+		header = append(header, "coverage")
+	}
+	header = append(header, kanjiColumns...)
+	header = append(header, wordColumns...)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	perThousand := func(count int) string {
+		return strconv.FormatFloat(kanjikana.PerThousand(count, res.AllCharactersCount), 'f', 4, 64)
+	}
+	// coverageValue returns the coverage column of the i-th row of a
+	// category, the cumulative percentage of its occurrences.
+	coverageValue := func(coverage []float64, i int) []string {
+		if !rep.coverage {
+			return nil
+		}
+		return []string{strconv.FormatFloat(coverage[i], 'f', 4, 64)}
+	}
+	emptyKanjiValues := make([]string, len(kanjiColumns))
+	emptyWordValues := make([]string, len(wordColumns))
+
+	categories := []struct {
+		name string
+		m    map[string]int
+	}{
+		{kanjikana.CategoryKanji, res.Kanjis},
+		{kanjikana.CategoryHiragana, res.Hiraganas},
+		{kanjikana.CategoryKatakana, res.Katakanas},
+		{kanjikana.CategoryPunctuation, res.Punctuation},
+	}
+
+	for _, category := range categories {
+		ranking := kanjikana.Ranking(category.m)
+		coverage := kanjikana.Coverage(category.m)
+		for i := 0; i < min(len(ranking), rep.rankingSize); i++ {
+			record := []string{
+				ranking[i].Character,
+				category.name,
+				strconv.Itoa(ranking[i].Count),
+				perThousand(ranking[i].Count),
+				strconv.Itoa(i + 1),
+				ranking[i].Romaji,
+			}
+			record = append(record, coverageValue(coverage, i)...)
+			if category.name == kanjikana.CategoryKanji {
+				record = append(record, rep.kanjiValues(ranking[i].Character)...)
+			} else {
+				record = append(record, emptyKanjiValues...)
+			}
+			record = append(record, emptyWordValues...)
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	wordCoverage := kanjikana.Coverage(res.Words)
+	for i, word := range kanjikana.WordRanking(res.Words) {
+		if i >= rep.rankingSize {
+			break
+		}
+		record := []string{word.Word, kanjikana.CategoryWord, strconv.Itoa(word.Count), perThousand(word.Count), strconv.Itoa(i + 1), ""}
+		record = append(record, coverageValue(wordCoverage, i)...)
+		record = append(record, emptyKanjiValues...)
+		record = append(record, rep.wordValues(word.Word)...)
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	compoundCoverage := kanjikana.Coverage(res.Compounds)
+	for i, compound := range kanjikana.CompoundRanking(res.Compounds) {
+		if i >= rep.rankingSize {
+			break
+		}
+		record := []string{compound.Compound, kanjikana.CategoryCompound, strconv.Itoa(compound.Count), perThousand(compound.Count), strconv.Itoa(i + 1), ""}
+		record = append(record, coverageValue(compoundCoverage, i)...)
+		record = append(record, emptyKanjiValues...)
+		record = append(record, rep.wordValues(compound.Compound)...)
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	ngramCoverage := kanjikana.Coverage(res.NGrams)
+	for i, ngram := range kanjikana.NGramRanking(res.NGrams) {
+		if i >= rep.rankingSize {
+			break
+		}
+		record := []string{ngram.NGram, kanjikana.CategoryNGram, strconv.Itoa(ngram.Count), perThousand(ngram.Count), strconv.Itoa(i + 1), ""}
+		record = append(record, coverageValue(ngramCoverage, i)...)
+		record = append(record, emptyKanjiValues...)
+		record = append(record, emptyWordValues...)
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	if rep.kradfile != nil {
+		components := rep.kradfile.CountComponents(res.Kanjis)
+		componentCoverage := kanjikana.Coverage(components)
+		for i, component := range kanjikana.ComponentRanking(components) {
+			if i >= rep.rankingSize {
+				break
+			}
+			record := []string{component.Component, kanjikana.CategoryComponent, strconv.Itoa(component.Count), perThousand(component.Count), strconv.Itoa(i + 1), ""}
+			record = append(record, coverageValue(componentCoverage, i)...)
+			record = append(record, emptyKanjiValues...)
+			record = append(record, emptyWordValues...)
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeText(w io.Writer, rep *report) {
+	res := rep.res
+	rankingSize := rep.rankingSize
+
+	mostCommonKanjis := kanjikana.MostCommonCharacters(res.Kanjis)
+	mostCommonKatakana := kanjikana.MostCommonCharacters(res.Katakanas)
+	mostCommonHiragana := kanjikana.MostCommonCharacters(res.Hiraganas)
+
+	fmt.Fprintln(w, "All Japanese characters found:", res.AllCharactersCount)
+	if res.SkippedChineseCount > 0 {
+		fmt.Fprintln(w, "Kanji skipped as Chinese:", res.SkippedChineseCount)
+	}
+	if res.NonJapanesePages > 0 {
+		fmt.Fprintln(w, "Pages skipped below the Japanese ratio:", res.NonJapanesePages)
+	}
+	if res.DuplicatePages > 0 {
+		fmt.Fprintln(w, "Duplicate pages skipped:", res.DuplicatePages)
+	}
+	if res.RepeatedBlocks > 0 {
+		fmt.Fprintln(w, "Repeated text blocks skipped:", res.RepeatedBlocks)
+	}
+	if res.Sentences != nil {
+		printSentences(w, *res.Sentences)
+	}
+	fmt.Fprintln(w, "Kanji unique count:", res.KanjiUniqueCount)
+
+	kanjiRankingSize := min(res.KanjiUniqueCount, rankingSize)
+	if res.KanjiUniqueCount > 0 {
+		fmt.Fprintln(w, kanjiRankingSize, "most common Kanji characters:")
+		printCharactersRanking(w, rep, res.Kanjis, mostCommonKanjis, kanjiRankingSize)
+	}
+
+	if rep.wanikani != nil {
+		learned, unknown := splitLearned(mostCommonKanjis, rep.wanikani)
+		if size := min(len(unknown), rankingSize); size > 0 {
+			fmt.Fprintln(w, size, "most common Kanji characters unknown to you on WaniKani:")
+			printCharactersRanking(w, rep, res.Kanjis, unknown, size)
+		}
+		if size := min(len(learned), rankingSize); size > 0 {
+			fmt.Fprintln(w, size, "most common Kanji characters already learned on WaniKani:")
+			printCharactersRanking(w, rep, res.Kanjis, learned, size)
+		}
+	}
+
+	if rep.jlpt != nil {
+		printLevelStats(w, "JLPT", rep.jlpt.Stats(res.Kanjis))
+	}
+
+	if rep.grades != nil {
+		printLevelStats(w, "School grade", rep.grades.Stats(res.Kanjis))
+	}
+
+	if rep.joyo {
+		printJoyoCoverage(w, res.Kanjis)
+	}
+
+	if rep.strokes {
+		printStrokeHistogram(w, rep.kanjidic.StrokeHistogram(res.Kanjis))
+	}
+
+	if rep.coverage && res.KanjiUniqueCount > 0 {
+		printKanjiCoverage(w, kanjikana.Coverage(res.Kanjis))
+	}
+
+	if rep.distribution && res.KanjiUniqueCount > 0 {
+		printDistribution(w, "Kanji", kanjikana.Distribution(res.Kanjis))
+	}
+
+	if rep.blocks && res.AllCharactersCount > 0 {
+		printBlocks(w, kanjikana.BlockCounts(res.Kanjis, res.Hiraganas, res.Katakanas), res.AllCharactersCount)
+	}
+
+	if rep.chapters && len(res.Chapters) > 0 {
+		printChapters(w, res.Chapters)
+	}
+
+	if rep.kradfile != nil {
+		componentRanking := kanjikana.ComponentRanking(rep.kradfile.CountComponents(res.Kanjis))
+		componentRankingSize := min(len(componentRanking), rankingSize)
+		if componentRankingSize > 0 {
+			fmt.Fprintln(w, componentRankingSize, "most common kanji components:")
+			lines := make([]rankingLine, componentRankingSize)
+			for i := range lines {
+				lines[i] = rankingLine{label: componentRanking[i].Component, count: componentRanking[i].Count, color: rep.lineColor(kanjikana.CategoryComponent, componentRanking[i].Component)}
+			}
+			printRanking(w, rep, lines)
+		}
+	}
+
+	fmt.Fprintln(w, "Kana unique count:", res.KanaUniqueCount)
+	fmt.Fprintln(w, "Katakana unique count:", res.KatakanaUniqueCount)
+	fmt.Fprintln(w, "Hiragana unique count:", res.HiraganaUniqueCount)
+
+	katakanaRankingSize := min(res.KatakanaUniqueCount, rankingSize)
+	if res.KatakanaUniqueCount > 0 {
+		fmt.Fprintln(w, katakanaRankingSize, "most common Katakana characters:")
+		printCharactersRanking(w, rep, res.Katakanas, mostCommonKatakana, katakanaRankingSize)
+	}
+
+	hiraganaRankingSize := min(res.HiraganaUniqueCount, rankingSize)
+	if res.HiraganaUniqueCount > 0 {
+		fmt.Fprintln(w, hiraganaRankingSize, "most common Hiragana characters:")
+		printCharactersRanking(w, rep, res.Hiraganas, mostCommonHiragana, hiraganaRankingSize)
+	}
+
+	if res.Punctuation != nil {
+		fmt.Fprintln(w, "Punctuation and symbol unique count:", len(res.Punctuation))
+		punctuationRanking := kanjikana.MostCommonCharacters(res.Punctuation)
+		punctuationRankingSize := min(len(punctuationRanking), rankingSize)
+		if punctuationRankingSize > 0 {
+			fmt.Fprintln(w, punctuationRankingSize, "most common punctuation marks and symbols:")
+			lines := make([]rankingLine, punctuationRankingSize)
+			for i := range lines {
+				c := punctuationRanking[i]
+				lines[i] = rankingLine{label: c, count: res.Punctuation[c], color: rep.lineColor(kanjikana.CategoryPunctuation, c)}
+			}
+			printRanking(w, rep, lines)
+		}
+	}
+
+	if res.Words != nil {
+		fmt.Fprintln(w, "Word unique count:", len(res.Words))
+		wordRanking := kanjikana.WordRanking(res.Words)
+		wordRankingSize := min(len(wordRanking), rankingSize)
+		if wordRankingSize > 0 {
+			fmt.Fprintln(w, wordRankingSize, "most common words:")
+			lines := make([]rankingLine, wordRankingSize)
+			for i := range lines {
+				lines[i] = rankingLine{label: wordRanking[i].Word, count: wordRanking[i].Count, color: rep.lineColor(kanjikana.CategoryWord, wordRanking[i].Word)}
+				if description := rep.describeWord(wordRanking[i].Word); description != "" {
+					lines[i].label += " " + description
+				}
+			}
+			printRanking(w, rep, lines)
+		}
+	}
+
+	if res.Compounds != nil {
+		fmt.Fprintln(w, "Kanji compound unique count:", len(res.Compounds))
+		compoundRanking := kanjikana.CompoundRanking(res.Compounds)
+		compoundRankingSize := min(len(compoundRanking), rankingSize)
+		if compoundRankingSize > 0 {
+			fmt.Fprintln(w, compoundRankingSize, "most common kanji compounds:")
+			lines := make([]rankingLine, compoundRankingSize)
+			for i := range lines {
+				lines[i] = rankingLine{label: compoundRanking[i].Compound, count: compoundRanking[i].Count, color: rep.lineColor(kanjikana.CategoryCompound, compoundRanking[i].Compound)}
+				if description := rep.describeWord(compoundRanking[i].Compound); description != "" {
+					lines[i].label += " " + description
+				}
+			}
+			printRanking(w, rep, lines)
+		}
+	}
+
+	if res.Readings != nil {
+		printReadingCounts(w, kanjikana.ReadingRanking(res.Readings), rankingSize)
+	}
+
+	if res.PartsOfSpeech != nil {
+		printPartsOfSpeech(w, kanjikana.PartOfSpeechRanking(res.PartsOfSpeech))
+	}
+
+	if res.NGrams != nil {
+		fmt.Fprintf(w, "%d-gram unique count: %d\n", res.NGramSize, len(res.NGrams))
+		ngramRanking := kanjikana.NGramRanking(res.NGrams)
+		ngramRankingSize := min(len(ngramRanking), rankingSize)
+		if ngramRankingSize > 0 {
+			fmt.Fprintf(w, "%d most common %d-grams:\n", ngramRankingSize, res.NGramSize)
+			lines := make([]rankingLine, ngramRankingSize)
+			for i := range lines {
+				lines[i] = rankingLine{label: ngramRanking[i].NGram, count: ngramRanking[i].Count, color: rep.lineColor(kanjikana.CategoryNGram, ngramRanking[i].NGram)}
+			}
+			printRanking(w, rep, lines)
+		}
+	}
+
+	if len(res.Roots) > 0 {
+		printRoots(w, rep)
+	}
+	if len(res.Domains) > 0 {
+		printDomains(w, rep)
+	}
+}
+
+// printRoots prints a summary of every website of a crawl of several root
+// URLs, followed by the kanji ranking of each.
+func printRoots(w io.Writer, rep *report) {
+	pages := make(map[string]int, len(rep.res.Roots))
+	for root, res := range rep.res.Roots {
+		pages[root] = len(res.Pages)
+	}
+	printBreakdown(w, rep, "website", rep.res.Roots, pages)
+}
+
+// printDomains prints a summary of every host reached by a crawl, followed
+// by the kanji ranking of each.
+func printDomains(w io.Writer, rep *report) {
+	pages := make(map[string]int)
+	for _, page := range rep.res.Pages {
+		if u, err := url.Parse(page.URL); err == nil {
+			pages[strings.ToLower(u.Hostname())]++
+		}
+	}
+	printBreakdown(w, rep, "domain", rep.res.Domains, pages)
+}
+
+// printBreakdown prints a summary of the results of every key, as a domain,
+// from the one with the most characters, followed by the kanji ranking of
+// each.
+func printBreakdown(w io.Writer, rep *report, key string, results map[string]*kanjikana.Result, pages map[string]int) {
+	characters := make(map[string]int, len(results))
+	for k, res := range results {
+		characters[k] = res.AllCharactersCount
+	}
+	keys := kanjikana.MostCommonCharacters(characters)
+
+	fmt.Fprintf(w, "Results by %s:\n", key)
+	fmt.Fprintf(w, "%-30s %6s %10s %6s %8s\n", key, "pages", "characters", "kanji", "kanji %")
+	for _, k := range keys {
+		res := results[k]
+		kanjis := 0
+		for _, count := range res.Kanjis {
+			kanjis += count
+		}
+		kanjiShare := 0.0
+		if res.AllCharactersCount > 0 {
+			kanjiShare = 100 * float64(kanjis) / float64(res.AllCharactersCount)
+		}
+		fmt.Fprintf(w, "%-30s %6d %10d %6d %7.1f%%\n", k, pages[k], res.AllCharactersCount, res.KanjiUniqueCount, kanjiShare)
+	}
+	fmt.Fprintln(w)
+
+	for _, k := range keys {
+		keyRep := *rep
+		keyRep.res = results[k]
+		mostCommon := kanjikana.MostCommonCharacters(keyRep.res.Kanjis)
+		if size := min(len(mostCommon), rep.rankingSize); size > 0 {
+			fmt.Fprintln(w, size, "most common Kanji characters on", k+":")
+			printCharactersRanking(w, &keyRep, keyRep.res.Kanjis, mostCommon, size)
+		}
+	}
+}
+
+// chapterPreviewSize is the number of new kanji listed for each chapter.
+const chapterPreviewSize = 10
+
+// printChapters prints, for each chapter of a book, its character counts and
+// the kanji it introduces, most frequent first.
+func printChapters(w io.Writer, chapters []kanjikana.ChapterStats) {
+	fmt.Fprintln(w, "Kanji introduced by chapter:")
+	fmt.Fprintf(w, "%7s %10s %6s %5s %6s  %s\n", "chapter", "characters", "kanji", "new", "total", "title")
+	total := 0
+	for i, chapter := range chapters {
+		total += len(chapter.NewKanjis)
+		fmt.Fprintf(w, "%7d %10d %6d %5d %6d  %s\n", i+1, chapter.AllCharactersCount, chapter.KanjiUniqueCount, len(chapter.NewKanjis), total, chapter.Title)
+		if len(chapter.NewKanjis) > 0 {
+			preview := strings.Join(chapter.NewKanjis[:min(len(chapter.NewKanjis), chapterPreviewSize)], "")
+			if len(chapter.NewKanjis) > chapterPreviewSize {
+				preview += "…"
+			}
+			fmt.Fprintf(w, "%40s%s\n", "", preview)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// partOfSpeechPreviewSize is the number of words listed for each part of
+// speech.
+const partOfSpeechPreviewSize = 8
+
+// printPartsOfSpeech prints the share of the words of every part of speech
+// and their most common words.
+func printPartsOfSpeech(w io.Writer, ranking []kanjikana.PartOfSpeechFrequency) {
+	if len(ranking) == 0 {
+		return
+	}
+	total := 0
+	for _, f := range ranking {
+		total += f.Count
+	}
+	fmt.Fprintln(w, "Words by part of speech:")
+	for _, f := range ranking {
+		words := make([]string, min(len(f.Words), partOfSpeechPreviewSize))
+		for i := range words {
+			words[i] = f.Words[i].Word
+		}
+		preview := strings.Join(words, " ")
+		if len(f.Words) > partOfSpeechPreviewSize {
+			preview += " …"
+		}
+		fmt.Fprintf(w, "%-13s %7d %5.1f%%  %s\n", f.PartOfSpeech, f.Count, 100*float64(f.Count)/float64(total), preview)
+	}
+	fmt.Fprintln(w)
+}
+
+// printReadingCounts prints the readings of the rankingSize kanji read the
+// most often, with their share of the readings of the kanji.
+func printReadingCounts(w io.Writer, ranking []kanjikana.ReadingFrequency, rankingSize int) {
+	size := min(len(ranking), rankingSize)
+	if size == 0 {
+		return
+	}
+	fmt.Fprintln(w, size, "kanji read most often, by reading:")
+	for i, f := range ranking[:size] {
+		readings := make([]string, len(f.Readings))
+		for j, reading := range f.Readings {
+			readings[j] = fmt.Sprintf("%s (%d, %.0f%%)", reading.Reading, reading.Count, 100*float64(reading.Count)/float64(f.Count))
+		}
+		fmt.Fprintf(w, "%4d. %s %s\n", i+1, f.Kanji, strings.Join(readings, ", "))
+	}
+	fmt.Fprintln(w)
+}
+
+func printCharactersRanking(w io.Writer, rep *report, m map[string]int, rankingList []string, rankingSize int) {
+	var lines []rankingLine
+	for i := 0; i < min(rankingSize, len(rankingList)); i++ {
+		c := rankingList[i]
+		label := c
+		category := kanjikana.CategoryKanji
+		if kana.IsKana(c) {
+			label += " " + kana.KanaToRomaji(c)
+			category = kanjikana.CategoryKatakana
+			if kana.IsHiragana(c) {
+				category = kanjikana.CategoryHiragana
+			}
+		} else if description := rep.describeKanji(c); description != "" {
+			label += " " + description
+		}
+		lines = append(lines, rankingLine{label: label, count: m[c], color: rep.lineColor(category, c)})
+	}
+	printRanking(w, rep, lines)
+}
+
+// rankingLine is an entry of a ranking printed by printRanking.
+type rankingLine struct {
+	label string
+	count int
+	color string
+}
+
+// printRanking prints the numbered lines of a ranking with their count and
+// their occurrences per 1,000 Japanese characters, preceded by a bar
+// proportional to their count when histograms are enabled.
+func printRanking(w io.Writer, rep *report, lines []rankingLine) {
+	if rep.histogramWidth == 0 {
+		for i, line := range lines {
+			text := fmt.Sprintf("%4d. %v (%v, %.2f‰)", i+1, line.label, line.count, kanjikana.PerThousand(line.count, rep.res.AllCharactersCount))
+			fmt.Fprintln(w, colorize(text, line.color))
+		}
+		fmt.Fprintln(w)
+		return
+	}
+
+	labelWidth, countWidth, maxCount := 0, 0, 0
+	for _, line := range lines {
+		labelWidth = max(labelWidth, displayWidth(line.label))
+		countWidth = max(countWidth, len(strconv.Itoa(line.count)))
+		maxCount = max(maxCount, line.count)
+	}
+	// The bar takes the room left by the rank, the label, the count and
+	// the occurrences per 1,000 characters, such as " (12.34‰)".
+	perThousandWidth := utf8.RuneCountInString(fmt.Sprintf(" (%.2f‰)", kanjikana.PerThousand(maxCount, rep.res.AllCharactersCount)))
+	barWidth := max(rep.histogramWidth-6-labelWidth-2-countWidth-perThousandWidth, 10)
+
+	for i, line := range lines {
+		padding := strings.Repeat(" ", labelWidth-displayWidth(line.label))
+		text := fmt.Sprintf("%4d. %v%v %v %v (%.2f‰)", i+1, line.label, padding, histogramBar(line.count, maxCount, barWidth), line.count, kanjikana.PerThousand(line.count, rep.res.AllCharactersCount))
+		fmt.Fprintln(w, colorize(text, line.color))
+	}
+	fmt.Fprintln(w)
+}
+
+// histogramBar returns a bar of up to width cells representing value
+// relative to maxValue, drawn with eighth blocks for sub-cell precision.
+func histogramBar(value, maxValue, width int) string {
+	const eighths = " ▏▎▍▌▋▊▉"
+	if maxValue == 0 {
+		return strings.Repeat(" ", width)
+	}
+	units := value * width * 8 / maxValue
+	if value > 0 && units == 0 {
+		units = 1
+	}
+	bar := strings.Repeat("█", units/8)
+	if units%8 > 0 {
+		bar += string([]rune(eighths)[units%8])
+	}
+	return bar + strings.Repeat(" ", width-utf8.RuneCountInString(bar))
+}
+
+// displayWidth returns the number of terminal cells taken by s, counting
+// kanji, kana and other full-width characters as two cells.
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) || (r >= 0x3000 && r <= 0x303f) || (r >= 0xff01 && r <= 0xff60) {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+// printLevelStats prints a table of the occurrences and coverage of every
+// level of a kanji classification.
+func printLevelStats(w io.Writer, name string, stats []kanjikana.LevelStats) {
+	fmt.Fprintln(w, name, "breakdown:")
+	fmt.Fprintf(w, "%10s %12s %12s %9s\n", "level", "occurrences", "seen/total", "coverage")
+	for _, s := range stats {
+		fmt.Fprintf(w, "%10s %12d %12s %8.1f%%\n", s.Level, s.Occurrences, fmt.Sprintf("%d/%d", s.Seen, s.Total), s.Coverage)
+	}
+	fmt.Fprintln(w)
+}
+
+// printJoyoCoverage prints how many of the jōyō kanji appear in the kanji
+// counts m, followed by the ones that never do.
+func printJoyoCoverage(w io.Writer, m map[string]int) {
+	joyo := kanjikana.JoyoKanji()
+	stats := joyo.Stats(m)[0]
+	fmt.Fprintf(w, "Jōyō kanji coverage: %d/%d (%.1f%%)\n", stats.Seen, stats.Total, stats.Coverage)
+
+	unseen := joyo.Unseen(m)
+	if len(unseen) > 0 {
+		fmt.Fprintln(w, len(unseen), "jōyō kanji never seen:")
+		const perLine = 40
+		for i := 0; i < len(unseen); i += perLine {
+			fmt.Fprintln(w, strings.Join(unseen[i:min(i+perLine, len(unseen))], ""))
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// coverageTops are the ranking sizes whose kanji coverage is reported, and
+// coverageTargets the coverage percentages whose number of kanji is.
+var (
+	coverageTops    = []int{10, 50, 100, 200, 500, 1000, 2000}
+	coverageTargets = []float64{50, 75, 90, 95, 98, 99}
+)
+
+// coverageMilestone is the share of the kanji occurrences covered by the
+// Top most frequent kanji.
+type coverageMilestone struct {
+	Top      int     `json:"top"`
+	Coverage float64 `json:"coverage"`
+}
+
+// coverageMilestones returns the points of the coverage curve at
+// coverageTops and, first, at coverageTargets, rounded up to the first
+// ranking size reaching them.
+func coverageMilestones(coverage []float64) (tops, targets []coverageMilestone) {
+	for _, top := range coverageTops {
+		if top < len(coverage) {
+			tops = append(tops, coverageMilestone{Top: top, Coverage: coverage[top-1]})
+		}
+	}
+	for _, target := range coverageTargets {
+		// The first kanji reaching the target, up to rounding errors.
+		i := sort.Search(len(coverage), func(i int) bool { return coverage[i] >= target-1e-9 })
+		if i < len(coverage) {
+			targets = append(targets, coverageMilestone{Top: i + 1, Coverage: target})
+		}
+	}
+	return tops, targets
+}
+
+// printKanjiCoverage prints the share of the kanji occurrences covered by the
+// most frequent kanji, and how many kanji cover common shares.
+func printKanjiCoverage(w io.Writer, coverage []float64) {
+	tops, targets := coverageMilestones(coverage)
+	fmt.Fprintln(w, "Kanji coverage:")
+	for _, m := range tops {
+		fmt.Fprintf(w, "  top %4d kanji cover %5.1f%% of kanji occurrences\n", m.Top, m.Coverage)
+	}
+	for _, m := range targets {
+		fmt.Fprintf(w, "  %3.0f%% of kanji occurrences are covered by the top %d kanji\n", m.Coverage, m.Top)
+	}
+	fmt.Fprintln(w)
+}
+
+// coverageSection is the JSON section of the kanji coverage. Curve is the
+// full cumulative coverage curve: its i-th value is the percentage of the
+// kanji occurrences covered by the i+1 most frequent kanji.
+type coverageSection struct {
+	Tops    []coverageMilestone `json:"tops"`
+	Targets []coverageMilestone `json:"targets"`
+	Curve   []float64           `json:"curve"`
+}
+
+func newCoverageSection(coverage []float64) coverageSection {
+	tops, targets := coverageMilestones(coverage)
+	if tops == nil {
+		tops = []coverageMilestone{}
+	}
+	if targets == nil {
+		targets = []coverageMilestone{}
+	}
+	return coverageSection{Tops: tops, Targets: targets, Curve: coverage}
+}
+
+// printDistribution prints the statistics of the distribution of a
+// category.
+func printDistribution(w io.Writer, name string, stats kanjikana.DistributionStats) {
+	fmt.Fprintln(w, name, "distribution:")
+	fmt.Fprintf(w, "  type-token ratio: %.4f (%d/%d)\n", stats.TypeTokenRatio, stats.Types, stats.Tokens)
+	fmt.Fprintf(w, "  Shannon entropy:  %.3f bits\n", stats.Entropy)
+	fmt.Fprintf(w, "  Zipf exponent:    %.3f (R² %.3f)\n", stats.ZipfExponent, stats.ZipfR2)
+	fmt.Fprintln(w)
+}
+
+// printBlocks prints the occurrences and distinct characters of every
+// Unicode block, and their share of all characters.
+func printBlocks(w io.Writer, blocks []kanjikana.BlockCount, total int) {
+	fmt.Fprintln(w, "Unicode blocks:")
+	fmt.Fprintf(w, "  %-40s %10s %12s %7s\n", "block", "characters", "occurrences", "share")
+	for _, b := range blocks {
+		fmt.Fprintf(w, "  %-40s %10d %12d %6.2f%%\n", b.Block, b.Characters, b.Count, 100*float64(b.Count)/float64(total))
+	}
+	fmt.Fprintln(w)
+}
+
+func printSentences(w io.Writer, stats kanjikana.SentenceStats) {
+	fmt.Fprintln(w, "Sentences:", stats.Count)
+	fmt.Fprintf(w, "  average length:        %.1f characters\n", stats.AverageLength())
+	fmt.Fprintf(w, "  average kanji density: %.1f%%\n", 100*stats.AverageKanjiDensity())
+	fmt.Fprintln(w)
+}
+
+// printStrokeHistogram prints the share of kanji occurrences for every stroke
+// count, along with the average stroke count of a kanji occurrence.
+func printStrokeHistogram(w io.Writer, histogram []kanjikana.StrokeStats) {
+	const barWidth = 40
+
+	occurrences, strokes, maxOccurrences := 0, 0, 0
+	for _, s := range histogram {
+		occurrences += s.Occurrences
+		strokes += s.Strokes * s.Occurrences
+		maxOccurrences = max(maxOccurrences, s.Occurrences)
+	}
+	if occurrences == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "Stroke count distribution:")
+	fmt.Fprintf(w, "%7s %6s %12s %7s\n", "strokes", "kanji", "occurrences", "share")
+	for _, s := range histogram {
+		bar := strings.Repeat("#", (s.Occurrences*barWidth+maxOccurrences-1)/maxOccurrences)
+		share := 100 * float64(s.Occurrences) / float64(occurrences)
+		fmt.Fprintf(w, "%7d %6d %12d %6.1f%% %s\n", s.Strokes, s.Kanjis, s.Occurrences, share, bar)
+	}
+	fmt.Fprintf(w, "Average strokes per kanji: %.1f\n", float64(strokes)/float64(occurrences))
+	fmt.Fprintln(w)
+}
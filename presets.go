@@ -0,0 +1,76 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+)
+
+// preset bundles the crawl settings known to work well for a site.
+type preset struct {
+	url   string
+	depth int
+	// rateLimit is a polite number of requests per second to the site.
+	rateLimit float64
+	// linkPattern matches the links worth following, such as articles.
+	linkPattern string
+	// selector matches the elements holding the text of an article.
+	selector string
+}
+
+var presets = map[string]preset{
+	"nhk-easy": {
+		url:         "https://www3.nhk.or.jp/news/easy/",
+		depth:       1,
+		rateLimit:   1,
+		linkPattern: `^https://www3\.nhk\.or\.jp/news/easy/k\d+/k\d+\.html$`,
+		selector:    "#js-article-body",
+	},
+	"asahi": {
+		url:         "https://www.asahi.com/",
+		depth:       1,
+		rateLimit:   1,
+		linkPattern: `^https://www\.asahi\.com/articles/[A-Z0-9]+\.html$`,
+		selector:    "main",
+	},
+	"aozora": {
+		url:         "https://www.aozora.gr.jp/",
+		depth:       3,
+		rateLimit:   0.5,
+		linkPattern: `^https://www\.aozora\.gr\.jp/(index_pages|cards)/`,
+		selector:    ".main_text",
+	},
+}
+
+// presetNames returns the names of the presets, for the usage message.
+func presetNames() string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// apply sets the flags of f left unset by the command line and the
+// configuration file to the values of the preset, and returns the options
+// restricting the crawl to its articles. -include-url replaces the link
+// pattern of the preset.
+func (p preset) apply(f *countFlags, isSet func(name string) bool) []kanjikana.Option {
+	if !isSet("url") {
+		f.url = p.url
+	}
+	if !isSet("depth") {
+		f.searchDepth = p.depth
+	}
+	if !isSet("ratelimit") {
+		f.rateLimit = p.rateLimit
+	}
+	options := []kanjikana.Option{kanjikana.WithContentSelector(p.selector)}
+	if !isSet("include-url") {
+		options = append(options, kanjikana.WithLinkPattern(regexp.MustCompile(p.linkPattern)))
+	}
+	return options
+}
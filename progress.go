@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+	"golang.org/x/term"
+)
+
+// progressInterval is the minimum time between two redraws of the progress
+// line.
+const progressInterval = 100 * time.Millisecond
+
+// progressLine keeps a status line at the bottom of a terminal. It is also
+// an io.Writer for the log, so that log messages are printed above the
+// status line instead of through it.
+type progressLine struct {
+	mu     sync.Mutex
+	w      io.Writer
+	status string
+	drawn  time.Time
+}
+
+func newProgressLine(w io.Writer) *progressLine {
+	return &progressLine{w: w}
+}
+
+// update redraws the status line with the state of the crawl.
+func (p *progressLine) update(progress kanjikana.Progress) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status = fmt.Sprintf("%d pages fetched, %d queued, %d in flight, %d characters, %v elapsed",
+		progress.Fetched, progress.Queued, progress.InFlight, progress.Characters, progress.Elapsed.Round(time.Second))
+	if time.Since(p.drawn) < progressInterval {
+		return
+	}
+	p.draw()
+}
+
+// Write clears the status line, writes b and draws the status line again.
+func (p *progressLine) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+	n, err := p.w.Write(b)
+	if p.status != "" {
+		p.draw()
+	}
+	return n, err
+}
+
+// finish clears the status line.
+func (p *progressLine) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+	p.status = ""
+}
+
+func (p *progressLine) draw() {
+	fmt.Fprint(p.w, "\r"+p.status+"\x1b[K")
+	p.drawn = time.Now()
+}
+
+func (p *progressLine) clear() {
+	if p.status != "" {
+		io.WriteString(p.w, "\r\x1b[K")
+	}
+}
+
+// showProgress reports whether the crawl progress should be shown, which is
+// only the case when stderr is a terminal.
+func showProgress() bool {
+	return term.IsTerminal(int(os.Stderr.Fd()))
+}
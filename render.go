@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// chromeRenderer renders pages in a headless Chrome driven by chromedp, one
+// tab per page.
+type chromeRenderer struct {
+	browser context.Context
+	// wait is how long scripts may run once a page is loaded.
+	wait time.Duration
+}
+
+// newChromeRenderer starts a headless Chrome, through proxyURL when not
+// empty. The returned function stops it.
+func newChromeRenderer(wait time.Duration, proxyURL string) (*chromeRenderer, context.CancelFunc, error) {
+	allocatorOptions := chromedp.DefaultExecAllocatorOptions[:]
+	if proxyURL != "" {
+		allocatorOptions = append(allocatorOptions, chromedp.ProxyServer(proxyURL))
+	}
+	allocator, cancelAllocator := chromedp.NewExecAllocator(context.Background(), allocatorOptions...)
+	browser, cancelBrowser := chromedp.NewContext(allocator)
+	cancel := func() {
+		cancelBrowser()
+		cancelAllocator()
+	}
+
+	// Running no action starts the browser, so that a missing Chrome is
+	// reported before crawling.
+	if err := chromedp.Run(browser); err != nil {
+		cancel()
+		return nil, nil, fmt.Errorf("unable to start Chrome: %w", err)
+	}
+	return &chromeRenderer{browser: browser, wait: wait}, cancel, nil
+}
+
+func (r *chromeRenderer) Render(ctx context.Context, pageURL string) (string, error) {
+	tab, cancel := chromedp.NewContext(r.browser)
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
+	var page string
+	err := chromedp.Run(tab,
+		chromedp.Navigate(pageURL),
+		chromedp.Sleep(r.wait),
+		chromedp.OuterHTML("html", &page, chromedp.ByQuery),
+	)
+	if err != nil && ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	return page, err
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+	"google.golang.org/grpc"
+)
+
+const serveCommand = "serve"
+
+// maxRequestBody is the largest text accepted by the /count endpoint.
+const maxRequestBody = 32 << 20
+
+// server counts characters over HTTP.
+type server struct {
+	// maxDepth and maxPages bound the crawls requested to /crawl.
+	maxDepth int
+	maxPages int
+	logger   *slog.Logger
+	metrics  *metrics
+
+	// jobs are the crawls started from the web UI, oldest first.
+	jobsMu    sync.Mutex
+	jobs      []*crawlJob
+	nextJobID int
+}
+
+func runServe(args []string) {
+	fs := flag.NewFlagSet(serveCommand, flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	grpcAddr := fs.String("grpc-addr", "", "also serve the gRPC API defined in proto/kanjikana.proto on this address")
+	srv := &server{metrics: newMetrics()}
+	fs.IntVar(&srv.maxDepth, "maxdepth", kanjikana.DefaultSearchDepth, "maximum search depth of a crawl")
+	fs.IntVar(&srv.maxPages, "maxpages", 100, "maximum number of pages of a crawl")
+	var lf logFlags
+	lf.register(fs)
+	setCommandUsage(fs, "serve [flags]", "Count characters over HTTP. The web UI at / starts crawls and shows their progress and rankings.\n\n  POST /count             counts the text of the request body (HTML when sent as text/html)\n  GET  /crawl?url=&depth=  crawls a website\n  GET  /metrics            exposes metrics in the Prometheus text format\n\n/count and /crawl accept words=1, compounds=1, punctuation=1 and ngram=n and respond with the JSON result.")
+	fs.Parse(args)
+
+	srv.logger = lf.setup(os.Stderr)
+	if *grpcAddr != "" {
+		lis, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			fatal(err)
+		}
+		grpcServer := grpc.NewServer()
+		grpcServer.RegisterService(&countServiceDesc, srv)
+		srv.logger.Info("serving gRPC", "addr", *grpcAddr)
+		go func() {
+			fatal(grpcServer.Serve(lis))
+		}()
+	}
+	srv.logger.Info("listening", "addr", *addr)
+	fatal(http.ListenAndServe(*addr, srv.handler()))
+}
+
+func (srv *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/count", srv.handleCount)
+	mux.HandleFunc("/crawl", srv.handleCrawl)
+	mux.Handle("/metrics", srv.metrics)
+	srv.handleWebUI(mux)
+	return mux
+}
+
+// handleCount counts the text posted in the request body.
+func (srv *server) handleCount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		httpError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	countOptions, err := requestCountOptions(r)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxRequestBody)
+	var res *kanjikana.Result
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "text/html") {
+		res, err = kanjikana.CountHTML(body, countOptions...)
+	} else {
+		res, err = kanjikana.CountReader(body, countOptions...)
+	}
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	srv.metrics.observeResult(res)
+	writeJSONResult(w, res)
+}
+
+// handleCrawl crawls the website given in the url query parameter.
+func (srv *server) handleCrawl(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		httpError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	rootURL := query.Get("url")
+	if !kanjikana.ValidateURL(rootURL) {
+		httpError(w, "missing or invalid url", http.StatusBadRequest)
+		return
+	}
+	depth := kanjikana.DefaultSearchDepth
+	if s := query.Get("depth"); s != "" {
+		var err error
+		depth, err = strconv.Atoi(s)
+		if err != nil || depth < 0 {
+			httpError(w, "invalid depth", http.StatusBadRequest)
+			return
+		}
+	}
+	countOptions, err := requestCountOptions(r)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	scraper, err := kanjikana.NewScraper(
+		kanjikana.WithSearchDepth(min(depth, srv.maxDepth)),
+		kanjikana.WithMaxPages(srv.maxPages),
+		kanjikana.WithCountOptions(countOptions...),
+		kanjikana.WithLogger(srv.logger),
+		kanjikana.WithFetchObserver(srv.metrics.observeFetch),
+	)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	start := time.Now()
+	res, err := scraper.ScrapeContext(r.Context(), rootURL)
+	srv.metrics.observeCrawl(time.Since(start))
+	srv.metrics.observeResult(res)
+	if r.Context().Err() != nil {
+		// The client went away.
+		return
+	}
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeJSONResult(w, res)
+}
+
+// requestCountOptions returns the count options set by the words and ngram
+// query parameters of r.
+func requestCountOptions(r *http.Request) ([]kanjikana.CountOption, error) {
+	var options []kanjikana.CountOption
+	query := r.URL.Query()
+	if words, _ := strconv.ParseBool(query.Get("words")); words {
+		tokenizer, err := loadKagomeTokenizer()
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, kanjikana.WithTokenizer(tokenizer))
+	}
+	if compounds, _ := strconv.ParseBool(query.Get("compounds")); compounds {
+		options = append(options, kanjikana.WithCompounds())
+	}
+	if punctuation, _ := strconv.ParseBool(query.Get("punctuation")); punctuation {
+		options = append(options, kanjikana.WithPunctuation())
+	}
+	if s := query.Get("ngram"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, kanjikana.WithNGrams(n))
+	}
+	return options, nil
+}
+
+func writeJSONResult(w http.ResponseWriter, res *kanjikana.Result) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		slog.Warn("unable to write response", "error", err)
+	}
+}
+
+func httpError(w http.ResponseWriter, message string, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
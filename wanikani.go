@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const (
+	wanikaniAPIURL      = "https://api.wanikani.com/v2"
+	wanikaniRevision    = "20170710"
+	wanikaniTokenEnvVar = "WANIKANI_API_TOKEN"
+)
+
+// wanikaniPage is a page of a WaniKani collection endpoint.
+type wanikaniPage struct {
+	Pages struct {
+		NextURL string `json:"next_url"`
+	} `json:"pages"`
+	Data []struct {
+		ID   int             `json:"id"`
+		Data json.RawMessage `json:"data"`
+	} `json:"data"`
+}
+
+// fetchWaniKaniKanji returns the kanji whose lessons the owner of token has
+// completed on WaniKani.
+func fetchWaniKaniKanji(ctx context.Context, token string) (map[string]bool, error) {
+	client := &http.Client{}
+
+	characters := make(map[int]string)
+	err := wanikaniCollection(ctx, client, token, "/subjects?types=kanji", func(id int, data json.RawMessage) error {
+		var subject struct {
+			Characters string `json:"characters"`
+		}
+		if err := json.Unmarshal(data, &subject); err != nil {
+			return err
+		}
+		characters[id] = subject.Characters
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	learned := make(map[string]bool)
+	err = wanikaniCollection(ctx, client, token, "/assignments?subject_types=kanji&started=true", func(_ int, data json.RawMessage) error {
+		var assignment struct {
+			SubjectID int `json:"subject_id"`
+		}
+		if err := json.Unmarshal(data, &assignment); err != nil {
+			return err
+		}
+		if c, ok := characters[assignment.SubjectID]; ok {
+			learned[c] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return learned, nil
+}
+
+// wanikaniCollection calls fn for every resource of a collection endpoint,
+// following its pagination.
+func wanikaniCollection(ctx context.Context, client *http.Client, token, path string, fn func(id int, data json.RawMessage) error) error {
+	next := wanikaniAPIURL + path
+	for next != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, next, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Wanikani-Revision", wanikaniRevision)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return fmt.Errorf("wanikani: %s: %s", path, resp.Status)
+		}
+
+		var page wanikaniPage
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("wanikani: %s: %w", path, err)
+		}
+
+		for _, resource := range page.Data {
+			if err := fn(resource.ID, resource.Data); err != nil {
+				return fmt.Errorf("wanikani: resource %d: %w", resource.ID, err)
+			}
+		}
+		next = page.Pages.NextURL
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+)
+
+// schedule tells when the next crawl of -watch starts.
+type schedule interface {
+	next(after time.Time) time.Time
+}
+
+// parseSchedule parses the value of -watch: an interval such as 6h, or a
+// cron expression such as "0 */6 * * *".
+func parseSchedule(s string) (schedule, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		if d <= 0 {
+			return nil, fmt.Errorf("invalid watch interval: %s", s)
+		}
+		return interval(d), nil
+	}
+	return parseCron(s)
+}
+
+// interval starts a crawl at a fixed interval after the previous one.
+type interval time.Duration
+
+func (d interval) next(after time.Time) time.Time {
+	return after.Add(time.Duration(d))
+}
+
+// cronSchedule starts a crawl at the minutes matching a cron expression.
+// Every field is a bit set of the values it matches.
+type cronSchedule struct {
+	minute, hour, day, month, weekday uint64
+	// anyDay and anyWeekday record a "*" day of month or day of week field:
+	// as in cron, when both are restricted a day matching either is run.
+	anyDay, anyWeekday bool
+}
+
+// cronFields are the bounds of the five fields of a cron expression.
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// parseCron parses a cron expression of five fields (minute, hour, day of
+// month, month and day of week), each a "*" or a comma-separated list of
+// values and ranges, optionally followed by a "/step".
+func parseCron(s string) (*cronSchedule, error) {
+	fields := strings.Fields(s)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid watch schedule %q: want an interval or a cron expression of 5 fields", s)
+	}
+	var sets [5]uint64
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in watch schedule %q: %w", cronFields[i].name, s, err)
+		}
+		sets[i] = set
+	}
+	// Sunday is both 0 and 7.
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+	c := &cronSchedule{
+		minute:     sets[0],
+		hour:       sets[1],
+		day:        sets[2],
+		month:      sets[3],
+		weekday:    sets[4],
+		anyDay:     strings.HasPrefix(fields[2], "*"),
+		anyWeekday: strings.HasPrefix(fields[4], "*"),
+	}
+	if c.next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("watch schedule %q never matches", s)
+	}
+	return c, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			first, last, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = strconv.Atoi(first); err != nil {
+				return 0, fmt.Errorf("invalid value %q", first)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(last); err != nil {
+					return 0, fmt.Errorf("invalid value %q", last)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q out of range %d-%d", rangePart, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func (c *cronSchedule) next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	// Every schedule matches at least once in a leap cycle.
+	for end := t.AddDate(5, 0, 0); t.Before(end); t = t.Add(time.Minute) {
+		if c.matches(t) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c *cronSchedule) matches(t time.Time) bool {
+	if c.minute&(1<<t.Minute()) == 0 || c.hour&(1<<t.Hour()) == 0 || c.month&(1<<t.Month()) == 0 {
+		return false
+	}
+	day := c.day&(1<<t.Day()) != 0
+	weekday := c.weekday&(1<<t.Weekday()) != 0
+	switch {
+	case c.anyDay:
+		return weekday
+	case c.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// watch runs crawl on sched until ctx is done, writing to w what changed
+// between every crawl and the previous one, started at previousTime. A
+// failed crawl is logged and skipped.
+func watch(ctx context.Context, w io.Writer, sched schedule, previous *kanjikana.Result, previousTime time.Time, rankingSize int, crawl func() (*kanjikana.Result, error)) {
+	for {
+		next := sched.next(time.Now())
+		slog.Info("waiting for the next crawl", "at", next.Format(time.DateTime))
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+
+		res, err := crawl()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.Error("crawl failed", "error", err)
+			continue
+		}
+		var changes bytes.Buffer
+		writeDiff(&changes, previous, res, rankingSize)
+		if changes.Len() == 0 {
+			fmt.Fprintf(w, "No changes since the crawl of %s.\n\n", previousTime.Format(time.DateTime))
+		} else {
+			fmt.Fprintf(w, "Changes since the crawl of %s:\n\n", previousTime.Format(time.DateTime))
+			changes.WriteTo(w)
+		}
+		previous, previousTime = res, next
+	}
+}
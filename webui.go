@@ -0,0 +1,241 @@
+package main
+
+import (
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+var webTemplates = template.Must(template.ParseFS(webFiles, "web/*.html"))
+
+// maxJobs is the number of crawls remembered by the web UI.
+const maxJobs = 20
+
+// crawlJob is a crawl started from the web UI.
+type crawlJob struct {
+	id      string
+	url     string
+	depth   int
+	started time.Time
+
+	mu       sync.Mutex
+	progress kanjikana.Progress
+	rep      *report
+	err      error
+	done     bool
+}
+
+// jobView is the state of a crawl job shown by the web UI templates.
+type jobView struct {
+	ID       string
+	URL      string
+	Depth    int
+	Started  time.Time
+	Status   string
+	Progress kanjikana.Progress
+	Elapsed  time.Duration
+}
+
+func (job *crawlJob) view() jobView {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	v := jobView{
+		ID:       job.id,
+		URL:      job.url,
+		Depth:    job.depth,
+		Started:  job.started,
+		Progress: job.progress,
+		Elapsed:  job.progress.Elapsed.Round(time.Second),
+	}
+	switch {
+	case job.err != nil:
+		v.Status = "failed: " + job.err.Error()
+	case job.done:
+		v.Status = "done, " + strconv.Itoa(job.progress.Fetched) + " pages"
+	default:
+		v.Status = "running, " + strconv.Itoa(job.progress.Fetched) + " pages fetched"
+	}
+	return v
+}
+
+// handleWebUI registers the web UI handlers on mux.
+func (srv *server) handleWebUI(mux *http.ServeMux) {
+	static, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err)
+	}
+	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.FS(static))))
+	mux.HandleFunc("/", srv.handleIndex)
+	mux.HandleFunc("/jobs", srv.handleNewJob)
+	mux.HandleFunc("/jobs/", srv.handleJob)
+}
+
+// handleIndex shows the form to start a crawl and the list of crawls.
+func (srv *server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	srv.jobsMu.Lock()
+	jobs := make([]jobView, len(srv.jobs))
+	running := false
+	for i, job := range srv.jobs {
+		// Most recent first.
+		jobs[len(jobs)-1-i] = job.view()
+		job.mu.Lock()
+		running = running || !job.done
+		job.mu.Unlock()
+	}
+	srv.jobsMu.Unlock()
+
+	data := struct {
+		Jobs     []jobView
+		Running  bool
+		MaxDepth int
+	}{jobs, running, srv.maxDepth}
+	if err := webTemplates.ExecuteTemplate(w, "index.html", data); err != nil {
+		srv.logger.Warn("unable to render page", "error", err)
+	}
+}
+
+// handleNewJob starts the crawl submitted with the form of the index page
+// and redirects to its page.
+func (srv *server) handleNewJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	rootURL := r.FormValue("url")
+	if !kanjikana.ValidateURL(rootURL) {
+		http.Error(w, "invalid url", http.StatusBadRequest)
+		return
+	}
+	depth, err := strconv.Atoi(r.FormValue("depth"))
+	if err != nil || depth < 0 {
+		http.Error(w, "invalid depth", http.StatusBadRequest)
+		return
+	}
+	var countOptions []kanjikana.CountOption
+	if r.FormValue("words") != "" {
+		tokenizer, err := loadKagomeTokenizer()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		countOptions = append(countOptions, kanjikana.WithTokenizer(tokenizer))
+	}
+
+	job := &crawlJob{url: rootURL, depth: min(depth, srv.maxDepth), started: time.Now()}
+	scraper, err := kanjikana.NewScraper(
+		kanjikana.WithSearchDepth(job.depth),
+		kanjikana.WithMaxPages(srv.maxPages),
+		kanjikana.WithCountOptions(countOptions...),
+		kanjikana.WithLogger(srv.logger),
+		kanjikana.WithFetchObserver(srv.metrics.observeFetch),
+		kanjikana.WithProgress(func(p kanjikana.Progress) {
+			job.mu.Lock()
+			job.progress = p
+			job.mu.Unlock()
+		}),
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !srv.addJob(job) {
+		http.Error(w, "too many crawls running", http.StatusServiceUnavailable)
+		return
+	}
+
+	go func() {
+		res, err := scraper.Scrape(job.url)
+		srv.metrics.observeCrawl(time.Since(job.started))
+		srv.metrics.observeResult(res)
+		job.mu.Lock()
+		defer job.mu.Unlock()
+		job.done = true
+		job.err = err
+		job.rep = &report{
+			res:         res,
+			rankingSize: defaultRankingSize,
+			readings:    kanjikana.BundledReadings(),
+			jlpt:        kanjikana.JLPTLevels(),
+		}
+	}()
+
+	http.Redirect(w, r, "/jobs/"+job.id, http.StatusSeeOther)
+}
+
+// addJob gives job an id and remembers it, forgetting the oldest finished
+// crawl when maxJobs crawls are already remembered. It reports false when
+// they are all running.
+func (srv *server) addJob(job *crawlJob) bool {
+	srv.jobsMu.Lock()
+	defer srv.jobsMu.Unlock()
+	if len(srv.jobs) >= maxJobs {
+		evicted := false
+		for i, old := range srv.jobs {
+			old.mu.Lock()
+			done := old.done
+			old.mu.Unlock()
+			if done {
+				srv.jobs = append(srv.jobs[:i], srv.jobs[i+1:]...)
+				evicted = true
+				break
+			}
+		}
+		if !evicted {
+			return false
+		}
+	}
+	srv.nextJobID++
+	job.id = strconv.Itoa(srv.nextJobID)
+	srv.jobs = append(srv.jobs, job)
+	return true
+}
+
+// handleJob shows the progress of a crawl, then its report once done.
+func (srv *server) handleJob(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/jobs/")
+	var job *crawlJob
+	srv.jobsMu.Lock()
+	for _, j := range srv.jobs {
+		if j.id == id {
+			job = j
+		}
+	}
+	srv.jobsMu.Unlock()
+	if job == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	job.mu.Lock()
+	done, err, rep := job.done, job.err, job.rep
+	job.mu.Unlock()
+
+	switch {
+	case !done:
+		err = webTemplates.ExecuteTemplate(w, "job.html", job.view())
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	default:
+		err = renderHTMLReport(w, job.url, rep)
+	}
+	if err != nil {
+		srv.logger.Warn("unable to render page", "error", err)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+)
+
+// wikipediaLogInterval is the number of articles between two log messages
+// while reading a dump.
+const wikipediaLogInterval = 10000
+
+// countWikipedia counts the articles of the Wikipedia dump at path, or read
+// from stdin when path is "-".
+func countWikipedia(path string, options ...kanjikana.CountOption) (*kanjikana.Result, error) {
+	var r io.Reader = os.Stdin
+	if path != stdinInput {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	counter, err := kanjikana.NewCounter(options...)
+	if err != nil {
+		return nil, err
+	}
+	articles := 0
+	err = kanjikana.ReadWikipediaDump(r, func(title, text string) error {
+		counter.Count(text)
+		articles++
+		if articles%wikipediaLogInterval == 0 {
+			slog.Info("reading dump", "articles", articles, "last", title)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("dump read", "articles", articles)
+	return counter.Result(), nil
+}
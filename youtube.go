@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/jefersonf/kanji-kana-frequency-counter/kanjikana"
+)
+
+const youtubeURL = "https://www.youtube.com/"
+
+var (
+	// youtubeVideoID matches the 11 characters of a video id.
+	youtubeVideoID = regexp.MustCompile(`^[\w-]{11}$`)
+	// youtubePlaylistVideo matches the videos listed by a playlist page.
+	youtubePlaylistVideo = regexp.MustCompile(`"playlistVideoRenderer":\{"videoId":"([\w-]{11})"`)
+)
+
+// youtubePlayerResponse holds the fields used of the player response
+// embedded in the page of a video.
+type youtubePlayerResponse struct {
+	PlayabilityStatus struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	} `json:"playabilityStatus"`
+	VideoDetails struct {
+		Title string `json:"title"`
+	} `json:"videoDetails"`
+	Captions struct {
+		Renderer struct {
+			CaptionTracks []youtubeCaptionTrack `json:"captionTracks"`
+		} `json:"playerCaptionsTracklistRenderer"`
+	} `json:"captions"`
+}
+
+type youtubeCaptionTrack struct {
+	BaseURL      string `json:"baseUrl"`
+	LanguageCode string `json:"languageCode"`
+	// Kind is "asr" for captions generated by speech recognition.
+	Kind string `json:"kind"`
+}
+
+// countYouTube fetches the Japanese captions of the YouTube videos given by
+// urls and counts them together. A URL is the one of a video, of a playlist,
+// or a video id. Captions written by people are preferred to the ones
+// generated by speech recognition, and videos without Japanese captions are
+// skipped. The per-video results are available in Result.Files, by title.
+func countYouTube(ctx context.Context, urls []string, options ...kanjikana.CountOption) (*kanjikana.Result, error) {
+	client := &http.Client{}
+
+	var videos []string
+	for _, u := range urls {
+		ids, err := youtubeVideos(ctx, client, u)
+		if err != nil {
+			return nil, err
+		}
+		videos = append(videos, ids...)
+	}
+
+	total := &kanjikana.Result{Files: make(map[string]*kanjikana.Result)}
+	for _, id := range videos {
+		title, text, err := youtubeCaptions(ctx, client, id)
+		if errors.Is(err, errNoJapaneseCaptions) {
+			slog.Warn("skipping video", "video", id, "title", title, "err", err)
+			continue
+		}
+		if err != nil && ctx.Err() != nil {
+			return total, ctx.Err()
+		}
+		if err != nil {
+			return nil, err
+		}
+		res, err := kanjikana.CountReader(strings.NewReader(text), options...)
+		if err != nil {
+			return nil, err
+		}
+		if err := total.Merge(res); err != nil {
+			return nil, err
+		}
+		if _, ok := total.Files[title]; ok {
+			title += " (" + id + ")"
+		}
+		total.Files[title] = res
+		slog.Info("captions counted", "video", id, "title", title, "characters", res.AllCharactersCount)
+	}
+	if len(total.Files) == 0 {
+		return nil, errors.New("no video with Japanese captions")
+	}
+	return total, nil
+}
+
+// youtubeVideos returns the ids of the videos given by a video or playlist
+// URL, or a video id. Only the videos listed on the first load of a playlist
+// page, at most 100, are returned.
+func youtubeVideos(ctx context.Context, client *http.Client, s string) ([]string, error) {
+	if youtubeVideoID.MatchString(s) {
+		return []string{s}, nil
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid YouTube URL %q: want the URL of a video or a playlist, or a video id", s)
+	}
+
+	query := u.Query()
+	if id := query.Get("v"); id != "" {
+		return []string{id}, nil
+	}
+	// Short links and shorts: https://youtu.be/ID, https://www.youtube.com/shorts/ID.
+	if segments := strings.Split(strings.Trim(u.Path, "/"), "/"); youtubeVideoID.MatchString(segments[len(segments)-1]) &&
+		(u.Host == "youtu.be" || segments[0] == "shorts" || segments[0] == "live") {
+		return []string{segments[len(segments)-1]}, nil
+	}
+	list := query.Get("list")
+	if list == "" {
+		return nil, fmt.Errorf("invalid YouTube URL %q: no video or playlist", s)
+	}
+
+	page, err := getYouTube(ctx, client, youtubeURL+"playlist?list="+url.QueryEscape(list))
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	seen := make(map[string]bool)
+	for _, m := range youtubePlaylistVideo.FindAllStringSubmatch(page, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			ids = append(ids, m[1])
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no video found in playlist %s", list)
+	}
+	return ids, nil
+}
+
+var errNoJapaneseCaptions = errors.New("no Japanese captions")
+
+// youtubeCaptions returns the title of the video id and the text of its
+// Japanese captions.
+func youtubeCaptions(ctx context.Context, client *http.Client, id string) (string, string, error) {
+	page, err := getYouTube(ctx, client, youtubeURL+"watch?v="+url.QueryEscape(id))
+	if err != nil {
+		return "", "", err
+	}
+	const marker = "ytInitialPlayerResponse = "
+	start := strings.Index(page, marker)
+	if start < 0 {
+		return "", "", fmt.Errorf("video %s: no player response found", id)
+	}
+	// The response is followed by more script, which the decoder ignores.
+	var player youtubePlayerResponse
+	if err := json.NewDecoder(strings.NewReader(page[start+len(marker):])).Decode(&player); err != nil {
+		return "", "", fmt.Errorf("video %s: %w", id, err)
+	}
+	title := player.VideoDetails.Title
+	if title == "" {
+		title = id
+	}
+	if status := player.PlayabilityStatus; status.Status != "" && status.Status != "OK" {
+		return title, "", fmt.Errorf("video %s: %s", id, status.Reason)
+	}
+
+	var track *youtubeCaptionTrack
+	for i, t := range player.Captions.Renderer.CaptionTracks {
+		if t.LanguageCode != "ja" {
+			continue
+		}
+		if track == nil || (track.Kind == "asr" && t.Kind != "asr") {
+			track = &player.Captions.Renderer.CaptionTracks[i]
+		}
+	}
+	if track == nil {
+		return title, "", errNoJapaneseCaptions
+	}
+
+	captions, err := getYouTube(ctx, client, track.BaseURL+"&fmt=vtt")
+	if err != nil {
+		return title, "", err
+	}
+	text, err := kanjikana.SubtitleText(strings.NewReader(captions))
+	if err != nil {
+		return title, "", err
+	}
+	return title, text, nil
+}
+
+// getYouTube gets pageURL and returns its body, failing on error statuses.
+func getYouTube(ctx context.Context, client *http.Client, pageURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept-Language", "ja")
+	// Skip the cookie consent page shown in some countries.
+	req.AddCookie(&http.Cookie{Name: "CONSENT", Value: "YES+"})
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", pageURL, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}